@@ -45,6 +45,24 @@ module "file_download_events_table" {
   schema            = file("${path.module}/event_table/file_download_events_schema.json")
 }
 
+module "file_not_found_events_table" {
+  source            = "./event_table"
+  dataset_id        = google_bigquery_dataset.default.dataset_id
+  table_id          = "file_not_found_events"
+  event_type        = "files-not-found"
+  event_description = "File not found events"
+  schema            = file("${path.module}/event_table/file_not_found_events_schema.json")
+}
+
+module "file_blocked_events_table" {
+  source            = "./event_table"
+  dataset_id        = google_bigquery_dataset.default.dataset_id
+  table_id          = "file_blocked_events"
+  event_type        = "files-blocked"
+  event_description = "File blocked events"
+  schema            = file("${path.module}/event_table/file_blocked_events_schema.json")
+}
+
 module "latest_version_check_events" {
   source            = "./event_table"
   dataset_id        = google_bigquery_dataset.default.dataset_id
@@ -0,0 +1,112 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+# Syncing runs as a scheduled job rather than in the service, so that only one sync runs at a time and it isn't throttled between requests.
+resource "google_service_account" "github_sync" {
+  account_id   = "github-sync"
+  display_name = "Publishes latest version descriptors from GitHub releases"
+}
+
+resource "google_cloud_run_v2_job" "github_sync" {
+  name     = "github-sync"
+  location = "us-central1"
+
+  template {
+    task_count  = 1
+    parallelism = 1
+
+    template {
+      service_account = google_service_account.github_sync.email
+      max_retries     = 0
+      timeout         = "180s"
+
+      containers {
+        image   = var.image_reference
+        command = ["/updates"]
+        args    = ["sync-github-releases"]
+
+        env {
+          name  = "GOOGLE_PROJECT"
+          value = data.google_project.project.name
+        }
+
+        env {
+          name = "HONEYCOMB_API_KEY"
+          value_source {
+            secret_key_ref {
+              secret  = google_secret_manager_secret.honeycomb_api_key.secret_id
+              version = "latest"
+            }
+          }
+        }
+
+        env {
+          name = "GITHUB_TOKEN"
+          value_source {
+            secret_key_ref {
+              secret  = google_secret_manager_secret.github_token.secret_id
+              version = "latest"
+            }
+          }
+        }
+      }
+    }
+  }
+}
+
+resource "google_secret_manager_secret" "github_token" {
+  secret_id = "github-token"
+
+  replication {
+    automatic = true
+  }
+}
+
+resource "google_secret_manager_secret_iam_binding" "github_token" {
+  secret_id = google_secret_manager_secret.github_token.secret_id
+  role      = "roles/secretmanager.secretAccessor"
+  members   = ["serviceAccount:${google_service_account.github_sync.email}"]
+}
+
+resource "google_service_account" "github_sync_scheduler" {
+  account_id   = "github-sync-scheduler"
+  display_name = "Starts the GitHub sync job"
+}
+
+resource "google_cloud_run_v2_job_iam_binding" "github_sync_invokers" {
+  name     = google_cloud_run_v2_job.github_sync.name
+  location = google_cloud_run_v2_job.github_sync.location
+  role     = "roles/run.invoker"
+  members  = ["serviceAccount:${google_service_account.github_sync_scheduler.email}"]
+}
+
+resource "google_cloud_scheduler_job" "github_sync" {
+  name     = "github-sync"
+  region   = google_cloud_run_v2_job.github_sync.location
+  schedule = "*/5 * * * *"
+
+  http_target {
+    http_method = "POST"
+    uri         = "https://${google_cloud_run_v2_job.github_sync.location}-run.googleapis.com/apis/run.googleapis.com/v1/namespaces/${data.google_project.project.number}/jobs/${google_cloud_run_v2_job.github_sync.name}:run"
+
+    oauth_token {
+      service_account_email = google_service_account.github_sync_scheduler.email
+    }
+  }
+}
@@ -67,3 +67,43 @@ resource "google_monitoring_alert_policy" "log_errors" {
     mime_type = "text/markdown"
   }
 }
+
+resource "google_logging_metric" "invalid_version_descriptors" {
+  name        = "invalid-version-descriptors"
+  description = "Number of times an invalid latest version descriptor was read from storage and rejected."
+  filter      = "resource.type=\"cloud_run_revision\" resource.labels.service_name=\"${google_cloud_run_service.service.name}\" jsonPayload.message=~\"^Latest version descriptor is invalid\""
+
+  label_extractors = {
+    "channel" = "EXTRACT(jsonPayload.channel)"
+  }
+
+  metric_descriptor {
+    metric_kind = "DELTA"
+    value_type  = "INT64"
+
+    labels {
+      key        = "channel"
+      value_type = "STRING"
+    }
+  }
+}
+
+resource "google_logging_metric" "dropped_events" {
+  name            = "dropped-events"
+  description     = "Number of events dropped because they could not be queued, as periodically reported by each instance."
+  filter          = "resource.type=\"cloud_run_revision\" resource.labels.service_name=\"${google_cloud_run_service.service.name}\" jsonPayload.message=\"Dropped events that could not be queued.\""
+  value_extractor = "EXTRACT(jsonPayload.dropped)"
+
+  metric_descriptor {
+    metric_kind = "DELTA"
+    value_type  = "DISTRIBUTION"
+  }
+
+  bucket_options {
+    exponential_buckets {
+      num_finite_buckets = 10
+      growth_factor      = 2
+      scale              = 1
+    }
+  }
+}
@@ -53,6 +53,41 @@ resource "google_cloud_run_service" "service" {
             }
           }
         }
+
+        env {
+          name  = "DESCRIPTOR_SNAPSHOT_DIRECTORY"
+          value = "/tmp/descriptor-snapshots"
+        }
+
+        env {
+          name = "ADMIN_TOKENS"
+          value_from {
+            secret_key_ref {
+              name = google_secret_manager_secret.admin_tokens.secret_id
+              key  = "latest"
+            }
+          }
+        }
+
+        env {
+          name  = "STORAGE_NOTIFICATION_AUDIENCE"
+          value = "https://${local.api_dns_fqdn}${local.storage_notification_path}"
+        }
+
+        env {
+          name  = "STORAGE_NOTIFICATION_SERVICE_ACCOUNT"
+          value = google_service_account.storage_notification_pusher.email
+        }
+
+        env {
+          name = "SIGNING_KEYS"
+          value_from {
+            secret_key_ref {
+              name = google_secret_manager_secret.signing_keys.secret_id
+              key  = "latest"
+            }
+          }
+        }
       }
     }
 
@@ -94,5 +129,36 @@ resource "google_secret_manager_secret" "honeycomb_api_key" {
 resource "google_secret_manager_secret_iam_binding" "honeycomb_api_key" {
   secret_id = google_secret_manager_secret.honeycomb_api_key.secret_id
   role      = "roles/secretmanager.secretAccessor"
+  members = [
+    "serviceAccount:${data.google_service_account.service.email}",
+    "serviceAccount:${google_service_account.github_sync.email}",
+  ]
+}
+
+resource "google_secret_manager_secret" "admin_tokens" {
+  secret_id = "admin-tokens"
+
+  replication {
+    automatic = true
+  }
+}
+
+resource "google_secret_manager_secret_iam_binding" "admin_tokens" {
+  secret_id = google_secret_manager_secret.admin_tokens.secret_id
+  role      = "roles/secretmanager.secretAccessor"
+  members   = ["serviceAccount:${data.google_service_account.service.email}"]
+}
+
+resource "google_secret_manager_secret" "signing_keys" {
+  secret_id = "signing-keys"
+
+  replication {
+    automatic = true
+  }
+}
+
+resource "google_secret_manager_secret_iam_binding" "signing_keys" {
+  secret_id = google_secret_manager_secret.signing_keys.secret_id
+  role      = "roles/secretmanager.secretAccessor"
   members   = ["serviceAccount:${data.google_service_account.service.email}"]
 }
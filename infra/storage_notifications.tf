@@ -0,0 +1,79 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+locals {
+  storage_notification_path = "/internal/storage-notifications"
+}
+
+resource "google_pubsub_topic" "public_bucket_changes" {
+  name = "public-bucket-changes"
+}
+
+data "google_storage_project_service_account" "gcs" {
+}
+
+resource "google_pubsub_topic_iam_binding" "public_bucket_changes_publishers" {
+  topic   = google_pubsub_topic.public_bucket_changes.id
+  role    = "roles/pubsub.publisher"
+  members = ["serviceAccount:${data.google_storage_project_service_account.gcs.email_address}"]
+}
+
+resource "google_storage_notification" "public_bucket_changes" {
+  bucket         = google_storage_bucket.public.name
+  payload_format = "JSON_API_V1"
+  topic          = google_pubsub_topic.public_bucket_changes.id
+  event_types    = ["OBJECT_FINALIZE"]
+
+  depends_on = [google_pubsub_topic_iam_binding.public_bucket_changes_publishers]
+}
+
+# Pub/Sub signs the OIDC token sent with each push request as this service account, and the service checks the token was issued to it.
+resource "google_service_account" "storage_notification_pusher" {
+  account_id   = "storage-notification-pusher"
+  display_name = "Pushes storage notifications to the service"
+}
+
+resource "google_service_account_iam_binding" "storage_notification_pusher_token_creators" {
+  service_account_id = google_service_account.storage_notification_pusher.name
+  role               = "roles/iam.serviceAccountTokenCreator"
+  members            = ["serviceAccount:service-${data.google_project.project.number}@gcp-sa-pubsub.iam.gserviceaccount.com"]
+}
+
+resource "google_pubsub_subscription" "storage_notifications" {
+  name                 = "storage-notifications"
+  topic                = google_pubsub_topic.public_bucket_changes.id
+  ack_deadline_seconds = 20
+
+  # Notifications are only useful for a short time: every instance checks the descriptor's generation within a second of its next request anyway.
+  message_retention_duration = "600s"
+
+  push_config {
+    push_endpoint = "https://${local.api_dns_fqdn}${local.storage_notification_path}"
+
+    oidc_token {
+      service_account_email = google_service_account.storage_notification_pusher.email
+      audience              = "https://${local.api_dns_fqdn}${local.storage_notification_path}"
+    }
+  }
+
+  retry_policy {
+    minimum_backoff = "10s"
+    maximum_backoff = "60s"
+  }
+}
@@ -43,3 +43,48 @@ resource "google_storage_bucket_iam_binding" "public_uploaders" {
   members = ["group:${data.google_project.project.name}-uploaders@batect.dev"]
   role    = "projects/${data.google_project.project.name}/roles/uploader"
 }
+
+resource "google_project_iam_custom_role" "descriptor_publisher" {
+  role_id     = "descriptorPublisher"
+  title       = "Descriptor publisher"
+  permissions = ["storage.objects.get", "storage.objects.create", "storage.objects.delete"]
+}
+
+locals {
+  public_bucket_objects = "projects/_/buckets/${google_storage_bucket.public.name}/objects/"
+}
+
+# Allows the admin API to publish and roll back latest version descriptors for every product, and record what it did.
+resource "google_storage_bucket_iam_member" "public_admin_publisher" {
+  bucket = google_storage_bucket.public.name
+  role   = google_project_iam_custom_role.descriptor_publisher.id
+  member = "serviceAccount:${data.google_service_account.service.email}"
+
+  condition {
+    title = "Descriptors, history and audit trail"
+    expression = join(" || ", [
+      "resource.name.endsWith('/latest.json')",
+      "resource.name.endsWith('/history.json')",
+      "resource.name.startsWith('${local.public_bucket_objects}admin/audit/')",
+      "resource.name.extract('${local.public_bucket_objects}products/{product}/admin/audit/') != ''",
+    ])
+  }
+}
+
+# Allows the GitHub sync job to publish latest version descriptors for the default product, and record what it did.
+resource "google_storage_bucket_iam_member" "public_github_sync_publisher" {
+  bucket = google_storage_bucket.public.name
+  role   = google_project_iam_custom_role.descriptor_publisher.id
+  member = "serviceAccount:${google_service_account.github_sync.email}"
+
+  condition {
+    title = "Default product descriptors, release history, descriptor history and audit trail"
+    expression = join(" || ", [
+      "resource.name == '${local.public_bucket_objects}v1/latest.json'",
+      "resource.name == '${local.public_bucket_objects}v1/releases.json'",
+      "(resource.name.startsWith('${local.public_bucket_objects}v1/channels/') && resource.name.endsWith('/latest.json'))",
+      "resource.name.startsWith('${local.public_bucket_objects}admin/channels/')",
+      "resource.name.startsWith('${local.public_bucket_objects}admin/audit/')",
+    ])
+  }
+}
@@ -0,0 +1,25 @@
+#! /usr/bin/env bash
+
+# Posts a sample Cloud Storage notification to a locally running instance of the service, as Pub/Sub would, to test refreshing cached
+# latest version descriptors without the Pub/Sub emulator.
+#
+# The service must be running with STORAGE_NOTIFICATION_TOKEN set to the same value as the token given here.
+#
+# Usage: post_storage_notification.sh <token> [object name] [base URL]
+# eg. post_storage_notification.sh abc123 v1/channels/beta/latest.json http://localhost:8080
+
+set -euo pipefail
+
+SCRIPT_DIR="$( cd "$( dirname "${BASH_SOURCE[0]}" )" >/dev/null 2>&1 && pwd )"
+TOKEN=${1:?"Usage: $0 <token> [object name] [base URL]"}
+OBJECT_NAME=${2:-v1/latest.json}
+BASE_URL=${3:-http://localhost:8080}
+
+sed "s#v1/channels/beta/latest.json#$OBJECT_NAME#g" "$SCRIPT_DIR/../server/api/testdata/storage_notification.json" | \
+  curl --fail --silent --show-error \
+    --request POST \
+    --header "Content-Type: application/json" \
+    --header "Authorization: Bearer $TOKEN" \
+    --data-binary @- \
+    --write-out "HTTP %{http_code}\n" \
+    "$BASE_URL/internal/storage-notifications"
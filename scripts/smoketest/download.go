@@ -20,7 +20,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 type downloadTest struct{}
@@ -29,8 +31,15 @@ func (t *downloadTest) Description() string {
 	return "check /v1/files/:version/:filename"
 }
 
+// Only released versions can be downloaded.
 func (t *downloadTest) Run(baseURL string) error {
-	resp, err := makeRequest(baseURL, "/v1/files/0.0.0/batect-0.0.0.jar")
+	version, err := getLatestVersion(baseURL)
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := makeRequest(baseURL, fmt.Sprintf("/v1/files/%s/batect-%s.jar", version, version))
 
 	if err != nil {
 		return err
@@ -43,7 +52,7 @@ func (t *downloadTest) Run(baseURL string) error {
 	}
 
 	actualLocation := resp.Header.Get("Location")
-	expectedLocation := "https://github.com/batect/batect/releases/download/0.0.0/batect-0.0.0.jar"
+	expectedLocation := fmt.Sprintf("https://github.com/batect/batect/releases/download/%s/batect-%s.jar", version, version)
 
 	if actualLocation != expectedLocation {
 		return fmt.Errorf("response had unexpected location header '%s', expected '%s'", actualLocation, expectedLocation)
@@ -51,3 +60,77 @@ func (t *downloadTest) Run(baseURL string) error {
 
 	return nil
 }
+
+func getLatestVersion(baseURL string) (string, error) {
+	resp, err := makeRequest(baseURL, "/v1/latest")
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("latest version response had non-200 status code %v", resp.StatusCode)
+	}
+
+	var body struct {
+		Version string `json:"version"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("could not decode latest version response: %w", err)
+	}
+
+	return body.Version, nil
+}
+
+type unreleasedDownloadTest struct{}
+
+func (t *unreleasedDownloadTest) Description() string {
+	return "check /v1/files/:version/:filename for a version that has not been released"
+}
+
+func (t *unreleasedDownloadTest) Run(baseURL string) error {
+	resp, err := makeRequest(baseURL, "/v1/files/0.0.0/batect-0.0.0.jar")
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 404 {
+		return fmt.Errorf("response had non-404 status code %v", resp.StatusCode)
+	}
+
+	return nil
+}
+
+type aliasDownloadTest struct{}
+
+func (t *aliasDownloadTest) Description() string {
+	return "check /v1/files/latest/batect.jar"
+}
+
+func (t *aliasDownloadTest) Run(baseURL string) error {
+	resp, err := makeRequest(baseURL, "/v1/files/latest/batect.jar")
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 302 {
+		return fmt.Errorf("response had non-302 status code %v", resp.StatusCode)
+	}
+
+	actualLocation := resp.Header.Get("Location")
+
+	if !strings.HasPrefix(actualLocation, "https://github.com/batect/batect/releases/download/") || !strings.HasSuffix(actualLocation, ".jar") {
+		return fmt.Errorf("response had unexpected location header '%s'", actualLocation)
+	}
+
+	return nil
+}
@@ -44,6 +44,8 @@ func main() {
 		&pingTest{},
 		&latestTest{},
 		&downloadTest{},
+		&unreleasedDownloadTest{},
+		&aliasDownloadTest{},
 	}
 
 	for _, t := range tests {
@@ -0,0 +1,32 @@
+#! /usr/bin/env bash
+
+# Refreshes the latest version descriptors compiled into the server binary from the bucket that holds version information, so that the
+# embedded fallback descriptors are as recent as possible. The pipeline runs this (via the updateEmbeddedDescriptors task) before building
+# the image it deploys.
+#
+# The descriptors are copied from the bucket rather than downloaded from the service, as the service only returns the part of each
+# descriptor that clients see (eg. without any rollout or support policy).
+#
+# Usage: update_embedded_descriptors.sh [bucket] [channel...]
+# eg. update_embedded_descriptors.sh batect-updates-prod-public beta
+
+set -euo pipefail
+
+SCRIPT_DIR="$( cd "$( dirname "${BASH_SOURCE[0]}" )" >/dev/null 2>&1 && pwd )"
+EMBEDDED_DIR="$SCRIPT_DIR/../server/storage/embedded"
+BUCKET=${1:-batect-updates-prod-public}
+shift || true
+
+function copy() {
+  local object=$1
+
+  echo "Copying $object..."
+  mkdir -p "$(dirname "$EMBEDDED_DIR/$object")"
+  gsutil cp "gs://$BUCKET/$object" "$EMBEDDED_DIR/$object"
+}
+
+copy v1/latest.json
+
+for channel in "$@"; do
+  copy "v1/channels/$channel/latest.json"
+done
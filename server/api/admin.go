@@ -0,0 +1,493 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/batect/updates.batect.dev/server/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const maxAdminRequestBodySize = 1024 * 1024
+
+type adminHandler struct {
+	urlPattern  *regexp.Regexp
+	store       storage.LatestVersionStore
+	generations storage.LatestVersionGenerationStore
+	publisher   storage.LatestVersionPublisher
+	history     storage.DescriptorHistoryStore
+	audit       storage.AuditTrail
+	channels    map[string]struct{}
+	tokens      map[string]string
+}
+
+// tokens maps the name of each administrator to their bearer token. store must not be cached, so that the current generation is always returned.
+func NewAdminHandler(
+	store storage.LatestVersionStore,
+	generations storage.LatestVersionGenerationStore,
+	publisher storage.LatestVersionPublisher,
+	history storage.DescriptorHistoryStore,
+	audit storage.AuditTrail,
+	channels []string,
+	tokens map[string]string,
+) http.Handler {
+	channelSet := make(map[string]struct{}, len(channels))
+
+	for _, channel := range channels {
+		channelSet[channel] = struct{}{}
+	}
+
+	return &adminHandler{
+		urlPattern:  regexp.MustCompile(`^/v1/admin/channels/(?P<channel>[^/]+)/(?P<resource>latest|latest/preview|history|rollback)$`),
+		store:       store,
+		generations: generations,
+		publisher:   publisher,
+		history:     history,
+		audit:       audit,
+		channels:    channelSet,
+		tokens:      tokens,
+	}
+}
+
+type adminRequest struct {
+	w       http.ResponseWriter
+	req     *http.Request
+	actor   string
+	channel string
+	log     *logrus.Entry
+}
+
+// Only the generation and ETag of an invalid descriptor are known.
+type currentDescriptor struct {
+	storage.VersionDescriptor
+	exists  bool
+	invalid bool
+}
+
+type previewResponse struct {
+	Current  *storage.VersionInfo `json:"current"`
+	Proposed storage.VersionInfo  `json:"proposed"`
+}
+
+type historyResponse struct {
+	History []storage.HistoricalDescriptor `json:"history"`
+}
+
+type rollbackRequest struct {
+	Generation int64 `json:"generation"`
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// Authenticate first, so that unauthenticated clients can't discover which channels exist.
+	actor, ok := h.authenticate(req)
+
+	if !ok {
+		middleware.LoggerFromContext(req.Context()).Warn("Rejected admin request without a valid bearer token.")
+		unauthorized(req.Context(), w, "admin")
+
+		return
+	}
+
+	match := h.urlPattern.FindStringSubmatch(req.URL.Path)
+
+	if match == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	channel, resource := match[1], match[2]
+
+	if _, known := h.channels[channel]; !known {
+		notFound(req.Context(), w, fmt.Sprintf("The channel '%v' does not exist", channel))
+		return
+	}
+
+	r := adminRequest{
+		w:       w,
+		req:     req,
+		actor:   actor,
+		channel: channel,
+		log:     middleware.LoggerFromContext(req.Context()).WithField("channel", channel).WithField("actor", actor),
+	}
+
+	switch {
+	case resource == "latest" && req.Method == http.MethodGet:
+		h.getLatest(r)
+	case resource == "latest" && req.Method == http.MethodPut:
+		h.publish(r)
+	case resource == "latest":
+		methodNotAllowed(req.Context(), w, "GET, PUT")
+	case resource == "latest/preview" && requireMethod(w, req, http.MethodPost):
+		h.preview(r)
+	case resource == "history" && requireMethod(w, req, http.MethodGet):
+		h.getHistory(r)
+	case resource == "rollback" && requireMethod(w, req, http.MethodPost):
+		h.rollback(r)
+	}
+}
+
+func (h *adminHandler) authenticate(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+
+	token := strings.TrimPrefix(header, "Bearer ")
+
+	if token == "" {
+		return "", false
+	}
+
+	for actor, expected := range h.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			return actor, true
+		}
+	}
+
+	return "", false
+}
+
+func (h *adminHandler) getLatest(r adminRequest) {
+	current, ok := h.getCurrent(r)
+
+	if !ok {
+		return
+	}
+
+	if !current.exists {
+		notFound(r.req.Context(), r.w, fmt.Sprintf("The channel '%v' does not have a latest version descriptor", r.channel))
+		return
+	}
+
+	r.w.Header().Set("ETag", current.ETag)
+
+	if current.invalid {
+		conflict(r.req.Context(), r.w, fmt.Sprintf("The latest version descriptor for channel '%v' is invalid and must be replaced", r.channel))
+		return
+	}
+
+	writeJSON(r.req.Context(), r.w, http.StatusOK, current.Info)
+}
+
+func (h *adminHandler) publish(r adminRequest) {
+	precondition, ok := requestedPrecondition(r)
+
+	if !ok {
+		return
+	}
+
+	info, ok := readVersionInfo(r)
+
+	if !ok {
+		return
+	}
+
+	h.replace(r, storage.AdminActionPublish, info, precondition)
+}
+
+func (h *adminHandler) preview(r adminRequest) {
+	info, ok := readVersionInfo(r)
+
+	if !ok {
+		return
+	}
+
+	current, ok := h.getCurrent(r)
+
+	if !ok {
+		return
+	}
+
+	response := previewResponse{Proposed: info}
+	action := storage.AdminAction{Actor: r.actor, Action: storage.AdminActionPreview, Channel: r.channel, Version: info.Version.String()}
+
+	if current.exists {
+		action.PreviousGeneration = current.Generation
+	}
+
+	if current.exists && !current.invalid {
+		response.Current = &current.Info
+		action.PreviousVersion = current.Info.Version.String()
+	}
+
+	if !h.recordAdminAction(r, action) {
+		return
+	}
+
+	writeJSON(r.req.Context(), r.w, http.StatusOK, response)
+}
+
+func (h *adminHandler) getHistory(r adminRequest) {
+	history, err := h.history.GetDescriptorHistory(r.req.Context(), r.channel)
+
+	if err != nil {
+		r.log.WithError(err).Error("Getting descriptor history failed.")
+		serviceUnavailable(r.req.Context(), r.w)
+
+		return
+	}
+
+	writeJSON(r.req.Context(), r.w, http.StatusOK, historyResponse{History: history})
+}
+
+func (h *adminHandler) rollback(r adminRequest) {
+	precondition, ok := requestedPrecondition(r)
+
+	if !ok {
+		return
+	}
+
+	var body rollbackRequest
+
+	if !readJSONBody(r, &body) {
+		return
+	}
+
+	history, err := h.history.GetDescriptorHistory(r.req.Context(), r.channel)
+
+	if err != nil {
+		r.log.WithError(err).Error("Getting descriptor history failed.")
+		serviceUnavailable(r.req.Context(), r.w)
+
+		return
+	}
+
+	for _, entry := range history {
+		if entry.Generation == body.Generation {
+			h.replace(r, storage.AdminActionRollback, entry.Info, precondition)
+			return
+		}
+	}
+
+	notFound(r.req.Context(), r.w, fmt.Sprintf("The history for channel '%v' does not contain a descriptor with generation %v", r.channel, body.Generation))
+}
+
+func (h *adminHandler) replace(r adminRequest, actionType storage.AdminActionType, info storage.VersionInfo, precondition storage.Precondition) {
+	current, ok := h.getCurrent(r)
+
+	if !ok {
+		return
+	}
+
+	if !preconditionHolds(precondition, current) {
+		preconditionFailed(r.req.Context(), r.w, "The latest version descriptor has changed since it was retrieved")
+		return
+	}
+
+	// The descriptor may have changed since it was read above.
+	published, err := h.publisher.PublishLatestVersionDescriptor(r.req.Context(), r.channel, info, precondition)
+
+	if errors.Is(err, storage.ErrPreconditionFailed) {
+		preconditionFailed(r.req.Context(), r.w, "The latest version descriptor has changed since it was retrieved")
+		return
+	}
+
+	if err != nil {
+		r.log.WithError(err).Error("Publishing latest version descriptor failed.")
+		serviceUnavailable(r.req.Context(), r.w)
+
+		return
+	}
+
+	action := storage.AdminAction{
+		Actor:      r.actor,
+		Action:     actionType,
+		Channel:    r.channel,
+		Version:    published.Info.Version.String(),
+		Generation: published.Generation,
+	}
+
+	if current.exists {
+		action.PreviousGeneration = current.Generation
+	}
+
+	// An invalid descriptor can't be rolled back to, so it isn't added to the history.
+	if current.exists && !current.invalid {
+		action.PreviousVersion = current.Info.Version.String()
+
+		h.addToHistory(r, current.VersionDescriptor)
+	}
+
+	if !h.recordAdminAction(r, action) {
+		return
+	}
+
+	r.w.Header().Set("ETag", published.ETag)
+	writeJSON(r.req.Context(), r.w, http.StatusOK, published.Info)
+}
+
+// getCurrent writes an error response if ok is false.
+func (h *adminHandler) getCurrent(r adminRequest) (current currentDescriptor, ok bool) {
+	descriptor, err := h.store.GetLatestVersionDescriptor(r.req.Context(), r.channel)
+
+	if errors.Is(err, storage.ErrInvalidVersionDescriptor) {
+		r.log.WithError(err).Warn("Latest version descriptor is invalid.")
+
+		return h.getCurrentGeneration(r)
+	}
+
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return currentDescriptor{}, true
+	}
+
+	if err != nil {
+		r.log.WithError(err).Error("Getting latest version descriptor failed.")
+		serviceUnavailable(r.req.Context(), r.w)
+
+		return currentDescriptor{}, false
+	}
+
+	return currentDescriptor{VersionDescriptor: descriptor, exists: true}, true
+}
+
+func (h *adminHandler) getCurrentGeneration(r adminRequest) (current currentDescriptor, ok bool) {
+	generation, err := h.generations.GetLatestVersionDescriptorGeneration(r.req.Context(), r.channel)
+
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return currentDescriptor{}, true
+	}
+
+	if err != nil {
+		r.log.WithError(err).Error("Getting latest version descriptor generation failed.")
+		serviceUnavailable(r.req.Context(), r.w)
+
+		return currentDescriptor{}, false
+	}
+
+	descriptor := storage.VersionDescriptor{ETag: fmt.Sprintf(`"%v"`, generation), Generation: generation}
+
+	return currentDescriptor{VersionDescriptor: descriptor, exists: true, invalid: true}, true
+}
+
+// The descriptor has already been published by this point, so failures are logged with everything needed to reconstruct the entry.
+func (h *adminHandler) addToHistory(r adminRequest, replaced storage.VersionDescriptor) {
+	entry := storage.HistoricalDescriptor{
+		Info:       replaced.Info,
+		Generation: replaced.Generation,
+		ReplacedAt: time.Now().UTC(),
+		ReplacedBy: r.actor,
+	}
+
+	if err := h.history.AddToDescriptorHistory(r.req.Context(), r.channel, entry); err != nil {
+		r.log.WithError(err).WithField("historyEntry", entry).Error("Adding replaced descriptor to history failed.")
+	}
+}
+
+// recordAdminAction writes an error response if the action could not be recorded, as every admin action must be in the audit trail.
+func (h *adminHandler) recordAdminAction(r adminRequest, action storage.AdminAction) bool {
+	log := r.log.WithField("adminAction", action)
+	log.Info("Admin action performed.")
+
+	if err := h.audit.RecordAdminAction(r.req.Context(), action); err != nil {
+		log.WithError(err).Error("Recording admin action in audit trail failed.")
+		serviceUnavailable(r.req.Context(), r.w)
+
+		return false
+	}
+
+	return true
+}
+
+// requestedPrecondition writes an error response if ok is false.
+func requestedPrecondition(r adminRequest) (precondition storage.Precondition, ok bool) {
+	if ifNoneMatch := r.req.Header.Get("If-None-Match"); ifNoneMatch == "*" {
+		return storage.Precondition{DoesNotExist: true}, true
+	}
+
+	ifMatch := r.req.Header.Get("If-Match")
+
+	if ifMatch == "" {
+		preconditionRequired(r.req.Context(), r.w, "This endpoint requires an If-Match header with the current ETag, or 'If-None-Match: *' if there is no current descriptor")
+		return storage.Precondition{}, false
+	}
+
+	generation, err := strconv.ParseInt(strings.Trim(ifMatch, `"`), 10, 64)
+
+	if err != nil || generation <= 0 {
+		// This can never match the ETag of any descriptor.
+		preconditionFailed(r.req.Context(), r.w, "The latest version descriptor has changed since it was retrieved")
+		return storage.Precondition{}, false
+	}
+
+	return storage.Precondition{GenerationMatch: generation}, true
+}
+
+func preconditionHolds(precondition storage.Precondition, current currentDescriptor) bool {
+	if precondition.DoesNotExist {
+		return !current.exists
+	}
+
+	return current.exists && current.Generation == precondition.GenerationMatch
+}
+
+func readVersionInfo(r adminRequest) (storage.VersionInfo, bool) {
+	body, ok := readBody(r)
+
+	if !ok {
+		return storage.VersionInfo{}, false
+	}
+
+	info, err := storage.ParseVersionInfo(body)
+
+	if err != nil {
+		badRequest(r.req.Context(), r.w, fmt.Sprintf("The descriptor is invalid: %v", err))
+		return storage.VersionInfo{}, false
+	}
+
+	return info, true
+}
+
+func readJSONBody(r adminRequest, target interface{}) bool {
+	body, ok := readBody(r)
+
+	if !ok {
+		return false
+	}
+
+	if err := json.Unmarshal(body, target); err != nil {
+		badRequest(r.req.Context(), r.w, fmt.Sprintf("The request body is invalid: %v", err))
+		return false
+	}
+
+	return true
+}
+
+func readBody(r adminRequest) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(r.w, r.req.Body, maxAdminRequestBodySize))
+
+	if err != nil {
+		badRequest(r.req.Context(), r.w, fmt.Sprintf("The request body could not be read: %v", err))
+		return nil, false
+	}
+
+	return body, true
+}
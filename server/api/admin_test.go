@@ -0,0 +1,497 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/api"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Admin endpoints", func() {
+	var objects storage.ObjectStore
+	var store storage.LatestVersionStore
+	var publisher storage.LatestVersionPublisher
+	var history storage.DescriptorHistoryStore
+	var audit *mockAuditTrail
+	var handler http.Handler
+	var resp *httptest.ResponseRecorder
+	var current storage.VersionDescriptor
+
+	newerDescriptorJSON := `{"version":"0.84.0","url":"https://github.com/batect/batect/releases/tag/0.84.0","files":[{"type":"script","name":"batect","url":"https://github.com/batect/batect/releases/download/0.84.0/batect"}]}`
+
+	send := func(method string, path string, body string, headers ...string) {
+		req, _ := testutils.RequestWithTestLogger(httptest.NewRequest(method, path, strings.NewReader(body)))
+		req.Header.Set("Authorization", "Bearer alices-token")
+
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		handler.ServeHTTP(resp, req)
+	}
+
+	storeInvalidDescriptor := func(channel string) storage.Object {
+		object, err := objects.PutObject(context.Background(), "v1/channels/"+channel+"/latest.json", []byte(`{"version":"0.84.0"}`), "application/json", storage.Precondition{})
+		Expect(err).ToNot(HaveOccurred())
+
+		return object
+	}
+
+	currentDescriptor := func() storage.VersionDescriptor {
+		descriptor, err := store.GetLatestVersionDescriptor(context.Background(), "stable")
+		Expect(err).ToNot(HaveOccurred())
+
+		return descriptor
+	}
+
+	BeforeEach(func() {
+		objects = storage.NewInMemoryObjectStore(nil)
+		store = storage.NewLatestVersionStore(objects)
+		publisher = storage.NewLatestVersionPublisher(objects)
+		history = storage.NewDescriptorHistoryStore(objects)
+		audit = &mockAuditTrail{}
+		handler = api.NewAdminHandler(store, storage.NewLatestVersionGenerationStore(objects), publisher, history, audit, []string{"stable", "beta"}, map[string]string{"alice": "alices-token", "bob": "bobs-token"})
+		resp = httptest.NewRecorder()
+
+		var err error
+		current, err = publisher.PublishLatestVersionDescriptor(context.Background(), "stable", exampleVersionInfo(), storage.Precondition{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("when the request has no bearer token", func() {
+		BeforeEach(func() {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/admin/channels/stable/latest", nil))
+			handler.ServeHTTP(resp, req)
+		})
+
+		It("returns a HTTP 401 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+			Expect(resp.Body).To(MatchJSON(`{"message":"This endpoint requires a valid bearer token"}`))
+			Expect(resp.Result().Header).To(HaveKeyWithValue("Www-Authenticate", []string{`Bearer realm="admin"`}))
+		})
+	})
+
+	Context("when the request has an unknown bearer token", func() {
+		BeforeEach(func() {
+			send("PUT", "/v1/admin/channels/stable/latest", newerDescriptorJSON, "Authorization", "Bearer not-a-token", "If-Match", current.ETag)
+		})
+
+		It("returns a HTTP 401 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("does not change the descriptor", func() {
+			Expect(currentDescriptor()).To(Equal(current))
+		})
+	})
+
+	Context("when the request is for an unknown channel", func() {
+		BeforeEach(func() {
+			send("GET", "/v1/admin/channels/unknown/latest", "")
+		})
+
+		It("returns a HTTP 404 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+			Expect(resp.Body).To(MatchJSON(`{"message":"The channel 'unknown' does not exist"}`))
+		})
+	})
+
+	Context("when the request is for an unknown endpoint", func() {
+		BeforeEach(func() {
+			send("GET", "/v1/admin/channels/stable/something-else", "")
+		})
+
+		It("returns a HTTP 404 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("getting the current descriptor", func() {
+		Context("given the channel has a descriptor", func() {
+			BeforeEach(func() {
+				send("GET", "/v1/admin/channels/stable/latest", "")
+			})
+
+			It("returns the descriptor with its ETag", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Etag", []string{current.ETag}))
+			})
+		})
+
+		Context("given the channel does not have a descriptor", func() {
+			BeforeEach(func() {
+				send("GET", "/v1/admin/channels/beta/latest", "")
+			})
+
+			It("returns a HTTP 404 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+				Expect(resp.Body).To(MatchJSON(`{"message":"The channel 'beta' does not have a latest version descriptor"}`))
+			})
+		})
+
+		Context("given the channel's descriptor is invalid", func() {
+			var invalid storage.Object
+
+			BeforeEach(func() {
+				invalid = storeInvalidDescriptor("beta")
+				send("GET", "/v1/admin/channels/beta/latest", "")
+			})
+
+			It("returns a HTTP 409 response with the descriptor's ETag, so that it can be replaced", func() {
+				Expect(resp.Code).To(Equal(http.StatusConflict))
+				Expect(resp.Body).To(MatchJSON(`{"message":"The latest version descriptor for channel 'beta' is invalid and must be replaced"}`))
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Etag", []string{fmt.Sprintf(`"%v"`, invalid.Generation)}))
+			})
+		})
+
+		Context("when invoked with an unsupported HTTP method", func() {
+			BeforeEach(func() {
+				send("DELETE", "/v1/admin/channels/stable/latest", "")
+			})
+
+			It("returns a HTTP 405 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusMethodNotAllowed))
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Allow", []string{"GET, PUT"}))
+			})
+		})
+	})
+
+	Describe("publishing a descriptor", func() {
+		Context("given the request has the current ETag", func() {
+			BeforeEach(func() {
+				send("PUT", "/v1/admin/channels/stable/latest", newerDescriptorJSON, "If-Match", current.ETag)
+			})
+
+			It("returns the published descriptor with its new ETag", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body).To(MatchJSON(newerDescriptorJSON))
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Etag", []string{currentDescriptor().ETag}))
+			})
+
+			It("publishes the descriptor", func() {
+				Expect(currentDescriptor().Info.Version).To(Equal(semver.MustParse("0.84.0")))
+			})
+
+			It("adds the replaced descriptor to the history", func() {
+				entries, err := history.GetDescriptorHistory(context.Background(), "stable")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Info).To(Equal(exampleVersionInfo()))
+				Expect(entries[0].Generation).To(Equal(current.Generation))
+				Expect(entries[0].ReplacedBy).To(Equal("alice"))
+			})
+
+			It("records the action in the audit trail", func() {
+				Expect(audit.actions).To(ConsistOf(storage.AdminAction{
+					Actor:              "alice",
+					Action:             storage.AdminActionPublish,
+					Channel:            "stable",
+					Version:            "0.84.0",
+					Generation:         currentDescriptor().Generation,
+					PreviousVersion:    "0.83.2",
+					PreviousGeneration: current.Generation,
+				}))
+			})
+		})
+
+		Context("given the request has an outdated ETag", func() {
+			BeforeEach(func() {
+				send("PUT", "/v1/admin/channels/stable/latest", newerDescriptorJSON, "If-Match", fmt.Sprintf(`"%v"`, current.Generation-1))
+			})
+
+			It("returns a HTTP 412 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusPreconditionFailed))
+				Expect(resp.Body).To(MatchJSON(`{"message":"The latest version descriptor has changed since it was retrieved"}`))
+			})
+
+			It("does not change the descriptor or record an action", func() {
+				Expect(currentDescriptor()).To(Equal(current))
+				Expect(audit.actions).To(BeEmpty())
+			})
+		})
+
+		Context("given the action can't be recorded in the audit trail", func() {
+			BeforeEach(func() {
+				audit.err = errors.New("something went wrong")
+				send("PUT", "/v1/admin/channels/stable/latest", newerDescriptorJSON, "If-Match", current.ETag)
+			})
+
+			It("returns a HTTP 503 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			})
+		})
+
+		Context("given the request requires that there is no descriptor and there is none", func() {
+			BeforeEach(func() {
+				send("PUT", "/v1/admin/channels/beta/latest", newerDescriptorJSON, "If-None-Match", "*")
+			})
+
+			It("publishes the descriptor", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(store.GetLatestVersionDescriptor(context.Background(), "beta")).To(HaveField("Info.Version", semver.MustParse("0.84.0")))
+			})
+
+			It("records the action in the audit trail without a previous version", func() {
+				Expect(audit.actions).To(HaveLen(1))
+				Expect(audit.actions[0].PreviousVersion).To(BeEmpty())
+			})
+		})
+
+		Context("given the request requires that there is no descriptor but there is one", func() {
+			BeforeEach(func() {
+				send("PUT", "/v1/admin/channels/stable/latest", newerDescriptorJSON, "If-None-Match", "*")
+			})
+
+			It("returns a HTTP 412 response without changing the descriptor", func() {
+				Expect(resp.Code).To(Equal(http.StatusPreconditionFailed))
+				Expect(currentDescriptor()).To(Equal(current))
+			})
+		})
+
+		Context("given the request has no precondition", func() {
+			BeforeEach(func() {
+				send("PUT", "/v1/admin/channels/stable/latest", newerDescriptorJSON)
+			})
+
+			It("returns a HTTP 428 response without changing the descriptor", func() {
+				Expect(resp.Code).To(Equal(http.StatusPreconditionRequired))
+				Expect(currentDescriptor()).To(Equal(current))
+			})
+		})
+
+		Context("given the current descriptor is invalid and the request has its ETag", func() {
+			var invalid storage.Object
+
+			BeforeEach(func() {
+				invalid = storeInvalidDescriptor("beta")
+				send("PUT", "/v1/admin/channels/beta/latest", newerDescriptorJSON, "If-Match", fmt.Sprintf(`"%v"`, invalid.Generation))
+			})
+
+			It("replaces the invalid descriptor", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(store.GetLatestVersionDescriptor(context.Background(), "beta")).To(HaveField("Info.Version", semver.MustParse("0.84.0")))
+			})
+
+			It("does not add the invalid descriptor to the history", func() {
+				Expect(history.GetDescriptorHistory(context.Background(), "beta")).To(BeEmpty())
+			})
+
+			It("records the action in the audit trail with the previous generation but no previous version", func() {
+				Expect(audit.actions).To(HaveLen(1))
+				Expect(audit.actions[0].PreviousVersion).To(BeEmpty())
+				Expect(audit.actions[0].PreviousGeneration).To(Equal(invalid.Generation))
+			})
+		})
+
+		Context("given the current descriptor is invalid and the request has an outdated ETag", func() {
+			BeforeEach(func() {
+				invalid := storeInvalidDescriptor("beta")
+				send("PUT", "/v1/admin/channels/beta/latest", newerDescriptorJSON, "If-Match", fmt.Sprintf(`"%v"`, invalid.Generation-1))
+			})
+
+			It("returns a HTTP 412 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusPreconditionFailed))
+			})
+		})
+
+		Context("given the descriptor is invalid", func() {
+			BeforeEach(func() {
+				send("PUT", "/v1/admin/channels/stable/latest", `{"version":"0.84.0"}`, "If-Match", current.ETag)
+			})
+
+			It("returns a HTTP 400 response without changing the descriptor", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp.Body).To(MatchJSON(`{"message":"The descriptor is invalid: version descriptor is invalid: url is invalid: value is missing"}`))
+				Expect(currentDescriptor()).To(Equal(current))
+			})
+		})
+	})
+
+	Describe("previewing a descriptor", func() {
+		Context("given the descriptor is valid", func() {
+			BeforeEach(func() {
+				send("POST", "/v1/admin/channels/stable/latest/preview", newerDescriptorJSON)
+			})
+
+			It("returns the current and proposed descriptors", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body).To(MatchJSON(`{"current":` + exampleVersionInfoJSON + `,"proposed":` + newerDescriptorJSON + `}`))
+			})
+
+			It("does not change the descriptor", func() {
+				Expect(currentDescriptor()).To(Equal(current))
+			})
+
+			It("records the action in the audit trail", func() {
+				Expect(audit.actions).To(ConsistOf(storage.AdminAction{
+					Actor:              "alice",
+					Action:             storage.AdminActionPreview,
+					Channel:            "stable",
+					Version:            "0.84.0",
+					PreviousVersion:    "0.83.2",
+					PreviousGeneration: current.Generation,
+				}))
+			})
+		})
+
+		Context("given the action can't be recorded in the audit trail", func() {
+			BeforeEach(func() {
+				audit.err = errors.New("something went wrong")
+				send("POST", "/v1/admin/channels/stable/latest/preview", newerDescriptorJSON)
+			})
+
+			It("returns a HTTP 503 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			})
+		})
+
+		Context("given the channel does not have a descriptor", func() {
+			BeforeEach(func() {
+				send("POST", "/v1/admin/channels/beta/latest/preview", newerDescriptorJSON)
+			})
+
+			It("returns no current descriptor", func() {
+				Expect(resp.Body).To(MatchJSON(`{"current":null,"proposed":` + newerDescriptorJSON + `}`))
+			})
+		})
+
+		Context("given the descriptor is invalid", func() {
+			BeforeEach(func() {
+				send("POST", "/v1/admin/channels/stable/latest/preview", `{"version":"0.84.0"}`)
+			})
+
+			It("returns a HTTP 400 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("rolling back", func() {
+		var replaced storage.VersionDescriptor
+
+		BeforeEach(func() {
+			send("PUT", "/v1/admin/channels/stable/latest", newerDescriptorJSON, "If-Match", current.ETag)
+			Expect(resp.Code).To(Equal(http.StatusOK))
+
+			replaced = currentDescriptor()
+			resp = httptest.NewRecorder()
+			audit.actions = nil
+		})
+
+		It("lists the replaced descriptors in the history", func() {
+			send("GET", "/v1/admin/channels/stable/history", "")
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(ContainSubstring(fmt.Sprintf(`"generation":%v`, current.Generation)))
+			Expect(resp.Body).To(ContainSubstring(`"replacedBy":"alice"`))
+		})
+
+		Context("given the requested generation is in the history and the request has the current ETag", func() {
+			BeforeEach(func() {
+				send("POST", "/v1/admin/channels/stable/rollback", fmt.Sprintf(`{"generation":%v}`, current.Generation), "If-Match", replaced.ETag, "Authorization", "Bearer bobs-token")
+			})
+
+			It("republishes the descriptor from the history", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
+				Expect(currentDescriptor().Info).To(Equal(exampleVersionInfo()))
+			})
+
+			It("adds the descriptor that was rolled back to the history", func() {
+				entries, err := history.GetDescriptorHistory(context.Background(), "stable")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(entries[0].Generation).To(Equal(replaced.Generation))
+				Expect(entries[0].ReplacedBy).To(Equal("bob"))
+			})
+
+			It("records the action in the audit trail", func() {
+				Expect(audit.actions).To(ConsistOf(storage.AdminAction{
+					Actor:              "bob",
+					Action:             storage.AdminActionRollback,
+					Channel:            "stable",
+					Version:            "0.83.2",
+					Generation:         currentDescriptor().Generation,
+					PreviousVersion:    "0.84.0",
+					PreviousGeneration: replaced.Generation,
+				}))
+			})
+		})
+
+		Context("given the requested generation is not in the history", func() {
+			BeforeEach(func() {
+				send("POST", "/v1/admin/channels/stable/rollback", `{"generation":999999}`, "If-Match", replaced.ETag)
+			})
+
+			It("returns a HTTP 404 response without changing the descriptor", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+				Expect(resp.Body).To(MatchJSON(`{"message":"The history for channel 'stable' does not contain a descriptor with generation 999999"}`))
+				Expect(currentDescriptor()).To(Equal(replaced))
+			})
+		})
+
+		Context("given the request has an outdated ETag", func() {
+			BeforeEach(func() {
+				send("POST", "/v1/admin/channels/stable/rollback", fmt.Sprintf(`{"generation":%v}`, current.Generation), "If-Match", current.ETag)
+			})
+
+			It("returns a HTTP 412 response without changing the descriptor", func() {
+				Expect(resp.Code).To(Equal(http.StatusPreconditionFailed))
+				Expect(currentDescriptor()).To(Equal(replaced))
+			})
+		})
+
+		Context("given the request body is invalid", func() {
+			BeforeEach(func() {
+				send("POST", "/v1/admin/channels/stable/rollback", `not JSON`, "If-Match", replaced.ETag)
+			})
+
+			It("returns a HTTP 400 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+})
+
+type mockAuditTrail struct {
+	actions []storage.AdminAction
+	err     error
+}
+
+func (m *mockAuditTrail) RecordAdminAction(_ context.Context, action storage.AdminAction) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.actions = append(m.actions, action)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+type advisoriesHandler struct {
+	store storage.AdvisoryStore
+}
+
+func NewAdvisoriesHandler(store storage.AdvisoryStore) http.Handler {
+	return &advisoriesHandler{
+		store: store,
+	}
+}
+
+type advisoriesResponse struct {
+	Version    *semver.Version    `json:"version,omitempty"`
+	Advisories []storage.Advisory `json:"advisories"`
+}
+
+func (h *advisoriesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !requireMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	response := advisoriesResponse{Advisories: []storage.Advisory{}}
+
+	if versionParam := req.URL.Query().Get("version"); versionParam != "" {
+		version, err := semver.Parse(versionParam)
+
+		if err != nil {
+			badRequest(req.Context(), w, fmt.Sprintf("The version '%v' is not a valid semantic version", versionParam))
+			return
+		}
+
+		response.Version = &version
+	}
+
+	advisories, err := h.store.GetAdvisories(req.Context())
+
+	if err != nil {
+		middleware.LoggerFromContext(req.Context()).WithError(err).Error("Getting advisories failed.")
+		serviceUnavailable(req.Context(), w)
+
+		return
+	}
+
+	for _, advisory := range advisories {
+		if response.Version == nil || advisory.Affects(*response.Version) {
+			response.Advisories = append(response.Advisories, advisory)
+		}
+	}
+
+	writeJSON(req.Context(), w, http.StatusOK, response)
+}
@@ -0,0 +1,178 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/api"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Advisories endpoint", func() {
+	var store *mockAdvisoryStore
+	var handler http.Handler
+	var resp *httptest.ResponseRecorder
+
+	get := func(path string) {
+		req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+		handler.ServeHTTP(resp, req)
+	}
+
+	securityAdvisoryJSON := `{
+		"id": "BATECT-2022-001",
+		"type": "security",
+		"severity": "high",
+		"summary": "Credentials may be written to logs",
+		"affectedVersions": ">=0.78.0 <0.79.2",
+		"fixedIn": "0.79.2",
+		"url": "https://github.com/batect/batect/security/advisories/1"
+	}`
+
+	bugAdvisoryJSON := `{
+		"id": "BATECT-2023-001",
+		"type": "bug",
+		"severity": "medium",
+		"summary": "Containers are not cleaned up",
+		"affectedVersions": "0.79.x || 0.83.x",
+		"fixedIn": null,
+		"url": "https://github.com/batect/batect/issues/2"
+	}`
+
+	BeforeEach(func() {
+		fixedIn := semver.MustParse("0.79.2")
+
+		store = &mockAdvisoryStore{
+			advisoriesToReturn: []storage.Advisory{
+				{
+					ID:               "BATECT-2022-001",
+					Type:             storage.AdvisoryTypeSecurity,
+					Severity:         storage.AdvisorySeverityHigh,
+					Summary:          "Credentials may be written to logs",
+					AffectedVersions: semver.MustParseRange(">=0.78.0 <0.79.2"),
+					FixedIn:          &fixedIn,
+					URL:              "https://github.com/batect/batect/security/advisories/1",
+				},
+				{
+					ID:               "BATECT-2023-001",
+					Type:             storage.AdvisoryTypeBug,
+					Severity:         storage.AdvisorySeverityMedium,
+					Summary:          "Containers are not cleaned up",
+					AffectedVersions: semver.MustParseRange("0.79.x || 0.83.x"),
+					URL:              "https://github.com/batect/batect/issues/2",
+				},
+			},
+		}
+
+		handler = api.NewAdvisoriesHandler(store)
+		resp = httptest.NewRecorder()
+	})
+
+	Context("when invoked with a HTTP method other than GET", func() {
+		BeforeEach(func() {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("POST", "/v1/advisories", nil))
+			handler.ServeHTTP(resp, req)
+		})
+
+		It("returns a HTTP 405 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+
+	Context("when no version is provided", func() {
+		BeforeEach(func() {
+			get("/v1/advisories")
+		})
+
+		It("returns all advisories", func() {
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(MatchJSON(`{"advisories": [` + securityAdvisoryJSON + `, ` + bugAdvisoryJSON + `]}`))
+		})
+	})
+
+	Context("when a version affected by advisories is provided", func() {
+		BeforeEach(func() {
+			get("/v1/advisories?version=0.79.1")
+		})
+
+		It("returns the advisories that affect the version", func() {
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(MatchJSON(`{"version": "0.79.1", "advisories": [` + securityAdvisoryJSON + `, ` + bugAdvisoryJSON + `]}`))
+		})
+	})
+
+	Context("when a version affected by some advisories is provided", func() {
+		BeforeEach(func() {
+			get("/v1/advisories?version=0.79.2")
+		})
+
+		It("returns only the advisories that affect the version", func() {
+			Expect(resp.Body).To(MatchJSON(`{"version": "0.79.2", "advisories": [` + bugAdvisoryJSON + `]}`))
+		})
+	})
+
+	Context("when a version not affected by any advisories is provided", func() {
+		BeforeEach(func() {
+			get("/v1/advisories?version=0.80.0")
+		})
+
+		It("returns an empty list", func() {
+			Expect(resp.Body).To(MatchJSON(`{"version": "0.80.0", "advisories": []}`))
+		})
+	})
+
+	Context("when an invalid version is provided", func() {
+		BeforeEach(func() {
+			get("/v1/advisories?version=0.79")
+		})
+
+		It("returns a HTTP 400 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body).To(MatchJSON(`{"message": "The version '0.79' is not a valid semantic version"}`))
+		})
+	})
+
+	Context("when getting the advisories fails", func() {
+		BeforeEach(func() {
+			store.errorToReturn = errors.New("something went wrong")
+			get("/v1/advisories?version=0.79.1")
+		})
+
+		It("returns a HTTP 503 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+		})
+	})
+})
+
+type mockAdvisoryStore struct {
+	advisoriesToReturn []storage.Advisory
+	errorToReturn      error
+}
+
+func (m *mockAdvisoryStore) GetAdvisories(_ context.Context) ([]storage.Advisory, error) {
+	return m.advisoriesToReturn, m.errorToReturn
+}
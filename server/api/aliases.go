@@ -0,0 +1,73 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"regexp"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+// 'latest' and 'stable' refer to the newest release, and a partial version such as '0.83' or '0.x' to the newest release that matches it.
+//
+//nolint:gochecknoglobals
+var versionAliasPattern = regexp.MustCompile(`^(latest|stable|\d+(\.\d+)?(\.x)?)$`)
+
+// The version an alias refers to changes with each release.
+const aliasMaxAge = 300
+
+func isVersionAlias(s string) bool {
+	return versionAliasPattern.MatchString(s)
+}
+
+func resolveVersionAlias(alias string, releases []storage.Release, yanked []storage.YankedVersion) (semver.Version, bool) {
+	versionRange := semver.MustParseRange("*")
+
+	if alias != "latest" && alias != "stable" {
+		r, err := semver.ParseRange(alias)
+		if err != nil {
+			return semver.Version{}, false
+		}
+
+		versionRange = r
+	}
+
+	var newest semver.Version
+
+	found := false
+
+	for _, release := range releases {
+		if release.Status == storage.ReleaseStatusYanked || release.Version.IsPrerelease() || !versionRange.Contains(release.Version) {
+			continue
+		}
+
+		if _, isYanked := storage.FindYankedVersion(yanked, release.Version.String()); isYanked {
+			continue
+		}
+
+		if !found || newest.LessThan(release.Version) {
+			newest = release.Version
+			found = true
+		}
+	}
+
+	return newest, found
+}
@@ -0,0 +1,202 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+const (
+	markdownMimeType  = "text/markdown"
+	plainTextMimeType = "text/plain"
+)
+
+type changesHandler struct {
+	store storage.ReleaseHistoryStore
+}
+
+func NewChangesHandler(store storage.ReleaseHistoryStore) http.Handler {
+	return &changesHandler{
+		store: store,
+	}
+}
+
+type changesResponse struct {
+	From    semver.Version `json:"from"`
+	To      semver.Version `json:"to"`
+	Changes []change       `json:"changes"`
+}
+
+type change struct {
+	Version     semver.Version        `json:"version"`
+	ReleaseDate time.Time             `json:"releaseDate"`
+	URL         string                `json:"url"`
+	Status      storage.ReleaseStatus `json:"status"`
+	Notes       string                `json:"notes"`
+}
+
+func (h *changesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !requireMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	from, ok := versionQueryParameter(w, req, "from")
+
+	if !ok {
+		return
+	}
+
+	to, ok := versionQueryParameter(w, req, "to")
+
+	if !ok {
+		return
+	}
+
+	if !from.LessThan(to) {
+		badRequest(req.Context(), w, "The 'from' version must be older than the 'to' version")
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+	contentType, ok := negotiateContentType(req, []string{jsonMimeType, markdownMimeType, plainTextMimeType})
+
+	if !ok {
+		notAcceptable(req.Context(), w, []string{jsonMimeType, markdownMimeType, plainTextMimeType})
+		return
+	}
+
+	releases, err := h.store.GetReleases(req.Context())
+
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		notFound(req.Context(), w, "No release history has been published")
+		return
+	}
+
+	if err != nil {
+		middleware.LoggerFromContext(req.Context()).WithError(err).Error("Getting release history failed.")
+		serviceUnavailable(req.Context(), w)
+
+		return
+	}
+
+	response := changesResponse{From: from, To: to, Changes: changesBetween(releases, from, to)}
+
+	switch contentType {
+	case markdownMimeType:
+		writeText(req.Context(), w, markdownMimeType, response.markdown())
+	case plainTextMimeType:
+		writeText(req.Context(), w, plainTextMimeType, response.plainText())
+	default:
+		writeJSON(req.Context(), w, http.StatusOK, response)
+	}
+}
+
+// If a version appears more than once in releases, only its first entry is used.
+func changesBetween(releases []storage.Release, from semver.Version, to semver.Version) []change {
+	changes := []change{}
+
+	for _, release := range releases {
+		if !from.LessThan(release.Version) || to.LessThan(release.Version) {
+			continue
+		}
+
+		if len(changes) > 0 && changes[len(changes)-1].Version.Equal(release.Version) {
+			continue
+		}
+
+		changes = append(changes, change{
+			Version:     release.Version,
+			ReleaseDate: release.ReleaseDate,
+			URL:         release.URL,
+			Status:      release.Status,
+			Notes:       strings.TrimSpace(release.Notes),
+		})
+	}
+
+	return changes
+}
+
+func (r changesResponse) markdown() string {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "# Changes from %v to %v\n", r.From, r.To)
+
+	for _, c := range r.Changes {
+		fmt.Fprintf(builder, "\n## [%v](%v) (%v)\n\n", c.Version, c.URL, c.ReleaseDate.Format("2006-01-02"))
+
+		if c.Status == storage.ReleaseStatusYanked {
+			builder.WriteString("**This release has been yanked.**\n\n")
+		}
+
+		builder.WriteString(c.notesOrPlaceholder() + "\n")
+	}
+
+	return builder.String()
+}
+
+func (r changesResponse) plainText() string {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "Changes from %v to %v\n", r.From, r.To)
+
+	for _, c := range r.Changes {
+		heading := fmt.Sprintf("%v (%v)", c.Version, c.ReleaseDate.Format("2006-01-02"))
+
+		if c.Status == storage.ReleaseStatusYanked {
+			heading += " - yanked"
+		}
+
+		fmt.Fprintf(builder, "\n%v\n%v\n\n%v\n", heading, strings.Repeat("=", len(heading)), c.notesOrPlaceholder())
+	}
+
+	return builder.String()
+}
+
+func (c change) notesOrPlaceholder() string {
+	if c.Notes == "" {
+		return "No release notes available. See " + c.URL + " for details."
+	}
+
+	return c.Notes
+}
+
+func versionQueryParameter(w http.ResponseWriter, req *http.Request, name string) (semver.Version, bool) {
+	value := req.URL.Query().Get(name)
+
+	if value == "" {
+		badRequest(req.Context(), w, fmt.Sprintf("The '%v' version is required", name))
+		return semver.Version{}, false
+	}
+
+	version, err := semver.Parse(value)
+
+	if err != nil {
+		badRequest(req.Context(), w, fmt.Sprintf("The '%v' version '%v' is not a valid semantic version", name, value))
+		return semver.Version{}, false
+	}
+
+	return version, true
+}
@@ -0,0 +1,242 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/api"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Changes endpoint", func() {
+	var store *mockReleaseHistoryStore
+	var handler http.Handler
+	var resp *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		store = &mockReleaseHistoryStore{}
+		handler = api.NewChangesHandler(store)
+		resp = httptest.NewRecorder()
+	})
+
+	Context("when invoked with a HTTP method other than GET", func() {
+		BeforeEach(func() {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("POST", "/v1/changes?from=0.79.0&to=0.83.2", nil))
+			handler.ServeHTTP(resp, req)
+		})
+
+		It("returns a HTTP 405 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("returns a JSON error payload", func() {
+			Expect(resp.Body).To(MatchJSON(`{"message":"This endpoint only supports GET requests"}`))
+		})
+	})
+
+	Context("when invoked with a HTTP GET", func() {
+		get := func(path string, accept string) {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+
+			handler.ServeHTTP(resp, req)
+		}
+
+		Context("given retrieving the release history succeeds", func() {
+			BeforeEach(func() {
+				withNotes := func(r storage.Release, notes string) storage.Release {
+					r.Notes = notes
+
+					return r
+				}
+
+				store.releasesToReturn = []storage.Release{
+					withNotes(release("0.83.2", storage.ReleaseStatusCurrent), "Fixes a bug.\n"),
+					withNotes(release("0.83.1", storage.ReleaseStatusDeprecated), "Adds a feature."),
+					withNotes(release("0.83.1", storage.ReleaseStatusDeprecated), "Duplicate entry."),
+					release("0.80.0", storage.ReleaseStatusYanked),
+					withNotes(release("0.79.1", storage.ReleaseStatusDeprecated), "Older release."),
+				}
+			})
+
+			Context("when no Accept header is provided", func() {
+				BeforeEach(func() {
+					get("/v1/changes?from=0.79.1&to=0.83.1", "")
+				})
+
+				It("returns a HTTP 200 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusOK))
+				})
+
+				It("returns the response as JSON", func() {
+					Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+				})
+
+				It("indicates that the response varies based on the Accept header", func() {
+					Expect(resp.Header().Get("Vary")).To(Equal("Accept"))
+				})
+
+				It("returns the changes after the 'from' version up to and including the 'to' version, newest first and without duplicates", func() {
+					Expect(resp.Body).To(MatchJSON(`{
+						"from": "0.79.1",
+						"to": "0.83.1",
+						"changes": [
+							{"version": "0.83.1", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.83.1", "status": "deprecated", "notes": "Adds a feature."},
+							{"version": "0.80.0", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.80.0", "status": "yanked", "notes": ""}
+						]
+					}`))
+				})
+			})
+
+			Context("when Markdown is requested", func() {
+				BeforeEach(func() {
+					get("/v1/changes?from=0.79.1&to=0.83.2", "text/markdown, application/json;q=0.5")
+				})
+
+				It("returns a HTTP 200 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusOK))
+				})
+
+				It("returns the response as Markdown", func() {
+					Expect(resp.Header().Get("Content-Type")).To(Equal("text/markdown; charset=utf-8"))
+				})
+
+				It("returns the combined release notes", func() {
+					Expect(resp.Body.String()).To(Equal(`# Changes from 0.79.1 to 0.83.2
+
+## [0.83.2](https://github.com/batect/batect/releases/tag/0.83.2) (2023-01-02)
+
+Fixes a bug.
+
+## [0.83.1](https://github.com/batect/batect/releases/tag/0.83.1) (2023-01-02)
+
+Adds a feature.
+
+## [0.80.0](https://github.com/batect/batect/releases/tag/0.80.0) (2023-01-02)
+
+**This release has been yanked.**
+
+No release notes available. See https://github.com/batect/batect/releases/tag/0.80.0 for details.
+`))
+				})
+			})
+
+			Context("when plain text is requested", func() {
+				BeforeEach(func() {
+					get("/v1/changes?from=0.80.0&to=0.83.1", "text/plain")
+				})
+
+				It("returns the response as plain text", func() {
+					Expect(resp.Header().Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+				})
+
+				It("returns the combined release notes", func() {
+					Expect(resp.Body.String()).To(Equal(`Changes from 0.80.0 to 0.83.1
+
+0.83.1 (2023-01-02)
+===================
+
+Adds a feature.
+`))
+				})
+			})
+
+			Context("when no offered content type is acceptable", func() {
+				BeforeEach(func() {
+					get("/v1/changes?from=0.79.1&to=0.83.2", "text/html, application/json;q=0")
+				})
+
+				It("returns a HTTP 406 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusNotAcceptable))
+				})
+
+				It("returns a JSON error payload", func() {
+					Expect(resp.Body).To(MatchJSON(`{"message":"This endpoint can only return application/json, text/markdown, text/plain"}`))
+				})
+			})
+
+			Context("when no releases are in the range", func() {
+				BeforeEach(func() {
+					get("/v1/changes?from=0.81.0&to=0.82.0", "")
+				})
+
+				It("returns an empty list of changes", func() {
+					Expect(resp.Body).To(MatchJSON(`{"from": "0.81.0", "to": "0.82.0", "changes": []}`))
+				})
+			})
+		})
+
+		DescribeTable("given invalid parameters",
+			func(path string, expectedMessage string) {
+				get(path, "")
+
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp.Body).To(MatchJSON(`{"message":"` + expectedMessage + `"}`))
+				Expect(store.called).To(BeFalse())
+			},
+			Entry("no 'from' version", "/v1/changes?to=0.83.2", "The 'from' version is required"),
+			Entry("no 'to' version", "/v1/changes?from=0.79.1", "The 'to' version is required"),
+			Entry("an invalid 'from' version", "/v1/changes?from=blah&to=0.83.2", "The 'from' version 'blah' is not a valid semantic version"),
+			Entry("an invalid 'to' version", "/v1/changes?from=0.79.1&to=blah", "The 'to' version 'blah' is not a valid semantic version"),
+			Entry("the same 'from' and 'to' versions", "/v1/changes?from=0.79.1&to=0.79.1", "The 'from' version must be older than the 'to' version"),
+			Entry("a 'from' version newer than the 'to' version", "/v1/changes?from=0.83.2&to=0.79.1", "The 'from' version must be older than the 'to' version"),
+		)
+
+		Context("given no release history has been published", func() {
+			BeforeEach(func() {
+				store.errorToReturn = fmt.Errorf("could not get release history: %w", storage.ErrObjectNotFound)
+				get("/v1/changes?from=0.79.1&to=0.83.2", "")
+			})
+
+			It("returns a HTTP 404 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("returns a JSON error payload", func() {
+				Expect(resp.Body).To(MatchJSON(`{"message":"No release history has been published"}`))
+			})
+		})
+
+		Context("given retrieving the release history fails", func() {
+			BeforeEach(func() {
+				store.errorToReturn = errors.New("something went wrong")
+				get("/v1/changes?from=0.79.1&to=0.83.2", "")
+			})
+
+			It("returns a HTTP 503 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			})
+
+			It("returns a JSON error payload", func() {
+				Expect(resp.Body).To(MatchJSON(`{"message":"Service unavailable"}`))
+			})
+		})
+	})
+})
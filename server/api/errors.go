@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/batect/services-common/middleware"
 )
@@ -42,11 +43,53 @@ func methodNotAllowed(ctx context.Context, w http.ResponseWriter, allowedMethod
 	resp.Write(ctx, w, http.StatusMethodNotAllowed)
 }
 
+func badRequest(ctx context.Context, w http.ResponseWriter, message string) {
+	resp := errorResponse{Message: message}
+	resp.Write(ctx, w, http.StatusBadRequest)
+}
+
+func unauthorized(ctx context.Context, w http.ResponseWriter, realm string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%v"`, realm))
+
+	resp := errorResponse{Message: "This endpoint requires a valid bearer token"}
+	resp.Write(ctx, w, http.StatusUnauthorized)
+}
+
+func conflict(ctx context.Context, w http.ResponseWriter, message string) {
+	resp := errorResponse{Message: message}
+	resp.Write(ctx, w, http.StatusConflict)
+}
+
+func preconditionFailed(ctx context.Context, w http.ResponseWriter, message string) {
+	resp := errorResponse{Message: message}
+	resp.Write(ctx, w, http.StatusPreconditionFailed)
+}
+
+func preconditionRequired(ctx context.Context, w http.ResponseWriter, message string) {
+	resp := errorResponse{Message: message}
+	resp.Write(ctx, w, http.StatusPreconditionRequired)
+}
+
+func notFound(ctx context.Context, w http.ResponseWriter, message string) {
+	resp := errorResponse{Message: message}
+	resp.Write(ctx, w, http.StatusNotFound)
+}
+
+func notAcceptable(ctx context.Context, w http.ResponseWriter, offered []string) {
+	resp := errorResponse{Message: fmt.Sprintf("This endpoint can only return %v", strings.Join(offered, ", "))}
+	resp.Write(ctx, w, http.StatusNotAcceptable)
+}
+
 func serviceUnavailable(ctx context.Context, w http.ResponseWriter) {
 	resp := errorResponse{Message: "Service unavailable"}
 	resp.Write(ctx, w, http.StatusServiceUnavailable)
 }
 
+func badGateway(ctx context.Context, w http.ResponseWriter) {
+	resp := errorResponse{Message: "Could not fetch the requested file from upstream"}
+	resp.Write(ctx, w, http.StatusBadGateway)
+}
+
 func (e *errorResponse) Write(ctx context.Context, w http.ResponseWriter, status int) {
 	log := middleware.LoggerFromContext(ctx)
 	log.WithField("errorResponse", e).WithField("statusCode", status).Warn("Returning error to client.")
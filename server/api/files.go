@@ -20,24 +20,111 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
 
+	"github.com/batect/services-common/middleware"
 	"github.com/batect/updates.batect.dev/server/events"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	"golang.org/x/sync/singleflight"
+)
+
+const cachedArtifactOrigin = "cache"
+
+var (
+	errUpstreamFileNotFound       = errors.New("file does not exist upstream")
+	errUpstreamFileDigestMismatch = errors.New("file from upstream does not match the digest in its release manifest")
+)
+
+const (
+	sha256Extension    = ".sha256"
+	sha512Extension    = ".sha512"
+	signatureExtension = ".asc"
 )
 
 type filesHandler struct {
 	urlPattern *regexp.Regexp
+	product    Product
 	eventSink  events.EventSink
+	releases   storage.ReleaseHistoryStore
+	yanked     storage.YankedVersionStore
+	manifests  storage.ReleaseManifestStore
+	random     func(n int) int
+
+	// cache, client and fetches are only used when proxying files.
+	cache   storage.ArtifactCache
+	client  *http.Client
+	fetches singleflight.Group
+}
+
+// releases and yanked should be cached.
+func NewFilesHandler(
+	product Product,
+	eventSink events.EventSink,
+	releases storage.ReleaseHistoryStore,
+	yanked storage.YankedVersionStore,
+	manifests storage.ReleaseManifestStore,
+) http.Handler {
+	return NewFilesHandlerWithSpecificDependencies(product, eventSink, releases, yanked, manifests, cryptoRandomInt)
+}
+
+// random must return a number in [0, n).
+func NewFilesHandlerWithSpecificDependencies(
+	product Product,
+	eventSink events.EventSink,
+	releases storage.ReleaseHistoryStore,
+	yanked storage.YankedVersionStore,
+	manifests storage.ReleaseManifestStore,
+	random func(n int) int,
+) http.Handler {
+	return newFilesHandler(product, eventSink, releases, yanked, manifests, random)
 }
 
-func NewFilesHandler(eventSink events.EventSink) http.Handler {
+func newFilesHandler(
+	product Product,
+	eventSink events.EventSink,
+	releases storage.ReleaseHistoryStore,
+	yanked storage.YankedVersionStore,
+	manifests storage.ReleaseManifestStore,
+	random func(n int) int,
+) *filesHandler {
 	return &filesHandler{
-		urlPattern: regexp.MustCompile(`^/v1/files/(?P<versionInPath>\d+\.\d+\.\d+)/batect-(?P<versionInFileName>\d+\.\d+\.\d+).jar$`),
+		urlPattern: regexp.MustCompile(`^/v1/files/(?P<version>[^/]+)/(?P<fileName>[^/]+)$`),
+		product:    product,
 		eventSink:  eventSink,
+		releases:   releases,
+		yanked:     yanked,
+		manifests:  manifests,
+		random:     random,
 	}
 }
 
+func NewProxyingFilesHandler(
+	product Product,
+	eventSink events.EventSink,
+	releases storage.ReleaseHistoryStore,
+	yanked storage.YankedVersionStore,
+	manifests storage.ReleaseManifestStore,
+	cache storage.ArtifactCache,
+	client *http.Client,
+) http.Handler {
+	handler := newFilesHandler(product, eventSink, releases, yanked, manifests, cryptoRandomInt)
+	handler.cache = cache
+	handler.client = client
+
+	return handler
+}
+
 func (h *filesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if !requireMethod(w, req, http.MethodGet) {
 		return
@@ -50,19 +137,484 @@ func (h *filesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	versionInPath := match[1]
+	version, fileName := match[1], match[2]
+	alias := ""
+
+	if isVersionAlias(version) {
+		resolved, ok := h.resolveAlias(w, req, version)
 
-	if versionInFileName := match[2]; versionInPath != versionInFileName {
+		if !ok {
+			return
+		}
+
+		alias, version = version, resolved.String()
+	}
+
+	parsedVersion, err := semver.Parse(version)
+
+	if err != nil {
 		http.NotFound(w, req)
 		return
 	}
 
-	version := versionInPath
-	fileName := "batect-" + version + ".jar"
+	artifacts := h.releaseArtifacts(req, version)
+
+	artifact, extension, ok := resolveArtifact(fileName, func(fileName string) (Artifact, bool) {
+		return findArtifact(artifacts, version, fileName)
+	})
+
+	if !ok && alias != "" {
+		artifact, extension, ok = resolveArtifact(fileName, func(fileName string) (Artifact, bool) {
+			return findVersionlessArtifact(artifacts, fileName)
+		})
+	}
+
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	fileName = artifact.fileNameForVersion(version)
+
+	if !h.isReleased(req, parsedVersion) {
+		if extension == "" {
+			h.eventSink.PostFileNotFound(req.Context(), events.FileNotFound{
+				Product:   h.product.Name,
+				UserAgent: req.UserAgent(),
+				Version:   version,
+				FileName:  fileName,
+			})
+		}
 
-	h.eventSink.PostFileDownload(req.Context(), req.UserAgent(), version, fileName)
+		notFound(req.Context(), w, fmt.Sprintf("Version %v of %v has not been released", version, h.product.Name))
 
-	w.Header().Set("Location", "https://github.com/batect/batect/releases/download/"+versionInPath+"/"+fileName)
+		return
+	}
+
+	// Checksums and signatures of yanked versions are still served, so that existing copies of their files can be verified.
+	if extension != "" {
+		h.serveChecksum(w, req, version, alias, fileName, extension)
+		return
+	}
+
+	if yanked, ok := h.findYankedVersion(req, version); ok {
+		h.serveYankedVersion(w, req, yanked, alias, artifact)
+		return
+	}
+
+	h.setDigestHeaders(w, req, version, fileName)
+
+	if h.cache != nil {
+		h.serveArtifact(w, req, version, alias, artifact)
+		return
+	}
+
+	origin := h.product.origin(req, artifact, h.random)
+	h.postFileDownload(req, version, alias, artifact, origin.Name)
+
+	// This also stops clients from caching the version an alias resolves to.
+	w.Header().Set("Location", origin.url(version, fileName))
 	w.Header().Set("Cache-Control", "no-store, max-age=0")
 	w.WriteHeader(http.StatusFound)
 }
+
+// Unlike isReleased, resolveAlias can't fail open if the release history can't be read.
+func (h *filesHandler) resolveAlias(w http.ResponseWriter, req *http.Request, alias string) (semver.Version, bool) {
+	releases, err := h.releases.GetReleases(req.Context())
+
+	if err != nil {
+		log := middleware.LoggerFromContext(req.Context())
+		log.WithError(err).WithField("alias", alias).Error("Getting release history failed.")
+		serviceUnavailable(req.Context(), w)
+
+		return semver.Version{}, false
+	}
+
+	version, ok := resolveVersionAlias(alias, releases, h.getYankedVersions(req))
+
+	if !ok {
+		notFound(req.Context(), w, fmt.Sprintf("No release of %v matches '%v'", h.product.Name, alias))
+		return semver.Version{}, false
+	}
+
+	return version, true
+}
+
+func (h *filesHandler) releaseArtifacts(req *http.Request, version string) []Artifact {
+	manifest, err := h.manifests.GetReleaseManifest(req.Context(), version)
+
+	if err != nil {
+		if !errors.Is(err, storage.ErrObjectNotFound) {
+			log := middleware.LoggerFromContext(req.Context())
+			log.WithError(err).WithField("version", version).Warn("Getting release manifest failed, using the product's artifacts.")
+		}
+
+		return h.product.Artifacts
+	}
+
+	if artifacts := manifestArtifacts(manifest, version); len(artifacts) > 0 {
+		return artifacts
+	}
+
+	return h.product.Artifacts
+}
+
+// Artifacts take precedence over checksum extensions, so that a product can publish its own checksum files.
+func resolveArtifact(fileName string, find func(fileName string) (Artifact, bool)) (Artifact, string, bool) {
+	if artifact, ok := find(fileName); ok {
+		return artifact, "", true
+	}
+
+	fileName, extension := splitChecksumExtension(fileName)
+
+	if extension == "" {
+		return Artifact{}, "", false
+	}
+
+	artifact, ok := find(fileName)
+
+	return artifact, extension, ok
+}
+
+// isReleased fails open if the release history can't be read or hasn't been published yet, as refusing every download would be worse.
+//
+// Build metadata is significant here, as it forms part of the names of the version's files.
+func (h *filesHandler) isReleased(req *http.Request, version semver.Version) bool {
+	releases, err := h.releases.GetReleases(req.Context())
+
+	if err != nil {
+		log := middleware.LoggerFromContext(req.Context())
+		log.WithError(err).Warn("Getting release history failed, assuming requested version has been released.")
+
+		return true
+	}
+
+	if len(releases) == 0 {
+		return true
+	}
+
+	for _, release := range releases {
+		if release.Version.String() == version.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *filesHandler) serveChecksum(w http.ResponseWriter, req *http.Request, version string, alias string, fileName string, extension string) {
+	manifest, err := h.manifests.GetReleaseManifest(req.Context(), version)
+
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		notFound(req.Context(), w, fmt.Sprintf("No checksums are available for version %v", version))
+		return
+	}
+
+	if err != nil {
+		log := middleware.LoggerFromContext(req.Context())
+		log.WithError(err).WithField("version", version).Error("Getting release manifest failed.")
+		serviceUnavailable(req.Context(), w)
+
+		return
+	}
+
+	file, ok := manifest.File(fileName)
+
+	if !ok {
+		notFound(req.Context(), w, fmt.Sprintf("No checksums are available for '%v'", fileName))
+		return
+	}
+
+	if extension == signatureExtension && file.Signature == "" {
+		notFound(req.Context(), w, fmt.Sprintf("No signature is available for '%v'", fileName))
+		return
+	}
+
+	w.Header().Set("Cache-Control", publicCacheControl(alias, 3600))
+
+	// This is the same format as sha256sum and sha512sum use.
+	switch extension {
+	case sha256Extension:
+		writeText(req.Context(), w, "text/plain", file.SHA256+"  "+fileName+"\n")
+	case sha512Extension:
+		writeText(req.Context(), w, "text/plain", file.SHA512+"  "+fileName+"\n")
+	case signatureExtension:
+		writeText(req.Context(), w, "application/pgp-signature", file.Signature)
+	}
+}
+
+// setDigestHeaders uses both the Digest format from RFC 3230 and the Repr-Digest format from RFC 9530.
+func (h *filesHandler) setDigestHeaders(w http.ResponseWriter, req *http.Request, version string, fileName string) {
+	manifest, err := h.manifests.GetReleaseManifest(req.Context(), version)
+
+	if err != nil {
+		if !errors.Is(err, storage.ErrObjectNotFound) {
+			log := middleware.LoggerFromContext(req.Context())
+			log.WithError(err).WithField("version", version).Warn("Getting release manifest failed, serving file without digests.")
+		}
+
+		return
+	}
+
+	file, ok := manifest.File(fileName)
+
+	if !ok {
+		return
+	}
+
+	// The manifest store has already validated the digests, so these can't fail.
+	sha256Digest, _ := hex.DecodeString(file.SHA256)
+	sha512Digest, _ := hex.DecodeString(file.SHA512)
+	encodedSHA256 := base64.StdEncoding.EncodeToString(sha256Digest)
+	encodedSHA512 := base64.StdEncoding.EncodeToString(sha512Digest)
+
+	w.Header().Set("Digest", fmt.Sprintf("sha-256=%v,sha-512=%v", encodedSHA256, encodedSHA512))
+	w.Header().Set("Repr-Digest", fmt.Sprintf("sha-256=:%v:, sha-512=:%v:", encodedSHA256, encodedSHA512))
+}
+
+func splitChecksumExtension(fileName string) (string, string) {
+	for _, extension := range []string{sha256Extension, sha512Extension, signatureExtension} {
+		if strings.HasSuffix(fileName, extension) {
+			return strings.TrimSuffix(fileName, extension), extension
+		}
+	}
+
+	return fileName, ""
+}
+
+// Everyone requesting the same uncached file at the same time shares a single fetch, and all but the first are served from the cache.
+func (h *filesHandler) serveArtifact(w http.ResponseWriter, req *http.Request, version string, alias string, artifact Artifact) {
+	if h.serveCachedArtifact(w, req, version, alias, artifact) {
+		return
+	}
+
+	fetched := false
+	_, err, _ := h.fetches.Do(version+"/"+artifact.fileNameForVersion(version), func() (interface{}, error) {
+		fetched = true
+
+		return nil, h.fetchArtifact(w, req, version, alias, artifact)
+	})
+
+	if !fetched {
+		if errors.Is(err, errUpstreamFileNotFound) {
+			http.NotFound(w, req)
+			return
+		}
+
+		if h.serveCachedArtifact(w, req, version, alias, artifact) {
+			return
+		}
+
+		err = h.fetchArtifact(w, req, version, alias, artifact)
+	}
+
+	// The file has already been sent, so abort the response so the client doesn't mistake it for a valid copy.
+	if errors.Is(err, errUpstreamFileDigestMismatch) {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (h *filesHandler) serveCachedArtifact(w http.ResponseWriter, req *http.Request, version string, alias string, artifact Artifact) bool {
+	fileName := artifact.fileNameForVersion(version)
+	cached, err := h.cache.GetArtifact(req.Context(), version, fileName)
+
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return false
+	}
+
+	if err != nil {
+		log := middleware.LoggerFromContext(req.Context()).WithField("version", version).WithField("fileName", fileName)
+		log.WithError(err).Error("Getting cached file failed.")
+		serviceUnavailable(req.Context(), w)
+
+		return true
+	}
+
+	defer cached.Content.Close()
+
+	// Clients resuming a download shouldn't be counted again.
+	if !isRangeContinuation(req) {
+		h.postFileDownload(req, version, alias, artifact, cachedArtifactOrigin)
+	}
+
+	w.Header().Set(contentTypeHeader, cached.ContentType)
+	w.Header().Set("ETag", cached.ETag)
+	w.Header().Set("Cache-Control", publicCacheControl(alias, 86400))
+
+	http.ServeContent(w, req, fileName, cached.LastModified, cached.Content)
+
+	return true
+}
+
+// Range requests aren't supported here, as the whole file is needed to cache it.
+func (h *filesHandler) fetchArtifact(w http.ResponseWriter, req *http.Request, version string, alias string, artifact Artifact) error {
+	ctx := req.Context()
+	fileName := artifact.fileNameForVersion(version)
+	origin := h.product.origin(req, artifact, h.random)
+	log := middleware.LoggerFromContext(ctx).WithField("version", version).WithField("fileName", fileName).WithField("origin", origin.Name)
+	resp, err := h.getUpstreamFile(ctx, origin.url(version, fileName))
+
+	if errors.Is(err, errUpstreamFileNotFound) {
+		http.NotFound(w, req)
+		return err
+	}
+
+	if err != nil {
+		log.WithError(err).Error("Fetching file from upstream failed.")
+		badGateway(ctx, w)
+
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get(contentTypeHeader)
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if !isRangeContinuation(req) {
+		h.postFileDownload(req, version, alias, artifact, origin.Name)
+	}
+
+	w.Header().Set(contentTypeHeader, contentType)
+	w.Header().Set("Cache-Control", publicCacheControl(alias, 86400))
+
+	if resp.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.copyAndCache(ctx, w, resp.Body, version, fileName, contentType); err != nil {
+		log.WithError(err).Error("Serving file fetched from upstream failed.")
+		return err
+	}
+
+	return nil
+}
+
+func (h *filesHandler) getUpstreamFile(ctx context.Context, url string) (*http.Response, error) {
+	upstreamReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+
+	resp, err := h.client.Do(upstreamReq)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch file: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+
+		return nil, errUpstreamFileNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+
+		return nil, fmt.Errorf("could not fetch file: upstream returned HTTP %v", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// Files that aren't in a release manifest are cached without being verified.
+func (h *filesHandler) copyAndCache(ctx context.Context, w io.Writer, content io.Reader, version string, fileName string, contentType string) error {
+	log := middleware.LoggerFromContext(ctx).WithField("version", version).WithField("fileName", fileName)
+	expectedSHA256, err := h.expectedSHA256(ctx, version, fileName)
+
+	if err != nil {
+		log.WithError(err).Warn("Getting release manifest failed, serving file fetched from upstream without caching it.")
+
+		_, err := io.Copy(w, content)
+
+		return err
+	}
+
+	cacheReader, cacheWriter := io.Pipe()
+	cached := make(chan error, 1)
+
+	go func() {
+		err := h.cache.PutArtifact(ctx, version, fileName, cacheReader, contentType)
+
+		_ = cacheReader.CloseWithError(err)
+		cached <- err
+	}()
+
+	digest := sha256.New()
+	_, err = io.Copy(w, io.TeeReader(content, io.MultiWriter(digest, &bestEffortWriter{w: cacheWriter})))
+
+	if err == nil && expectedSHA256 != "" && hex.EncodeToString(digest.Sum(nil)) != expectedSHA256 {
+		err = errUpstreamFileDigestMismatch
+	}
+
+	_ = cacheWriter.CloseWithError(err)
+
+	if cacheErr := <-cached; cacheErr != nil && err == nil {
+		log.WithError(cacheErr).Warn("Caching file fetched from upstream failed.")
+	}
+
+	return err
+}
+
+func (h *filesHandler) expectedSHA256(ctx context.Context, version string, fileName string) (string, error) {
+	manifest, err := h.manifests.GetReleaseManifest(ctx, version)
+
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	file, _ := manifest.File(fileName)
+
+	return file.SHA256, nil
+}
+
+// bestEffortWriter never reports a failure, so that failing to cache a file doesn't stop it being served.
+type bestEffortWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (b *bestEffortWriter) Write(p []byte) (int, error) {
+	if b.err == nil {
+		_, b.err = b.w.Write(p)
+	}
+
+	return len(p), nil
+}
+
+func (h *filesHandler) postFileDownload(req *http.Request, version string, alias string, artifact Artifact, origin string) {
+	h.eventSink.PostFileDownload(req.Context(), events.FileDownload{
+		Product:      h.product.Name,
+		UserAgent:    req.UserAgent(),
+		Version:      version,
+		FileName:     artifact.fileNameForVersion(version),
+		Origin:       origin,
+		ArtifactKind: string(artifact.Kind),
+		Platform:     artifact.Platform,
+		Alias:        alias,
+	})
+}
+
+func publicCacheControl(alias string, maxAge int) string {
+	if alias != "" && maxAge > aliasMaxAge {
+		maxAge = aliasMaxAge
+	}
+
+	return fmt.Sprintf("public, max-age=%v", maxAge)
+}
+
+func isRangeContinuation(req *http.Request) bool {
+	rangeHeader := strings.TrimSpace(req.Header.Get("Range"))
+
+	return rangeHeader != "" && !strings.HasPrefix(rangeHeader, "bytes=0-")
+}
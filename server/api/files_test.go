@@ -20,23 +20,81 @@
 package api_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/batect/services-common/middleware/testutils"
 	"github.com/batect/updates.batect.dev/server/api"
+	"github.com/batect/updates.batect.dev/server/events"
+	"github.com/batect/updates.batect.dev/server/storage"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("Files endpoint", func() {
 	var eventSink *mockEventSink
+	var releases storage.ReleaseHistoryStore
+	var yanked storage.YankedVersionStore
+	var manifestObjects storage.ObjectStore
 	var handler http.Handler
 	var resp *httptest.ResponseRecorder
 
+	exampleSHA256 := strings.Repeat("ab", 32)
+	exampleSHA512 := strings.Repeat("cd", 64)
+	base64SHA256 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xab}, 32))
+	base64SHA512 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xcd}, 64))
+	signature := "-----BEGIN PGP SIGNATURE-----\n\nabc123\n-----END PGP SIGNATURE-----\n"
+
 	BeforeEach(func() {
 		eventSink = newMockEventSink()
-		handler = api.NewFilesHandler(eventSink)
+		releases = releaseHistoryWithVersions("0.1.2", "0.1.3", "0.2.0", "0.3.0", "0.3.1", "0.4.0", "0.9.9", "0.85.0-rc.1", "1.0.0+build.5")
+		yanked = storage.NewYankedVersionStore(storage.NewInMemoryObjectStore(map[string]storage.Object{
+			"v1/yanked.json": {Content: []byte(`{
+				"versions": [
+					{"version": "0.3.0", "reason": "The JAR was corrupted", "replacement": "0.3.1"},
+					{"version": "0.4.0", "reason": "Containers are not cleaned up"}
+				]
+			}`)},
+		}))
+		manifestObjects = storage.NewInMemoryObjectStore(map[string]storage.Object{
+			"v1/manifests/0.1.2.json": {Content: []byte(`{
+				"files": [
+					{"name": "batect-0.1.2.jar", "sha256": "` + exampleSHA256 + `", "sha512": "` + exampleSHA512 + `", "signature": "-----BEGIN PGP SIGNATURE-----\n\nabc123\n-----END PGP SIGNATURE-----\n"},
+					{"name": "batect-0.1.2.zip", "sha256": "` + exampleSHA256 + `", "sha512": "` + exampleSHA512 + `"}
+				]
+			}`)},
+			"v1/manifests/0.2.0.json": {Content: []byte(`{"files": [{"name": "batect-0.2.0.jar", "sha256": "` + exampleSHA256 + `", "sha512": "` + exampleSHA512 + `"}]}`)},
+			"v1/manifests/0.9.9.json": {Content: []byte(`not JSON`)},
+			"v1/manifests/0.3.1.json": {Content: []byte(`{
+				"files": [
+					{"name": "batect-0.3.1.jar", "sha256": "` + exampleSHA256 + `", "sha512": "` + exampleSHA512 + `", "kind": "jar"},
+					{"name": "batect-0.3.1-installer.msi", "sha256": "` + exampleSHA256 + `", "sha512": "` + exampleSHA512 + `", "kind": "other", "platform": "windows"}
+				]
+			}`)},
+		})
+
+		handler = api.NewFilesHandler(api.Product{
+			Name: "batect",
+			Origins: []api.DownloadOrigin{
+				{Name: "github", URLTemplate: "https://github.com/batect/batect/releases/download/{version}/{fileName}"},
+				{Name: "artifactory", URLTemplate: "https://artifactory.example.com/batect/{version}/{fileName}"},
+			},
+			Artifacts: []api.Artifact{
+				{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar},
+				{FileName: "batect-{version}.zip", Kind: api.ArtifactKindOther},
+				{FileName: "batect.cmd", Kind: api.ArtifactKindWrapperScript, Platform: "windows"},
+				{FileName: "batect-{version}.spdx.json", Kind: api.ArtifactKindSBOM, RedirectTarget: "https://sboms.example.com/batect/{version}/{fileName}"},
+			},
+		}, eventSink, releases, yanked, storage.NewReleaseManifestStore(manifestObjects))
 		resp = httptest.NewRecorder()
 	})
 
@@ -97,40 +155,295 @@ var _ = Describe("Files endpoint", func() {
 			})
 
 			It("posts a 'file download' event", func() {
-				Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(fileDownloadEvent{
-					userAgent: "MyApp/1.2.3",
-					version:   "0.1.2",
-					fileName:  "batect-0.1.2.jar",
+				Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(events.FileDownload{
+					Product:      "batect",
+					UserAgent:    "MyApp/1.2.3",
+					Version:      "0.1.2",
+					FileName:     "batect-0.1.2.jar",
+					Origin:       "github",
+					ArtifactKind: "jar",
 				}))
 			})
+
+			It("includes the digests of the file from the release manifest", func() {
+				Expect(resp.Header()).To(HaveKeyWithValue("Digest", []string{"sha-256=" + base64SHA256 + ",sha-512=" + base64SHA512}))
+				Expect(resp.Header()).To(HaveKeyWithValue("Repr-Digest", []string{"sha-256=:" + base64SHA256 + ":, sha-512=:" + base64SHA512 + ":"}))
+			})
 		})
 
-		Context("when invoked with an invalid path", func() {
-			examples := []string{
-				"/",
-				"/v1",
-				"/v1/files",
-				"/v1/files/",
-				"/v1/files/0.1.2",
-				"/v1/files/0.1.2/",
-				"/v1/files/0.1.2/batect.jar",
-				"/v1/files/0.1.2/batect-0.1.2",
-				"/v1/files/0.1.2/batect-0.1.2.blah",
-				"/v1/files/0.1/batect-0.1.2.jar",
-				"/v1/files/0/batect-0.1.2.jar",
-				"/v1/files/blah/batect-0.1.2.jar",
-				"/v1/files/0.1.2/batect-0.1.jar",
-				"/v1/files/0.1.2/batect-0.jar",
-				"/v1/files/0.1.2/batect-blah.jar",
-				"/v1/files/0.1.2/batect-3.4.5.jar",
-				"/v1/files/0.1.2/batect-0.1.2.jar/thing",
-				"/v1/files/0.1.2/somethingelse-0.1.2.jar",
+		Context("when invoked with a valid path for a platform-specific artifact", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.1.2/batect.cmd", nil))
+				req.Header.Set("User-Agent", "MyApp/1.2.3")
+
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 302 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+			})
+
+			It("returns the GitHub download URL in the Location header", func() {
+				Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://github.com/batect/batect/releases/download/0.1.2/batect.cmd"}))
+			})
+
+			It("posts a 'file download' event with the kind and platform of the artifact", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(events.FileDownload{
+					Product:      "batect",
+					UserAgent:    "MyApp/1.2.3",
+					Version:      "0.1.2",
+					FileName:     "batect.cmd",
+					Origin:       "github",
+					ArtifactKind: "wrapper-script",
+					Platform:     "windows",
+				}))
+			})
+		})
+
+		Context("when invoked with a valid path for an artifact with its own redirect target", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.1.2/batect-0.1.2.spdx.json", nil))
+				req.Header.Set("X-Batect-Mirror", "artifactory")
+
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 302 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+			})
+
+			It("returns the artifact's redirect target in the Location header, regardless of the preferred mirror", func() {
+				Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://sboms.example.com/batect/0.1.2/batect-0.1.2.spdx.json"}))
+			})
+
+			It("posts a 'file download' event with the kind of the artifact and the redirect target as the origin", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(SatisfyAll(
+					HaveField("FileName", "batect-0.1.2.spdx.json"),
+					HaveField("ArtifactKind", "sbom"),
+					HaveField("Origin", "artifact-redirect"),
+				)))
+			})
+		})
+
+		Context("when invoked with a valid path for an artifact listed in the release's manifest", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.3.1/batect-0.3.1-installer.msi", nil))
+				req.Header.Set("User-Agent", "MyApp/1.2.3")
+
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns the GitHub download URL in the Location header", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+				Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://github.com/batect/batect/releases/download/0.3.1/batect-0.3.1-installer.msi"}))
+			})
+
+			It("posts a 'file download' event with the kind and platform of the artifact from the manifest", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(events.FileDownload{
+					Product:      "batect",
+					UserAgent:    "MyApp/1.2.3",
+					Version:      "0.3.1",
+					FileName:     "batect-0.3.1-installer.msi",
+					Origin:       "github",
+					ArtifactKind: "other",
+					Platform:     "windows",
+				}))
+			})
+		})
+
+		Context("when invoked through a version alias with the versionless name of an artifact listed in the release's manifest", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.3/batect-installer.msi", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns the download URL for the artifact from the release the alias resolves to", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+				Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://github.com/batect/batect/releases/download/0.3.1/batect-0.3.1-installer.msi"}))
+			})
+		})
+
+		Context("when invoked with a valid path for one of the product's artifacts that is not listed in the release's manifest", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.3.1/batect.cmd", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 404 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("does not post a 'file download' event", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+			})
+		})
+
+		Context("when invoked with a valid path for a version that has not been released", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/999.0.0/batect-999.0.0.jar", nil))
+				req.Header.Set("User-Agent", "MyApp/1.2.3")
+
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 404 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("returns a JSON error payload", func() {
+				Expect(resp.Body).To(MatchJSON(`{"message":"Version 999.0.0 of batect has not been released"}`))
+			})
+
+			It("does not post a 'file download' event", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+			})
+
+			It("posts a 'file not found' event", func() {
+				Expect(eventSink.FileNotFoundEventsPosted).To(ConsistOf(events.FileNotFound{
+					Product:   "batect",
+					UserAgent: "MyApp/1.2.3",
+					Version:   "999.0.0",
+					FileName:  "batect-999.0.0.jar",
+				}))
+			})
+		})
+
+		Context("when invoked with a path for the checksum of a file from a version that has not been released", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/999.0.0/batect-999.0.0.jar.sha256", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 404 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("does not post any events", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+				Expect(eventSink.FileNotFoundEventsPosted).To(BeEmpty())
+			})
+		})
+
+		Context("when the release history cannot be read", func() {
+			BeforeEach(func() {
+				handler = api.NewFilesHandler(api.Product{
+					Name:      "batect",
+					Origins:   []api.DownloadOrigin{{Name: "github", URLTemplate: "https://github.com/batect/batect/releases/download/{version}/{fileName}"}},
+					Artifacts: []api.Artifact{{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar}},
+				}, eventSink, storage.NewReleaseHistoryStore(storage.NewInMemoryObjectStore(nil)), yanked, storage.NewReleaseManifestStore(manifestObjects))
+
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/999.0.0/batect-999.0.0.jar", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("assumes the version has been released and redirects to the file", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+				Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://github.com/batect/batect/releases/download/999.0.0/batect-999.0.0.jar"}))
+			})
+		})
+
+		Context("when the release history has not been published yet", func() {
+			BeforeEach(func() {
+				handler = api.NewFilesHandler(api.Product{
+					Name:      "batect",
+					Origins:   []api.DownloadOrigin{{Name: "github", URLTemplate: "https://github.com/batect/batect/releases/download/{version}/{fileName}"}},
+					Artifacts: []api.Artifact{{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar}},
+				}, eventSink, releaseHistoryWithVersions(), yanked, storage.NewReleaseManifestStore(manifestObjects))
+
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/999.0.0/batect-999.0.0.jar", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("assumes the version has been released and redirects to the file", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+			})
+		})
+
+		Context("when invoked with a valid path for a version with no release manifest", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.1.3/batect-0.1.3.jar", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 302 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+			})
+
+			It("does not include any digests", func() {
+				Expect(resp.Header()).ToNot(HaveKey("Digest"))
+				Expect(resp.Header()).ToNot(HaveKey("Repr-Digest"))
+			})
+		})
+
+		Context("when invoked with a valid path for a version with a release manifest that cannot be read", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.9.9/batect-0.9.9.jar", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("still redirects to the file", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+				Expect(resp.Header()).To(HaveKey("Location"))
+			})
+
+			It("does not include any digests", func() {
+				Expect(resp.Header()).ToNot(HaveKey("Digest"))
+			})
+		})
+
+		Context("when invoked with a path for a checksum or signature", func() {
+			examples := []struct {
+				path        string
+				contentType string
+				body        string
+			}{
+				{"/v1/files/0.1.2/batect-0.1.2.jar.sha256", "text/plain; charset=utf-8", exampleSHA256 + "  batect-0.1.2.jar\n"},
+				{"/v1/files/0.1.2/batect-0.1.2.jar.sha512", "text/plain; charset=utf-8", exampleSHA512 + "  batect-0.1.2.jar\n"},
+				{"/v1/files/0.1.2/batect-0.1.2.zip.sha256", "text/plain; charset=utf-8", exampleSHA256 + "  batect-0.1.2.zip\n"},
+				{"/v1/files/0.1.2/batect-0.1.2.jar.asc", "application/pgp-signature; charset=utf-8", signature},
 			}
 
 			for _, e := range examples {
-				path := e
+				example := e
 
-				Context("given the invalid path '"+path+"'", func() {
+				Context("given the path '"+example.path+"'", func() {
+					BeforeEach(func() {
+						req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", example.path, nil))
+						handler.ServeHTTP(resp, req)
+					})
+
+					It("returns a HTTP 200 response", func() {
+						Expect(resp.Code).To(Equal(http.StatusOK))
+					})
+
+					It("returns the checksum or signature from the release manifest in the response body", func() {
+						Expect(resp.Body.String()).To(Equal(example.body))
+					})
+
+					It("sets the response Content-Type header", func() {
+						Expect(resp.Result().Header).To(HaveKeyWithValue("Content-Type", []string{example.contentType}))
+					})
+
+					It("allows the response to be cached", func() {
+						Expect(resp.Result().Header).To(HaveKeyWithValue("Cache-Control", []string{"public, max-age=3600"}))
+					})
+
+					It("does not post any events", func() {
+						Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+					})
+				})
+			}
+
+			notFoundExamples := map[string]string{
+				"/v1/files/0.1.2/batect-0.1.2.zip.asc":    "No signature is available for 'batect-0.1.2.zip'",
+				"/v1/files/0.1.3/batect-0.1.3.jar.sha256": "No checksums are available for version 0.1.3",
+			}
+
+			for path, message := range notFoundExamples {
+				path := path
+				message := message
+
+				Context("given the path '"+path+"' for a checksum or signature that is not in a release manifest", func() {
 					BeforeEach(func() {
 						req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
 						handler.ServeHTTP(resp, req)
@@ -140,16 +453,946 @@ var _ = Describe("Files endpoint", func() {
 						Expect(resp.Code).To(Equal(http.StatusNotFound))
 					})
 
-					It("includes the default Golang 404 error message in the body", func() {
-						Expect(resp.Body.String()).To(Equal("404 page not found\n"))
+					It("returns a JSON error payload", func() {
+						Expect(resp.Body).To(MatchJSON(fmt.Sprintf(`{"message":%q}`, message)))
 					})
+				})
+			}
 
-					It("does not post any events", func() {
-						Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+			Context("given the release manifest cannot be read", func() {
+				BeforeEach(func() {
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.9.9/batect-0.9.9.jar.sha256", nil))
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns a HTTP 503 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+				})
+			})
+		})
+
+		Context("when invoked with a mirror preference", func() {
+			examples := map[string]string{
+				"artifactory":                 "https://artifactory.example.com/batect/0.1.2/batect-0.1.2.jar",
+				"Artifactory":                 "https://artifactory.example.com/batect/0.1.2/batect-0.1.2.jar",
+				"something-else, artifactory": "https://artifactory.example.com/batect/0.1.2/batect-0.1.2.jar",
+				"github, artifactory":         "https://github.com/batect/batect/releases/download/0.1.2/batect-0.1.2.jar",
+				"something-else":              "https://github.com/batect/batect/releases/download/0.1.2/batect-0.1.2.jar",
+				"":                            "https://github.com/batect/batect/releases/download/0.1.2/batect-0.1.2.jar",
+			}
+
+			for preference, location := range examples {
+				preference := preference
+				location := location
+
+				Context("given the preference '"+preference+"'", func() {
+					BeforeEach(func() {
+						req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.1.2/batect-0.1.2.jar", nil))
+						req.Header.Set("X-Batect-Mirror", preference)
+
+						handler.ServeHTTP(resp, req)
+					})
+
+					It("returns a HTTP 302 response", func() {
+						Expect(resp.Code).To(Equal(http.StatusFound))
+					})
+
+					It("returns the download URL from the expected origin in the Location header", func() {
+						Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{location}))
 					})
 				})
 			}
+
+			Context("given the preference matches an origin", func() {
+				BeforeEach(func() {
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.1.2/batect-0.1.2.jar", nil))
+					req.Header.Set("X-Batect-Mirror", "artifactory")
+
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("records the origin used in the 'file download' event", func() {
+					Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(HaveField("Origin", "artifactory")))
+				})
+			})
 		})
 
-	})
-})
+		Context("when the product has weighted origins", func() {
+			var randomValue int
+			var randomBound int
+
+			BeforeEach(func() {
+				handler = api.NewFilesHandlerWithSpecificDependencies(api.Product{
+					Name: "batect",
+					Origins: []api.DownloadOrigin{
+						{Name: "internal", URLTemplate: "https://internal.example.com/{version}/{fileName}"},
+						{Name: "github", URLTemplate: "https://github.com/batect/batect/releases/download/{version}/{fileName}", Weight: 3},
+						{Name: "artifactory", URLTemplate: "https://artifactory.example.com/batect/{version}/{fileName}", Weight: 1},
+					},
+					Artifacts: []api.Artifact{{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar}},
+				}, eventSink, releases, yanked, storage.NewReleaseManifestStore(manifestObjects), func(n int) int {
+					randomBound = n
+					return randomValue
+				})
+			})
+
+			serve := func(preference string) {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.1.2/batect-0.1.2.jar", nil))
+
+				if preference != "" {
+					req.Header.Set("X-Batect-Mirror", preference)
+				}
+
+				handler.ServeHTTP(resp, req)
+			}
+
+			examples := map[int]string{
+				0: "https://github.com/batect/batect/releases/download/0.1.2/batect-0.1.2.jar",
+				2: "https://github.com/batect/batect/releases/download/0.1.2/batect-0.1.2.jar",
+				3: "https://artifactory.example.com/batect/0.1.2/batect-0.1.2.jar",
+			}
+
+			for value, location := range examples {
+				value := value
+				location := location
+
+				Context(fmt.Sprintf("given the random choice is %v", value), func() {
+					BeforeEach(func() {
+						randomValue = value
+						serve("")
+					})
+
+					It("chooses from the total weight of all origins", func() {
+						Expect(randomBound).To(Equal(4))
+					})
+
+					It("returns the download URL from the origin with that share of the total weight in the Location header", func() {
+						Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{location}))
+					})
+				})
+			}
+
+			Context("given the client prefers an origin without a weight", func() {
+				BeforeEach(func() {
+					serve("internal")
+				})
+
+				It("returns the download URL from that origin in the Location header", func() {
+					Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://internal.example.com/0.1.2/batect-0.1.2.jar"}))
+				})
+			})
+		})
+
+		Context("when invoked with a path for a file from a pre-release or a version with build metadata", func() {
+			examples := map[string]string{
+				"0.85.0-rc.1":   "batect-0.85.0-rc.1.jar",
+				"1.0.0+build.5": "batect-1.0.0+build.5.jar",
+			}
+
+			for version, fileName := range examples {
+				version := version
+				fileName := fileName
+
+				Context("given the version '"+version+"'", func() {
+					BeforeEach(func() {
+						req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/"+version+"/"+fileName, nil))
+						handler.ServeHTTP(resp, req)
+					})
+
+					It("returns a HTTP 302 response", func() {
+						Expect(resp.Code).To(Equal(http.StatusFound))
+					})
+
+					It("returns the download URL for that file in the Location header", func() {
+						Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://github.com/batect/batect/releases/download/" + version + "/" + fileName}))
+					})
+
+					It("posts a 'file download' event with the full version", func() {
+						Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(HaveField("Version", version)))
+					})
+				})
+			}
+		})
+
+		Context("when invoked with a version alias", func() {
+			examples := []struct {
+				path             string
+				expectedVersion  string
+				expectedFileName string
+			}{
+				{"/v1/files/latest/batect.jar", "1.0.0+build.5", "batect-1.0.0+build.5.jar"},
+				{"/v1/files/stable/batect.jar", "1.0.0+build.5", "batect-1.0.0+build.5.jar"},
+				{"/v1/files/0/batect.jar", "0.9.9", "batect-0.9.9.jar"},
+				{"/v1/files/0.x/batect.jar", "0.9.9", "batect-0.9.9.jar"},
+				{"/v1/files/0.1/batect.jar", "0.1.3", "batect-0.1.3.jar"},
+				{"/v1/files/0.1.x/batect.jar", "0.1.3", "batect-0.1.3.jar"},
+				{"/v1/files/0.1/batect-0.1.3.jar", "0.1.3", "batect-0.1.3.jar"},
+			}
+
+			for _, example := range examples {
+				example := example
+
+				Context("given the path '"+example.path+"'", func() {
+					BeforeEach(func() {
+						req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", example.path, nil))
+						req.Header.Set("User-Agent", "MyApp/1.2.3")
+
+						handler.ServeHTTP(resp, req)
+					})
+
+					It("returns a HTTP 302 response", func() {
+						Expect(resp.Code).To(Equal(http.StatusFound))
+					})
+
+					It("returns the download URL for the file from the newest matching release in the Location header", func() {
+						Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{
+							"https://github.com/batect/batect/releases/download/" + example.expectedVersion + "/" + example.expectedFileName,
+						}))
+					})
+
+					It("prevents caching of the response", func() {
+						Expect(resp.Result().Header).To(HaveKeyWithValue("Cache-Control", []string{"no-store, max-age=0"}))
+					})
+
+					It("posts a 'file download' event with both the alias and the version it resolved to", func() {
+						alias := strings.Split(example.path, "/")[3]
+
+						Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(events.FileDownload{
+							Product:      "batect",
+							UserAgent:    "MyApp/1.2.3",
+							Version:      example.expectedVersion,
+							FileName:     example.expectedFileName,
+							Origin:       "github",
+							ArtifactKind: "jar",
+							Alias:        alias,
+						}))
+					})
+				})
+			}
+
+			Context("given the newest matching release has been yanked", func() {
+				BeforeEach(func() {
+					releases := storage.NewReleaseHistoryStore(storage.NewInMemoryObjectStore(map[string]storage.Object{
+						"v1/releases.json": {Content: []byte(`{"releases": [
+							{"version": "0.2.0", "releaseDate": "2023-01-02T00:00:00Z", "url": "https://github.com/batect/batect/releases/tag/0.2.0", "status": "yanked"},
+							{"version": "0.1.5", "releaseDate": "2023-01-01T00:00:00Z", "url": "https://github.com/batect/batect/releases/tag/0.1.5", "status": "current"}
+						]}`)},
+					}))
+
+					handler = api.NewFilesHandler(api.Product{
+						Name:      "batect",
+						Origins:   []api.DownloadOrigin{{Name: "github", URLTemplate: "https://github.com/batect/batect/releases/download/{version}/{fileName}"}},
+						Artifacts: []api.Artifact{{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar}},
+					}, eventSink, releases, yanked, storage.NewReleaseManifestStore(manifestObjects))
+
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/latest/batect.jar", nil))
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns the download URL for the file from the newest release that has not been yanked", func() {
+					Expect(resp.Code).To(Equal(http.StatusFound))
+					Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://github.com/batect/batect/releases/download/0.1.5/batect-0.1.5.jar"}))
+				})
+			})
+
+			Context("given no release matches the alias", func() {
+				BeforeEach(func() {
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/2.x/batect.jar", nil))
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns a HTTP 404 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusNotFound))
+				})
+
+				It("returns a JSON error payload", func() {
+					Expect(resp.Body).To(MatchJSON(`{"message":"No release of batect matches '2.x'"}`))
+				})
+
+				It("does not post a 'file download' event", func() {
+					Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+				})
+			})
+
+			for _, alias := range []string{"99999999999999999999", "0.99999999999999999999"} {
+				alias := alias
+
+				Context("given the alias '"+alias+"' is too large to be a valid version range", func() {
+					BeforeEach(func() {
+						req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/"+alias+"/batect.jar", nil))
+						handler.ServeHTTP(resp, req)
+					})
+
+					It("returns a HTTP 404 response", func() {
+						Expect(resp.Code).To(Equal(http.StatusNotFound))
+					})
+
+					It("returns a JSON error payload", func() {
+						Expect(resp.Body).To(MatchJSON(`{"message":"No release of batect matches '` + alias + `'"}`))
+					})
+
+					It("does not post a 'file download' event", func() {
+						Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+					})
+				})
+			}
+
+			Context("given the file name includes a different version to the one the alias resolves to", func() {
+				BeforeEach(func() {
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.1/batect-0.1.2.jar", nil))
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns a HTTP 404 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusNotFound))
+				})
+			})
+
+			DescribeTable("given the file name leaves out the version",
+				func(fileNameTemplate string, path string, expectedFileName string) {
+					handler = api.NewFilesHandler(api.Product{
+						Name:      "my-plugin",
+						Origins:   []api.DownloadOrigin{{Name: "github", URLTemplate: "https://github.com/batect/my-plugin/releases/download/{version}/{fileName}"}},
+						Artifacts: []api.Artifact{{FileName: fileNameTemplate, Kind: api.ArtifactKindOther}},
+					}, eventSink, releases, yanked, storage.NewReleaseManifestStore(manifestObjects))
+
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+					handler.ServeHTTP(resp, req)
+
+					Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://github.com/batect/my-plugin/releases/download/1.0.0+build.5/" + expectedFileName}))
+				},
+				Entry("version after a hyphen", "my-plugin-{version}.spdx.json", "/v1/files/latest/my-plugin.spdx.json", "my-plugin-1.0.0+build.5.spdx.json"),
+				Entry("version after an underscore", "my_plugin_{version}.zip", "/v1/files/latest/my_plugin.zip", "my_plugin_1.0.0+build.5.zip"),
+				Entry("version at the start", "{version}-notes.txt", "/v1/files/latest/notes.txt", "1.0.0+build.5-notes.txt"),
+				Entry("no version", "my-plugin.zip", "/v1/files/latest/my-plugin.zip", "my-plugin.zip"),
+			)
+
+			Context("given the file name includes the alias", func() {
+				BeforeEach(func() {
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.1/batect-0.1.jar", nil))
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns a HTTP 404 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusNotFound))
+				})
+			})
+
+			Context("given the release history cannot be read", func() {
+				BeforeEach(func() {
+					handler = api.NewFilesHandler(api.Product{
+						Name:      "batect",
+						Origins:   []api.DownloadOrigin{{Name: "github", URLTemplate: "https://github.com/batect/batect/releases/download/{version}/{fileName}"}},
+						Artifacts: []api.Artifact{{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar}},
+					}, eventSink, storage.NewReleaseHistoryStore(storage.NewInMemoryObjectStore(nil)), yanked, storage.NewReleaseManifestStore(manifestObjects))
+
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/latest/batect.jar", nil))
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns a HTTP 503 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+				})
+			})
+		})
+
+		Context("when invoked with a path for the checksum of a file through a version alias", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.2/batect.jar.sha256", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns the checksum of the file from the newest matching release", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body.String()).To(Equal(exampleSHA256 + "  batect-0.2.0.jar\n"))
+			})
+
+			It("only allows the response to be cached briefly", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Cache-Control", []string{"public, max-age=300"}))
+			})
+		})
+
+		Context("when invoked with a valid path for a version that has been yanked and has a replacement", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.3.0/batect-0.3.0.jar", nil))
+				req.Header.Set("User-Agent", "MyApp/1.2.3")
+
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 302 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+			})
+
+			It("redirects to the same file from the replacement version", func() {
+				Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"../0.3.1/batect-0.3.1.jar"}))
+			})
+
+			It("returns a JSON payload explaining why the version was yanked", func() {
+				Expect(resp.Body).To(MatchJSON(`{
+					"message": "Version 0.3.0 of batect has been yanked, use version 0.3.1 instead",
+					"version": "0.3.0",
+					"reason": "The JAR was corrupted",
+					"replacement": "0.3.1"
+				}`))
+			})
+
+			It("prevents caching of the response", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Cache-Control", []string{"no-store, max-age=0"}))
+			})
+
+			It("posts a 'file blocked' event", func() {
+				Expect(eventSink.FileBlockedEventsPosted).To(ConsistOf(events.FileBlocked{
+					Product:     "batect",
+					UserAgent:   "MyApp/1.2.3",
+					Version:     "0.3.0",
+					FileName:    "batect-0.3.0.jar",
+					Replacement: "0.3.1",
+				}))
+			})
+
+			It("does not post a 'file download' event", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+			})
+		})
+
+		Context("when invoked with a valid path for a version that has been yanked and has no replacement", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.4.0/batect-0.4.0.jar", nil))
+				req.Header.Set("User-Agent", "MyApp/1.2.3")
+
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 410 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusGone))
+			})
+
+			It("returns a JSON payload explaining why the version was yanked", func() {
+				Expect(resp.Body).To(MatchJSON(`{
+					"message": "Version 0.4.0 of batect has been yanked and can no longer be downloaded",
+					"version": "0.4.0",
+					"reason": "Containers are not cleaned up"
+				}`))
+			})
+
+			It("does not return a Location header", func() {
+				Expect(resp.Header()).ToNot(HaveKey("Location"))
+			})
+
+			It("posts a 'file blocked' event", func() {
+				Expect(eventSink.FileBlockedEventsPosted).To(ConsistOf(events.FileBlocked{
+					Product:   "batect",
+					UserAgent: "MyApp/1.2.3",
+					Version:   "0.4.0",
+					FileName:  "batect-0.4.0.jar",
+				}))
+			})
+		})
+
+		Context("when invoked with a path for the checksum of a file from a version that has been yanked", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.4.0/batect-0.4.0.jar.sha256", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("does not block the request", func() {
+				Expect(resp.Code).ToNot(Equal(http.StatusGone))
+				Expect(eventSink.FileBlockedEventsPosted).To(BeEmpty())
+			})
+		})
+
+		Context("when invoked with a valid path for a version that is only marked as yanked in the release history", func() {
+			BeforeEach(func() {
+				releases := storage.NewReleaseHistoryStore(storage.NewInMemoryObjectStore(map[string]storage.Object{
+					"v1/releases.json": {Content: []byte(`{"releases": [
+						{"version": "0.2.0", "releaseDate": "2023-01-02T00:00:00Z", "url": "https://github.com/batect/batect/releases/tag/0.2.0", "status": "yanked"}
+					]}`)},
+				}))
+
+				handler = api.NewFilesHandler(api.Product{
+					Name:      "batect",
+					Origins:   []api.DownloadOrigin{{Name: "github", URLTemplate: "https://github.com/batect/batect/releases/download/{version}/{fileName}"}},
+					Artifacts: []api.Artifact{{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar}},
+				}, eventSink, releases, yanked, storage.NewReleaseManifestStore(manifestObjects))
+
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.2.0/batect-0.2.0.jar", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 410 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusGone))
+				Expect(resp.Body).To(MatchJSON(`{
+					"message": "Version 0.2.0 of batect has been yanked and can no longer be downloaded",
+					"version": "0.2.0",
+					"reason": "This version is marked as yanked in the release history."
+				}`))
+			})
+
+			It("posts a 'file blocked' event rather than a 'file download' event", func() {
+				Expect(eventSink.FileBlockedEventsPosted).To(HaveLen(1))
+				Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+			})
+		})
+
+		Context("when invoked with a version alias that would otherwise resolve to a version that has been yanked", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.4/batect.jar", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("does not resolve the alias to the yanked version", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+				Expect(resp.Body).To(MatchJSON(`{"message":"No release of batect matches '0.4'"}`))
+			})
+		})
+
+		Context("when the yanked versions cannot be read", func() {
+			BeforeEach(func() {
+				yanked = storage.NewYankedVersionStore(storage.NewInMemoryObjectStore(map[string]storage.Object{
+					"v1/yanked.json": {Content: []byte(`not JSON`)},
+				}))
+
+				handler = api.NewFilesHandler(api.Product{
+					Name:      "batect",
+					Origins:   []api.DownloadOrigin{{Name: "github", URLTemplate: "https://github.com/batect/batect/releases/download/{version}/{fileName}"}},
+					Artifacts: []api.Artifact{{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar}},
+				}, eventSink, releases, yanked, storage.NewReleaseManifestStore(manifestObjects))
+
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.4.0/batect-0.4.0.jar", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("assumes the version has not been yanked and redirects to the file", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+				Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://github.com/batect/batect/releases/download/0.4.0/batect-0.4.0.jar"}))
+			})
+		})
+
+		Context("when invoked with a path for another of the product's files", func() {
+			BeforeEach(func() {
+				req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/files/0.1.2/batect-0.1.2.zip", nil))
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 302 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusFound))
+			})
+
+			It("returns the download URL for that file in the Location header", func() {
+				Expect(resp.Header()).To(HaveKeyWithValue("Location", []string{"https://github.com/batect/batect/releases/download/0.1.2/batect-0.1.2.zip"}))
+			})
+		})
+
+		Context("when invoked with an invalid path", func() {
+			examples := []string{
+				"/",
+				"/v1",
+				"/v1/files",
+				"/v1/files/",
+				"/v1/files/0.1.2",
+				"/v1/files/0.1.2/",
+				"/v1/files/0.1.2/batect.jar",
+				"/v1/files/0.1.2/batect-0.1.2",
+				"/v1/files/0.1.2/batect-0.1.2.blah",
+				"/v1/files/0.1/batect-0.1.2.jar",
+				"/v1/files/0/batect-0.1.2.jar",
+				"/v1/files/blah/batect-0.1.2.jar",
+				"/v1/files/0.1.2/batect-0.1.jar",
+				"/v1/files/0.1.2/batect-0.jar",
+				"/v1/files/0.1.2/batect-blah.jar",
+				"/v1/files/0.1.2/batect-3.4.5.jar",
+				"/v1/files/0.1.2/batect-0.1.2.jar/thing",
+				"/v1/files/0.1.2/somethingelse-0.1.2.jar",
+				"/v1/files/0.1.2/batect-0.1.2.sha256",
+				"/v1/files/0.1.2/batect-0.1.3.jar.sha256",
+				"/v1/files/0.1.2/batect-0.1.2.jar.md5",
+				"/v1/files/01.1.2/batect-01.1.2.jar",
+				"/v1/files/0.85.0-rc.01/batect-0.85.0-rc.01.jar",
+				"/v1/files/0.85.0-rc.1/batect-0.85.0.jar",
+				"/v1/files/0.85.0-rc.1/batect-0.85.0-rc.2.jar",
+				"/v1/files/0.85.0-/batect-0.85.0-.jar",
+				"/v1/files/1.0.0+build.5/batect-1.0.0.jar",
+			}
+
+			for _, e := range examples {
+				path := e
+
+				Context("given the invalid path '"+path+"'", func() {
+					BeforeEach(func() {
+						req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+						handler.ServeHTTP(resp, req)
+					})
+
+					It("returns a HTTP 404 response", func() {
+						Expect(resp.Code).To(Equal(http.StatusNotFound))
+					})
+
+					It("includes the default Golang 404 error message in the body", func() {
+						Expect(resp.Body.String()).To(Equal("404 page not found\n"))
+					})
+
+					It("does not post any events", func() {
+						Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+					})
+				})
+			}
+		})
+
+	})
+})
+
+var _ = Describe("Files endpoint when proxying files", func() {
+	var eventSink *mockEventSink
+	var cacheObjects storage.ObjectStore
+	var releases storage.ReleaseHistoryStore
+	var yanked storage.YankedVersionStore
+	var manifestObjects storage.ObjectStore
+	var upstream *httptest.Server
+	var upstreamLock sync.Mutex
+	var upstreamRequests []string
+	var upstreamGate chan struct{}
+	var handler http.Handler
+	var resp *httptest.ResponseRecorder
+
+	jarContent := "this is the content of the jar"
+
+	BeforeEach(func() {
+		upstreamRequests = nil
+		upstreamGate = nil
+
+		upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			upstreamLock.Lock()
+			upstreamRequests = append(upstreamRequests, req.URL.Path)
+			gate := upstreamGate
+			upstreamLock.Unlock()
+
+			if gate != nil {
+				<-gate
+			}
+
+			switch req.URL.Path {
+			case "/batect/0.1.2/batect-0.1.2.jar", "/batect/0.2.0/batect-0.2.0.jar":
+				w.Header().Set("Content-Type", "application/java-archive")
+				_, _ = w.Write([]byte(jarContent))
+			case "/batect/0.3.0/batect-0.3.0.jar":
+				_, _ = w.Write([]byte("this is not the jar that was released"))
+			case "/batect/0.9.9/batect-0.9.9.jar":
+				w.WriteHeader(http.StatusInternalServerError)
+			default:
+				http.NotFound(w, req)
+			}
+		}))
+
+		eventSink = newMockEventSink()
+		cacheObjects = storage.NewInMemoryObjectStore(nil)
+		releases = releaseHistoryWithVersions("0.1.2", "0.1.3", "0.2.0", "0.3.0", "0.5.0", "0.9.9")
+		yanked = storage.NewYankedVersionStore(storage.NewInMemoryObjectStore(map[string]storage.Object{
+			"v1/yanked.json": {Content: []byte(`{"versions": [{"version": "0.5.0", "reason": "The JAR was corrupted"}]}`)},
+		}))
+		manifestObjects = storage.NewInMemoryObjectStore(map[string]storage.Object{
+			"v1/manifests/0.1.2.json": {Content: []byte(fmt.Sprintf(
+				`{"files": [{"name": "batect-0.1.2.jar", "sha256": "%x", "sha512": "%x"}]}`,
+				sha256.Sum256([]byte(jarContent)),
+				sha512.Sum512([]byte(jarContent)),
+			))},
+			"v1/manifests/0.3.0.json": {Content: []byte(fmt.Sprintf(
+				`{"files": [{"name": "batect-0.3.0.jar", "sha256": "%x", "sha512": "%x"}]}`,
+				sha256.Sum256([]byte(jarContent)),
+				sha512.Sum512([]byte(jarContent)),
+			))},
+		})
+		handler = api.NewProxyingFilesHandler(api.Product{
+			Name:      "batect",
+			Origins:   []api.DownloadOrigin{{Name: "upstream", URLTemplate: upstream.URL + "/batect/{version}/{fileName}"}},
+			Artifacts: []api.Artifact{{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar}},
+		}, eventSink, releases, yanked, storage.NewReleaseManifestStore(manifestObjects), storage.NewArtifactCache(cacheObjects), upstream.Client())
+		resp = httptest.NewRecorder()
+	})
+
+	AfterEach(func() {
+		upstream.Close()
+	})
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+		req.Header.Set("User-Agent", "MyApp/1.2.3")
+
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	Context("when the file has not been cached", func() {
+		BeforeEach(func() {
+			resp = get("/v1/files/0.1.2/batect-0.1.2.jar", nil)
+		})
+
+		It("returns a HTTP 200 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusOK))
+		})
+
+		It("returns the file from upstream in the response body", func() {
+			Expect(resp.Body.String()).To(Equal(jarContent))
+		})
+
+		It("sets the response headers", func() {
+			Expect(resp.Result().Header).To(HaveKeyWithValue("Content-Type", []string{"application/java-archive"}))
+			Expect(resp.Result().Header).To(HaveKeyWithValue("Content-Length", []string{fmt.Sprint(len(jarContent))}))
+		})
+
+		It("does not set validators, as the cached copy of the file does not exist until it has been served", func() {
+			Expect(resp.Result().Header).ToNot(HaveKey("Etag"))
+			Expect(resp.Result().Header).ToNot(HaveKey("Last-Modified"))
+		})
+
+		It("includes the digest of the file from the release manifest", func() {
+			digest := sha256.Sum256([]byte(jarContent))
+			Expect(resp.Result().Header.Get("Repr-Digest")).To(HavePrefix("sha-256=:" + base64.StdEncoding.EncodeToString(digest[:]) + ":, sha-512=:"))
+		})
+
+		It("caches the file", func() {
+			Expect(cacheObjects.GetObject(context.Background(), "v1/artifacts/0.1.2/batect-0.1.2.jar")).To(HaveField("Content", []byte(jarContent)))
+		})
+
+		It("posts a 'file download' event with the origin the file was fetched from", func() {
+			Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(events.FileDownload{
+				Product:      "batect",
+				UserAgent:    "MyApp/1.2.3",
+				Version:      "0.1.2",
+				FileName:     "batect-0.1.2.jar",
+				Origin:       "upstream",
+				ArtifactKind: "jar",
+			}))
+		})
+	})
+
+	Context("when the file is not in a release manifest", func() {
+		BeforeEach(func() {
+			resp = get("/v1/files/0.2.0/batect-0.2.0.jar", nil)
+		})
+
+		It("returns the file from upstream", func() {
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(Equal(jarContent))
+		})
+
+		It("caches the file without verifying it", func() {
+			Expect(cacheObjects.GetObject(context.Background(), "v1/artifacts/0.2.0/batect-0.2.0.jar")).To(HaveField("Content", []byte(jarContent)))
+		})
+	})
+
+	Context("when the file from upstream does not match the digest in its release manifest", func() {
+		It("aborts the response and does not cache the file", func() {
+			Expect(func() { get("/v1/files/0.3.0/batect-0.3.0.jar", nil) }).To(PanicWith(http.ErrAbortHandler))
+
+			_, err := cacheObjects.GetObject(context.Background(), "v1/artifacts/0.3.0/batect-0.3.0.jar")
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+		})
+	})
+
+	Context("when the same uncached file is requested several times at once", func() {
+		var responses []*httptest.ResponseRecorder
+
+		BeforeEach(func() {
+			upstreamGate = make(chan struct{})
+			results := make(chan *httptest.ResponseRecorder, 3)
+
+			for i := 0; i < 3; i++ {
+				go func() {
+					defer GinkgoRecover()
+
+					results <- get("/v1/files/0.1.2/batect-0.1.2.jar", nil)
+				}()
+			}
+
+			Eventually(func() []string {
+				upstreamLock.Lock()
+				defer upstreamLock.Unlock()
+
+				return upstreamRequests
+			}).ShouldNot(BeEmpty())
+
+			// Give the other requests a chance to reach upstream if they're not sharing the first request's fetch.
+			time.Sleep(100 * time.Millisecond)
+			close(upstreamGate)
+
+			responses = []*httptest.ResponseRecorder{<-results, <-results, <-results}
+		})
+
+		It("fetches the file from upstream once", func() {
+			Expect(upstreamRequests).To(HaveLen(1))
+		})
+
+		It("returns the file in every response", func() {
+			for _, r := range responses {
+				Expect(r.Code).To(Equal(http.StatusOK))
+				Expect(r.Body.String()).To(Equal(jarContent))
+			}
+		})
+	})
+
+	Context("when the file is requested through a version alias", func() {
+		BeforeEach(func() {
+			resp = get("/v1/files/0.2/batect.jar", nil)
+		})
+
+		It("returns the file from the newest matching release", func() {
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(upstreamRequests).To(ConsistOf("/batect/0.2.0/batect-0.2.0.jar"))
+		})
+
+		It("only allows the response to be cached briefly", func() {
+			Expect(resp.Result().Header).To(HaveKeyWithValue("Cache-Control", []string{"public, max-age=300"}))
+		})
+
+		It("posts a 'file download' event with both the alias and the version it resolved to", func() {
+			Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(SatisfyAll(HaveField("Alias", "0.2"), HaveField("Version", "0.2.0"))))
+		})
+	})
+
+	Context("when the file is from a version that has been yanked", func() {
+		BeforeEach(func() {
+			resp = get("/v1/files/0.5.0/batect-0.5.0.jar", nil)
+		})
+
+		It("returns a HTTP 410 response without fetching the file from upstream", func() {
+			Expect(resp.Code).To(Equal(http.StatusGone))
+			Expect(upstreamRequests).To(BeEmpty())
+		})
+
+		It("posts a 'file blocked' event rather than a 'file download' event", func() {
+			Expect(eventSink.FileBlockedEventsPosted).To(HaveLen(1))
+			Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+		})
+	})
+
+	Context("when the file has been cached", func() {
+		var etag string
+
+		BeforeEach(func() {
+			get("/v1/files/0.1.2/batect-0.1.2.jar", nil)
+			etag = get("/v1/files/0.1.2/batect-0.1.2.jar", nil).Header().Get("ETag")
+			Expect(etag).ToNot(BeEmpty())
+			upstreamRequests = nil
+			eventSink.FileDownloadEventsPosted = nil
+		})
+
+		Context("when the whole file is requested", func() {
+			BeforeEach(func() {
+				resp = get("/v1/files/0.1.2/batect-0.1.2.jar", nil)
+			})
+
+			It("returns the cached file without fetching it from upstream again", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body.String()).To(Equal(jarContent))
+				Expect(upstreamRequests).To(BeEmpty())
+			})
+
+			It("returns the same ETag as before", func() {
+				Expect(resp.Header()).To(HaveKeyWithValue("Etag", []string{etag}))
+			})
+
+			It("posts a 'file download' event recording that the file was served from the cache", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(ConsistOf(HaveField("Origin", "cache")))
+			})
+		})
+
+		Context("when a range at the start of the file is requested", func() {
+			BeforeEach(func() {
+				resp = get("/v1/files/0.1.2/batect-0.1.2.jar", map[string]string{"Range": "bytes=0-3"})
+			})
+
+			It("returns a HTTP 206 response with that part of the file", func() {
+				Expect(resp.Code).To(Equal(http.StatusPartialContent))
+				Expect(resp.Body.String()).To(Equal("this"))
+				Expect(resp.Header()).To(HaveKeyWithValue("Content-Range", []string{fmt.Sprintf("bytes 0-3/%v", len(jarContent))}))
+				Expect(resp.Header()).To(HaveKeyWithValue("Content-Length", []string{"4"}))
+			})
+
+			It("posts a 'file download' event", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(HaveLen(1))
+			})
+		})
+
+		Context("when a range continuing a download is requested", func() {
+			BeforeEach(func() {
+				resp = get("/v1/files/0.1.2/batect-0.1.2.jar", map[string]string{"Range": "bytes=5-"})
+			})
+
+			It("returns a HTTP 206 response with the rest of the file", func() {
+				Expect(resp.Code).To(Equal(http.StatusPartialContent))
+				Expect(resp.Body.String()).To(Equal(jarContent[5:]))
+			})
+
+			It("does not post a 'file download' event", func() {
+				Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+			})
+		})
+
+		Context("when the client already has the current version of the file", func() {
+			BeforeEach(func() {
+				resp = get("/v1/files/0.1.2/batect-0.1.2.jar", map[string]string{"If-None-Match": etag})
+			})
+
+			It("returns a HTTP 304 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotModified))
+				Expect(resp.Body.String()).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when the file does not exist upstream", func() {
+		BeforeEach(func() {
+			resp = get("/v1/files/0.1.3/batect-0.1.3.jar", nil)
+		})
+
+		It("returns a HTTP 404 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("does not post any events", func() {
+			Expect(eventSink.FileDownloadEventsPosted).To(BeEmpty())
+		})
+	})
+
+	Context("when fetching the file from upstream fails", func() {
+		BeforeEach(func() {
+			resp = get("/v1/files/0.9.9/batect-0.9.9.jar", nil)
+		})
+
+		It("returns a HTTP 502 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusBadGateway))
+		})
+
+		It("returns a JSON error payload", func() {
+			Expect(resp.Body).To(MatchJSON(`{"message":"Could not fetch the requested file from upstream"}`))
+		})
+
+		It("does not cache anything", func() {
+			_, err := cacheObjects.GetObject(context.Background(), "v1/artifacts/0.9.9/batect-0.9.9.jar")
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+		})
+	})
+})
+
+func releaseHistoryWithVersions(versions ...string) storage.ReleaseHistoryStore {
+	releases := make([]string, 0, len(versions))
+
+	for _, version := range versions {
+		releases = append(releases, fmt.Sprintf(
+			`{"version": "%v", "releaseDate": "2023-01-01T00:00:00Z", "url": "https://github.com/batect/batect/releases/tag/%v", "status": "current"}`,
+			version,
+			version,
+		))
+	}
+
+	return storage.NewReleaseHistoryStore(storage.NewInMemoryObjectStore(map[string]storage.Object{
+		"v1/releases.json": {Content: []byte(`{"releases": [` + strings.Join(releases, ", ") + `]}`)},
+	}))
+}
@@ -19,9 +19,21 @@
 
 package api
 
-import "net/http"
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/batect/updates.batect.dev/server/signing"
+)
+
+// signatureHeader contains a JWS with a detached payload (RFC 7515, appendix F) that signs the response body.
+const signatureHeader = "X-JWS-Signature"
 
-//nolint:unparam
 func requireMethod(w http.ResponseWriter, req *http.Request, method string) bool {
 	if req.Method != method {
 		methodNotAllowed(req.Context(), w, method)
@@ -30,3 +42,139 @@ func requireMethod(w http.ResponseWriter, req *http.Request, method string) bool
 
 	return true
 }
+
+// isNotModified reports whether the client already has the current representation of a resource, based on the request's
+// If-None-Match and If-Modified-Since headers, in which case a HTTP 304 response should be returned instead of the resource.
+//
+// As per RFC 9110, If-Modified-Since is ignored if If-None-Match is present.
+func isNotModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etag != "" && etagListContains(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince := req.Header.Get("If-Modified-Since")
+
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagListContains performs a weak comparison of etag against each of the entity tags in the header value list.
+func etagListContains(list string, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeJSON(ctx context.Context, w http.ResponseWriter, status int, body interface{}) {
+	writeSignedJSON(ctx, w, status, body, nil)
+}
+
+// If signer is nil, the response is not signed.
+func writeSignedJSON(ctx context.Context, w http.ResponseWriter, status int, body interface{}, signer signing.Signer) {
+	bytes, err := json.Marshal(body)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if signer != nil {
+		w.Header().Set(signatureHeader, signer.SignDetached(bytes))
+	}
+
+	w.Header().Set(contentTypeHeader, jsonMimeType)
+	w.WriteHeader(status)
+
+	if _, err := w.Write(bytes); err != nil {
+		log := middleware.LoggerFromContext(ctx)
+		log.WithError(err).Error("Writing response failed.")
+	}
+}
+
+func writeText(ctx context.Context, w http.ResponseWriter, contentType string, body string) {
+	w.Header().Set(contentTypeHeader, contentType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write([]byte(body)); err != nil {
+		log := middleware.LoggerFromContext(ctx)
+		log.WithError(err).Error("Writing response failed.")
+	}
+}
+
+// offered should be in order of preference.
+func negotiateContentType(req *http.Request, offered []string) (string, bool) {
+	accept := req.Header.Get("Accept")
+
+	if accept == "" {
+		return offered[0], true
+	}
+
+	best := ""
+	bestQuality := 0.0
+
+	for _, candidate := range offered {
+		if quality := acceptQuality(accept, candidate); quality > bestQuality {
+			best = candidate
+			bestQuality = quality
+		}
+	}
+
+	return best, best != ""
+}
+
+func acceptQuality(accept string, mediaType string) float64 {
+	quality := 0.0
+	specificity := -1
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		rangeType := strings.ToLower(strings.TrimSpace(params[0]))
+
+		var rangeSpecificity int
+
+		switch rangeType {
+		case mediaType:
+			rangeSpecificity = 2
+		case mainType + "/*":
+			rangeSpecificity = 1
+		case "*/*":
+			rangeSpecificity = 0
+		default:
+			continue
+		}
+
+		if rangeSpecificity <= specificity {
+			continue
+		}
+
+		specificity = rangeSpecificity
+		quality = 1.0
+
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+	}
+
+	return quality
+}
@@ -0,0 +1,46 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/batect/updates.batect.dev/server/signing"
+)
+
+type keysHandler struct {
+	signer signing.Signer
+}
+
+func NewKeysHandler(signer signing.Signer) http.Handler {
+	return &keysHandler{
+		signer: signer,
+	}
+}
+
+func (h *keysHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !requireMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	// Keys are published well before they are used for signing.
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(req.Context(), w, http.StatusOK, h.signer.PublicKeys())
+}
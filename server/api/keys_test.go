@@ -0,0 +1,93 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/api"
+	"github.com/batect/updates.batect.dev/server/signing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Keys endpoint", func() {
+	var handler http.Handler
+	var resp *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		handler = api.NewKeysHandler(&mockSigner{})
+		resp = httptest.NewRecorder()
+	})
+
+	Context("when invoked with a HTTP method other than GET", func() {
+		BeforeEach(func() {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("POST", "/v1/keys", nil))
+			handler.ServeHTTP(resp, req)
+		})
+
+		It("returns a HTTP 405 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("returns a JSON error payload", func() {
+			Expect(resp.Body).To(MatchJSON(`{"message":"This endpoint only supports GET requests"}`))
+		})
+	})
+
+	Context("when invoked with a HTTP GET", func() {
+		BeforeEach(func() {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/keys", nil))
+			handler.ServeHTTP(resp, req)
+		})
+
+		It("returns a HTTP 200 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusOK))
+		})
+
+		It("returns the public keys", func() {
+			Expect(resp.Body).To(MatchJSON(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"2023-01","use":"sig","alg":"EdDSA","x":"abc123"}]}`))
+		})
+
+		It("allows the response to be cached", func() {
+			Expect(resp.Header().Get("Cache-Control")).To(Equal("public, max-age=3600"))
+		})
+	})
+})
+
+type mockSigner struct {
+	contentSigned [][]byte
+}
+
+func (m *mockSigner) SignDetached(content []byte) string {
+	m.contentSigned = append(m.contentSigned, content)
+
+	return "header..signature"
+}
+
+func (m *mockSigner) PublicKeys() signing.JSONWebKeySet {
+	return signing.JSONWebKeySet{
+		Keys: []signing.JSONWebKey{
+			{KeyType: "OKP", Curve: "Ed25519", KeyID: "2023-01", Use: "sig", Algorithm: "EdDSA", X: "abc123"},
+		},
+	}
+}
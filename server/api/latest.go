@@ -20,22 +20,57 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/batect/services-common/middleware"
 	"github.com/batect/updates.batect.dev/server/events"
+	"github.com/batect/updates.batect.dev/server/signing"
 	"github.com/batect/updates.batect.dev/server/storage"
 )
 
+// stalenessHeader reports the number of seconds since the returned descriptor was last confirmed to be current.
+// It is only set if the descriptor could not be refreshed from storage and a previously retrieved copy was returned instead.
+const stalenessHeader = "X-Descriptor-Staleness"
+
+const sourceHeader = "X-Descriptor-Source"
+
 type latestHandler struct {
-	store     storage.LatestVersionStore
-	eventSink events.EventSink
+	channelURLPattern *regexp.Regexp
+	product           string
+	store             storage.LatestVersionStore
+	eventSink         events.EventSink
+	channels          map[string]struct{}
+	cacheControl      string
+	signer            signing.Signer
 }
 
-func NewLatestHandler(store storage.LatestVersionStore, eventSink events.EventSink) http.Handler {
+func NewLatestHandler(
+	product string,
+	store storage.LatestVersionStore,
+	eventSink events.EventSink,
+	channels []string,
+	cacheControl string,
+	signer signing.Signer,
+) http.Handler {
+	channelSet := make(map[string]struct{}, len(channels))
+
+	for _, channel := range channels {
+		channelSet[channel] = struct{}{}
+	}
+
 	return &latestHandler{
-		store:     store,
-		eventSink: eventSink,
+		channelURLPattern: regexp.MustCompile(`^/v1/channels/(?P<channel>[^/]+)/latest$`),
+		product:           product,
+		store:             store,
+		eventSink:         eventSink,
+		channels:          channelSet,
+		cacheControl:      cacheControl,
+		signer:            signer,
 	}
 }
 
@@ -44,23 +79,152 @@ func (h *latestHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	log := middleware.LoggerFromContext(req.Context())
+	channel, ok := h.channelForRequest(req)
+
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	if _, known := h.channels[channel]; !known {
+		notFound(req.Context(), w, fmt.Sprintf("The channel '%v' does not exist", channel))
+		return
+	}
+
+	log := middleware.LoggerFromContext(req.Context()).WithField("channel", channel)
 
-	descriptor, err := h.store.GetLatestVersionDescriptor(req.Context())
+	descriptor, err := h.store.GetLatestVersionDescriptor(req.Context(), channel)
+
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		notFound(req.Context(), w, fmt.Sprintf("The channel '%v' does not have a published version", channel))
+		return
+	}
 
 	if err != nil {
-		log.WithError(err).Error("Getting latest version descriptor failed.")
+		if errors.Is(err, storage.ErrInvalidVersionDescriptor) {
+			log.WithError(err).Error("Latest version descriptor is invalid.")
+		} else {
+			log.WithError(err).Error("Getting latest version descriptor failed.")
+		}
+
 		serviceUnavailable(req.Context(), w)
 
 		return
 	}
 
-	h.eventSink.PostLatestVersionCheck(req.Context(), req.UserAgent())
+	if descriptor.Staleness > 0 {
+		log.WithField("descriptorStaleness", descriptor.Staleness.String()).Warn("Serving stale latest version descriptor.")
+		w.Header().Set(stalenessHeader, strconv.Itoa(int(descriptor.Staleness.Seconds())))
+	}
+
+	if descriptor.Source != "" {
+		w.Header().Set(sourceHeader, string(descriptor.Source))
+	}
+
+	info, etag, check := h.resolveRollout(req, descriptor)
+	check.Product = h.product
+	check.UserAgent = req.UserAgent()
+	check.Channel = channel
+
+	response := latestResponse{VersionInfo: info}
+
+	if version, ok := clientVersion(req.UserAgent()); ok && descriptor.Info.Support != nil {
+		response.Upgrade = adviseUpgrade(version, info.Version, *descriptor.Info.Support)
+	}
+
+	if response.Upgrade != nil {
+		etag = etagWithSuffix(etag, response.Upgrade.etagSuffix())
+	}
+
+	h.eventSink.PostLatestVersionCheck(req.Context(), check)
+	h.setCachingHeaders(w, descriptor, etag)
+
+	if isNotModified(req, etag, descriptor.LastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeSignedJSON(req.Context(), w, http.StatusOK, response, h.signer)
+}
+
+type latestResponse struct {
+	storage.VersionInfo
+
+	// Upgrade is only set if the descriptor has a support policy and the client's version is known.
+	Upgrade *upgradeAdvice `json:"upgrade,omitempty"`
+}
+
+func (h *latestHandler) resolveRollout(req *http.Request, descriptor storage.VersionDescriptor) (storage.VersionInfo, string, events.LatestVersionCheck) {
+	rollout := descriptor.Info.Rollout
+
+	if rollout == nil {
+		return descriptor.Info.ForClient(true), descriptor.ETag, events.LatestVersionCheck{Version: descriptor.Info.Version.String()}
+	}
+
+	decision := events.RolloutDecisionPrevious
+
+	if receivesNewVersion(req, *rollout, descriptor.Info.Version.String()) {
+		decision = events.RolloutDecisionNew
+	}
+
+	info := descriptor.Info.ForClient(decision == events.RolloutDecisionNew)
+
+	check := events.LatestVersionCheck{
+		Version:           info.Version.String(),
+		RolloutDecision:   decision,
+		RolloutPercentage: rollout.Percentage,
+	}
+
+	return info, etagWithSuffix(descriptor.ETag, string(decision)), check
+}
+
+func etagWithSuffix(etag string, suffix string) string {
+	if etag == "" {
+		return ""
+	}
+
+	return strings.TrimSuffix(etag, `"`) + "-" + suffix + `"`
+}
+
+func (h *latestHandler) channelForRequest(req *http.Request) (string, bool) {
+	if req.URL.Path == "/v1/latest" {
+		if channel := req.URL.Query().Get("channel"); channel != "" {
+			return channel, true
+		}
+
+		return storage.StableChannel, true
+	}
+
+	if match := h.channelURLPattern.FindStringSubmatch(req.URL.Path); match != nil {
+		return match[1], true
+	}
+
+	return "", false
+}
 
-	w.Header().Set(contentTypeHeader, descriptor.ContentType)
+func (h *latestHandler) setCachingHeaders(w http.ResponseWriter, descriptor storage.VersionDescriptor, etag string) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	if !descriptor.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", descriptor.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// The response depends on who the client is during a rollout, so shared caches must not store it.
+	if descriptor.Info.Rollout != nil {
+		w.Header().Set("Cache-Control", "private, no-cache")
+		w.Header().Set("Vary", installationIDHeader+", User-Agent")
 
-	if _, err := w.Write(descriptor.Content); err != nil {
-		log.WithError(err).Error("Writing response failed.")
 		return
 	}
+
+	// Upgrade advice, and so the response, only depends on the client's version if there is a support policy.
+	if descriptor.Info.Support != nil {
+		w.Header().Set("Vary", "User-Agent")
+	}
+
+	if h.cacheControl != "" {
+		w.Header().Set("Cache-Control", h.cacheControl)
+	}
 }
@@ -22,14 +22,21 @@ package api_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 
 	"github.com/batect/services-common/middleware/testutils"
 	"github.com/batect/updates.batect.dev/server/api"
+	"github.com/batect/updates.batect.dev/server/events"
+	"github.com/batect/updates.batect.dev/server/semver"
 	"github.com/batect/updates.batect.dev/server/storage"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 )
 
 var _ = Describe("Latest version endpoint", func() {
@@ -41,7 +48,7 @@ var _ = Describe("Latest version endpoint", func() {
 	BeforeEach(func() {
 		eventSink = newMockEventSink()
 		latestVersionStoreMock = &mockLatestVersionStore{}
-		handler = api.NewLatestHandler(latestVersionStoreMock, eventSink)
+		handler = api.NewLatestHandler("batect", latestVersionStoreMock, eventSink, []string{"stable", "beta"}, "public, max-age=60", nil)
 		resp = httptest.NewRecorder()
 	})
 
@@ -74,9 +81,10 @@ var _ = Describe("Latest version endpoint", func() {
 
 	Context("when invoked with a HTTP GET", func() {
 		var req *http.Request
+		var hook *test.Hook
 
 		BeforeEach(func() {
-			req, _ = testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/latest", nil))
+			req, hook = testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/latest", nil))
 			req.Header.Set("User-Agent", "MyApp/1.2.3")
 		})
 
@@ -84,8 +92,219 @@ var _ = Describe("Latest version endpoint", func() {
 			BeforeEach(func() {
 				latestVersionStoreMock.errorToReturn = nil
 				latestVersionStoreMock.descriptorToReturn = storage.VersionDescriptor{
-					Content:     []byte(`{"some":"descriptor"}`),
-					ContentType: "application/json+descriptor",
+					Info: exampleVersionInfo(),
+				}
+
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 200 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+			})
+
+			It("retrieves the descriptor for the stable channel", func() {
+				Expect(latestVersionStoreMock.channelsRequested).To(ConsistOf("stable"))
+			})
+
+			It("returns the version descriptor in the response body", func() {
+				Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
+			})
+
+			It("sets the response Content-Type header", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Content-Type", []string{"application/json"}))
+			})
+
+			It("posts a 'latest version check' event", func() {
+				Expect(eventSink.LatestVersionCheckEventsPosted).To(ConsistOf(events.LatestVersionCheck{
+					Product:   "batect",
+					UserAgent: "MyApp/1.2.3",
+					Channel:   "stable",
+					Version:   "0.83.2",
+				}))
+			})
+
+			It("does not set the staleness header", func() {
+				Expect(resp.Result().Header).ToNot(HaveKey("X-Descriptor-Staleness"))
+			})
+
+			It("does not set the source header", func() {
+				Expect(resp.Result().Header).ToNot(HaveKey("X-Descriptor-Source"))
+			})
+
+			It("does not sign the response", func() {
+				Expect(resp.Result().Header).ToNot(HaveKey("X-Jws-Signature"))
+			})
+
+			It("does not set the ETag header", func() {
+				Expect(resp.Result().Header).ToNot(HaveKey("Etag"))
+			})
+
+			It("does not set the Last-Modified header", func() {
+				Expect(resp.Result().Header).ToNot(HaveKey("Last-Modified"))
+			})
+
+			It("sets the configured Cache-Control header", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Cache-Control", []string{"public, max-age=60"}))
+			})
+		})
+
+		Context("given retrieving the latest version information succeeds and the descriptor has validators", func() {
+			lastModified := time.Date(2021, 3, 1, 9, 54, 40, 123456789, time.UTC)
+
+			BeforeEach(func() {
+				latestVersionStoreMock.errorToReturn = nil
+				latestVersionStoreMock.descriptorToReturn = storage.VersionDescriptor{
+					Info:         exampleVersionInfo(),
+					ETag:         `"1234"`,
+					LastModified: lastModified,
+				}
+			})
+
+			Context("when the request has no conditional headers", func() {
+				BeforeEach(func() {
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns a HTTP 200 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusOK))
+				})
+
+				It("returns the version descriptor in the response body", func() {
+					Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
+				})
+
+				It("sets the ETag header", func() {
+					Expect(resp.Result().Header).To(HaveKeyWithValue("Etag", []string{`"1234"`}))
+				})
+
+				It("sets the Last-Modified header", func() {
+					Expect(resp.Result().Header).To(HaveKeyWithValue("Last-Modified", []string{"Mon, 01 Mar 2021 09:54:40 GMT"}))
+				})
+
+				It("sets the configured Cache-Control header", func() {
+					Expect(resp.Result().Header).To(HaveKeyWithValue("Cache-Control", []string{"public, max-age=60"}))
+				})
+			})
+
+			notModifiedExamples := map[string]map[string]string{
+				"a matching If-None-Match header":                             {"If-None-Match": `"1234"`},
+				"a matching weak If-None-Match header":                        {"If-None-Match": `W/"1234"`},
+				"an If-None-Match header with a list including a match":       {"If-None-Match": `"5678", "1234"`},
+				"a wildcard If-None-Match header":                             {"If-None-Match": `*`},
+				"an If-Modified-Since header equal to the last modified time": {"If-Modified-Since": "Mon, 01 Mar 2021 09:54:40 GMT"},
+				"an If-Modified-Since header after the last modified time":    {"If-Modified-Since": "Tue, 02 Mar 2021 00:00:00 GMT"},
+				"a matching If-None-Match header and an old If-Modified-Since header": {
+					"If-None-Match":     `"1234"`,
+					"If-Modified-Since": "Mon, 01 Feb 2021 00:00:00 GMT",
+				},
+			}
+
+			for description, headers := range notModifiedExamples {
+				headers := headers
+
+				Context("when the request has "+description, func() {
+					BeforeEach(func() {
+						for name, value := range headers {
+							req.Header.Set(name, value)
+						}
+
+						handler.ServeHTTP(resp, req)
+					})
+
+					It("returns a HTTP 304 response", func() {
+						Expect(resp.Code).To(Equal(http.StatusNotModified))
+					})
+
+					It("does not return a response body", func() {
+						Expect(resp.Body.String()).To(BeEmpty())
+					})
+
+					It("does not set the response Content-Type header", func() {
+						Expect(resp.Result().Header).ToNot(HaveKey("Content-Type"))
+					})
+
+					It("sets the ETag header", func() {
+						Expect(resp.Result().Header).To(HaveKeyWithValue("Etag", []string{`"1234"`}))
+					})
+
+					It("sets the configured Cache-Control header", func() {
+						Expect(resp.Result().Header).To(HaveKeyWithValue("Cache-Control", []string{"public, max-age=60"}))
+					})
+
+					It("posts a 'latest version check' event", func() {
+						Expect(eventSink.LatestVersionCheckEventsPosted).To(ConsistOf(events.LatestVersionCheck{
+							Product:   "batect",
+							UserAgent: "MyApp/1.2.3",
+							Channel:   "stable",
+							Version:   "0.83.2",
+						}))
+					})
+				})
+			}
+
+			modifiedExamples := map[string]map[string]string{
+				"a non-matching If-None-Match header":                       {"If-None-Match": `"5678"`},
+				"an If-Modified-Since header before the last modified time": {"If-Modified-Since": "Mon, 01 Mar 2021 09:54:39 GMT"},
+				"an invalid If-Modified-Since header":                       {"If-Modified-Since": "yesterday"},
+				"a non-matching If-None-Match header and a recent If-Modified-Since header": {
+					"If-None-Match":     `"5678"`,
+					"If-Modified-Since": "Tue, 02 Mar 2021 00:00:00 GMT",
+				},
+			}
+
+			for description, headers := range modifiedExamples {
+				headers := headers
+
+				Context("when the request has "+description, func() {
+					BeforeEach(func() {
+						for name, value := range headers {
+							req.Header.Set(name, value)
+						}
+
+						handler.ServeHTTP(resp, req)
+					})
+
+					It("returns a HTTP 200 response", func() {
+						Expect(resp.Code).To(Equal(http.StatusOK))
+					})
+
+					It("returns the version descriptor in the response body", func() {
+						Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
+					})
+				})
+			}
+		})
+
+		Context("given retrieving the latest version information returns a descriptor from a fallback source", func() {
+			BeforeEach(func() {
+				latestVersionStoreMock.errorToReturn = nil
+				latestVersionStoreMock.descriptorToReturn = storage.VersionDescriptor{
+					Info:   exampleVersionInfo(),
+					Source: storage.DescriptorSourceEmbedded,
+				}
+
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 200 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+			})
+
+			It("returns the version descriptor in the response body", func() {
+				Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
+			})
+
+			It("reports the source of the descriptor", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("X-Descriptor-Source", []string{"embedded"}))
+			})
+		})
+
+		Context("given retrieving the latest version information returns a stale descriptor", func() {
+			BeforeEach(func() {
+				latestVersionStoreMock.errorToReturn = nil
+				latestVersionStoreMock.descriptorToReturn = storage.VersionDescriptor{
+					Info:      exampleVersionInfo(),
+					Staleness: 90*time.Second + 400*time.Millisecond,
 				}
 
 				handler.ServeHTTP(resp, req)
@@ -96,16 +315,19 @@ var _ = Describe("Latest version endpoint", func() {
 			})
 
 			It("returns the version descriptor in the response body", func() {
-				Expect(resp.Body.String()).To(Equal(`{"some":"descriptor"}`))
+				Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
 			})
 
-			It("returns the content type provided by the version information source", func() {
-				Expect(resp.Result().Header).To(HaveKeyWithValue("Content-Type", []string{"application/json+descriptor"}))
+			It("reports the staleness of the descriptor in whole seconds", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("X-Descriptor-Staleness", []string{"90"}))
 			})
 
 			It("posts a 'latest version check' event", func() {
-				Expect(eventSink.LatestVersionCheckEventsPosted).To(ConsistOf(latestVersionCheckEvent{
-					userAgent: "MyApp/1.2.3",
+				Expect(eventSink.LatestVersionCheckEventsPosted).To(ConsistOf(events.LatestVersionCheck{
+					Product:   "batect",
+					UserAgent: "MyApp/1.2.3",
+					Channel:   "stable",
+					Version:   "0.83.2",
 				}))
 			})
 		})
@@ -134,14 +356,462 @@ var _ = Describe("Latest version endpoint", func() {
 				Expect(eventSink.LatestVersionCheckEventsPosted).To(BeEmpty())
 			})
 		})
+
+		Context("given the channel does not have a descriptor", func() {
+			BeforeEach(func() {
+				latestVersionStoreMock.errorToReturn = fmt.Errorf("could not get descriptor: %w", storage.ErrObjectNotFound)
+				latestVersionStoreMock.descriptorToReturn = storage.VersionDescriptor{}
+
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 404 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("returns a JSON error payload", func() {
+				Expect(resp.Body).To(MatchJSON(`{"message":"The channel 'stable' does not have a published version"}`))
+			})
+
+			It("does not log an error", func() {
+				for _, entry := range hook.AllEntries() {
+					Expect(entry.Level).ToNot(Equal(logrus.ErrorLevel))
+				}
+			})
+
+			It("does not post any events", func() {
+				Expect(eventSink.LatestVersionCheckEventsPosted).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when invoked with a HTTP GET and signing is enabled", func() {
+		var signer *mockSigner
+		var req *http.Request
+
+		BeforeEach(func() {
+			signer = &mockSigner{}
+			handler = api.NewLatestHandler("batect", latestVersionStoreMock, eventSink, []string{"stable", "beta"}, "public, max-age=60", signer)
+			latestVersionStoreMock.descriptorToReturn = storage.VersionDescriptor{
+				Info: exampleVersionInfo(),
+				ETag: `"1234"`,
+			}
+
+			req, _ = testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/latest", nil))
+		})
+
+		Context("given the client does not have the current descriptor", func() {
+			BeforeEach(func() {
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("signs the exact bytes of the response body", func() {
+				Expect(signer.contentSigned).To(Equal([][]byte{resp.Body.Bytes()}))
+			})
+
+			It("returns the signature in the response headers", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("X-Jws-Signature", []string{"header..signature"}))
+			})
+		})
+
+		Context("given the client already has the current descriptor", func() {
+			BeforeEach(func() {
+				req.Header.Set("If-None-Match", `"1234"`)
+				handler.ServeHTTP(resp, req)
+			})
+
+			It("returns a HTTP 304 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotModified))
+			})
+
+			It("does not sign the response, as it has no body", func() {
+				Expect(signer.contentSigned).To(BeEmpty())
+				Expect(resp.Result().Header).ToNot(HaveKey("X-Jws-Signature"))
+			})
+		})
+	})
+
+	Context("when invoked with a HTTP GET while a staged rollout is in progress", func() {
+		previousVersionInfo := storage.VersionInfo{
+			Version: semver.MustParse("0.83.1"),
+			URL:     "https://github.com/batect/batect/releases/tag/0.83.1",
+			Files:   []storage.VersionFile{{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.83.1/batect"}},
+		}
+
+		givenRolloutPercentage := func(percentage int) {
+			info := exampleVersionInfo()
+			info.Rollout = &storage.Rollout{Percentage: percentage, Previous: previousVersionInfo}
+
+			latestVersionStoreMock.descriptorToReturn = storage.VersionDescriptor{Info: info, ETag: `"1234"`}
+		}
+
+		send := func(headers ...string) *httptest.ResponseRecorder {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/latest", nil))
+			req.Header.Set("User-Agent", "MyApp/1.2.3")
+
+			for i := 0; i < len(headers); i += 2 {
+				req.Header.Set(headers[i], headers[i+1])
+			}
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+
+			return resp
+		}
+
+		Context("given the client has not been selected to receive the new version", func() {
+			BeforeEach(func() {
+				givenRolloutPercentage(0)
+				resp = send(installationIDHeader, "installation-1")
+			})
+
+			It("returns the previous version", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body).To(MatchJSON(`{"version":"0.83.1","url":"https://github.com/batect/batect/releases/tag/0.83.1","files":[{"type":"script","name":"batect","url":"https://github.com/batect/batect/releases/download/0.83.1/batect"}]}`))
+			})
+
+			It("returns an entity tag specific to the previous version", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Etag", []string{`"1234-previous"`}))
+			})
+
+			It("prevents shared caches from storing the response", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Cache-Control", []string{"private, no-cache"}))
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Vary", []string{"X-Installation-ID, User-Agent"}))
+			})
+
+			It("records the decision in the 'latest version check' event", func() {
+				Expect(eventSink.LatestVersionCheckEventsPosted).To(ConsistOf(events.LatestVersionCheck{
+					Product:           "batect",
+					UserAgent:         "MyApp/1.2.3",
+					Channel:           "stable",
+					Version:           "0.83.1",
+					RolloutDecision:   events.RolloutDecisionPrevious,
+					RolloutPercentage: 0,
+				}))
+			})
+		})
+
+		Context("given the client has been selected to receive the new version", func() {
+			BeforeEach(func() {
+				givenRolloutPercentage(100)
+				resp = send(installationIDHeader, "installation-1")
+			})
+
+			It("returns the new version, without the details of the rollout", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
+			})
+
+			It("returns an entity tag specific to the new version", func() {
+				Expect(resp.Result().Header).To(HaveKeyWithValue("Etag", []string{`"1234-new"`}))
+			})
+
+			It("records the decision in the 'latest version check' event", func() {
+				Expect(eventSink.LatestVersionCheckEventsPosted).To(ConsistOf(events.LatestVersionCheck{
+					Product:           "batect",
+					UserAgent:         "MyApp/1.2.3",
+					Channel:           "stable",
+					Version:           "0.83.2",
+					RolloutDecision:   events.RolloutDecisionNew,
+					RolloutPercentage: 100,
+				}))
+			})
+		})
+
+		Context("given the rollout is partially complete", func() {
+			BeforeEach(func() {
+				givenRolloutPercentage(30)
+			})
+
+			It("selects roughly the requested percentage of installations", func() {
+				selected := 0
+
+				for i := 0; i < 1000; i++ {
+					if send(installationIDHeader, fmt.Sprintf("installation-%v", i)).Result().Header.Get("ETag") == `"1234-new"` {
+						selected++
+					}
+				}
+
+				Expect(selected).To(BeNumerically("~", 300, 50))
+			})
+
+			It("consistently returns the same version to the same installation", func() {
+				for i := 0; i < 20; i++ {
+					id := fmt.Sprintf("installation-%v", i)
+					Expect(send(installationIDHeader, id).Body.String()).To(Equal(send(installationIDHeader, id).Body.String()))
+				}
+			})
+
+			It("consistently returns the same version to the same client without an installation ID", func() {
+				for i := 0; i < 20; i++ {
+					ip := fmt.Sprintf("203.0.113.%v", i)
+					Expect(send("X-Forwarded-For", "198.51.100.1, "+ip).Body.String()).To(Equal(send("X-Forwarded-For", ip).Body.String()))
+				}
+			})
+
+			It("identifies clients without an installation ID by the address Cloud Run appended, rather than addresses they supplied", func() {
+				selected := 0
+
+				for i := 0; i < 1000; i++ {
+					ip := fmt.Sprintf("10.1.%v.%v", i/250, i%250)
+
+					if send("X-Forwarded-For", "198.51.100.1, "+ip).Result().Header.Get("ETag") == `"1234-new"` {
+						selected++
+					}
+				}
+
+				Expect(selected).To(BeNumerically("~", 300, 50))
+			})
+
+			It("continues to return the new version to installations that received it as the percentage increases", func() {
+				initiallySelected := []string{}
+
+				for i := 0; i < 100; i++ {
+					id := fmt.Sprintf("installation-%v", i)
+
+					if send(installationIDHeader, id).Result().Header.Get("ETag") == `"1234-new"` {
+						initiallySelected = append(initiallySelected, id)
+					}
+				}
+
+				givenRolloutPercentage(60)
+
+				for _, id := range initiallySelected {
+					Expect(send(installationIDHeader, id).Result().Header.Get("ETag")).To(Equal(`"1234-new"`))
+				}
+			})
+		})
+	})
+
+	Context("when invoked with a HTTP GET by a client that provides its version", func() {
+		minimumVersion := semver.MustParse("0.80.0")
+		recommendedMinimumVersion := semver.MustParse("0.82.0")
+
+		policy := &storage.SupportPolicy{
+			MinimumVersion:            &minimumVersion,
+			RecommendedMinimumVersion: &recommendedMinimumVersion,
+			BadVersions:               []storage.BadVersion{{Range: semver.MustParseRange("0.82.1 || 0.83.0"), Reason: "This version can delete files outside the project directory."}},
+		}
+
+		send := func(userAgent string, support *storage.SupportPolicy) *httptest.ResponseRecorder {
+			info := exampleVersionInfo()
+			info.Support = support
+			latestVersionStoreMock.descriptorToReturn = storage.VersionDescriptor{Info: info, ETag: `"1234"`}
+
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/v1/latest", nil))
+			req.Header.Set("User-Agent", userAgent)
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+
+			return resp
+		}
+
+		DescribeTable("returning upgrade advice",
+			func(userAgent string, support *storage.SupportPolicy, expectedUpgrade string) {
+				resp := send(userAgent, support)
+
+				Expect(resp.Code).To(Equal(http.StatusOK))
+
+				if expectedUpgrade == "" {
+					Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
+				} else {
+					Expect(resp.Body).To(MatchJSON(strings.TrimSuffix(exampleVersionInfoJSON, "}") + `, "upgrade": ` + expectedUpgrade + `}`))
+				}
+			},
+			Entry("client is on the latest version", "batect/0.83.2 (Java HotSpot(TM) 64-Bit Server VM; Mac OS X 10.15.7; x86_64)", policy, ""),
+			Entry("client is on a newer version", "batect/0.84.0-dev", policy, ""),
+			Entry("client is not batect", "curl/7.64.1", policy, ""),
+			Entry("client has an invalid version", "batect/0.83", policy, ""),
+			Entry("client is on an older version and there is no policy", "batect/0.60.0", nil, ""),
+			Entry("client is on an older, supported version", "batect/0.82.2", policy,
+				`{"urgency": "optional", "reason": "Version 0.83.2 is available."}`),
+			Entry("client is on a version older than the recommended minimum", "Batect/0.81.0", policy,
+				`{"urgency": "recommended", "reason": "Version 0.81.0 has known issues. Upgrading to version 0.82.0 or later is recommended."}`),
+			Entry("client is on a version older than the minimum", "batect/0.79.0", policy,
+				`{"urgency": "required", "reason": "Version 0.79.0 is no longer supported. Upgrade to version 0.80.0 or later."}`),
+			Entry("client is on a known bad version", "batect/0.83.0", policy,
+				`{"urgency": "required", "reason": "This version can delete files outside the project directory."}`),
+			Entry("client is on a known bad version newer than the latest version", "batect/0.84.0",
+				&storage.SupportPolicy{BadVersions: []storage.BadVersion{{Range: semver.MustParseRange("0.84.0"), Reason: "This version was released by mistake."}}},
+				`{"urgency": "required", "reason": "This version was released by mistake."}`),
+			Entry("client is on a version older than the minimum with a custom reason", "batect/0.79.0",
+				&storage.SupportPolicy{MinimumVersion: &minimumVersion, MinimumVersionReason: "Versions before 0.80.0 can no longer download Java."},
+				`{"urgency": "required", "reason": "Versions before 0.80.0 can no longer download Java."}`),
+		)
+
+		It("never returns the support policy", func() {
+			Expect(send("batect/0.83.2", policy).Body.String()).ToNot(ContainSubstring("minimumVersion"))
+		})
+
+		It("returns a different entity tag for each distinct response", func() {
+			etags := map[string]struct{}{}
+
+			for _, userAgent := range []string{"batect/0.83.2", "batect/0.82.2", "batect/0.81.0", "batect/0.79.0", "batect/0.83.0"} {
+				etags[send(userAgent, policy).Result().Header.Get("ETag")] = struct{}{}
+			}
+
+			Expect(etags).To(HaveLen(5))
+		})
+
+		It("returns the same entity tag for the same response", func() {
+			Expect(send("batect/0.82.1", policy).Result().Header.Get("ETag")).To(Equal(send("batect/0.83.0", policy).Result().Header.Get("ETag")))
+		})
+
+		It("indicates that the response depends on the client if there is a support policy", func() {
+			Expect(send("batect/0.81.0", policy).Result().Header).To(HaveKeyWithValue("Vary", []string{"User-Agent"}))
+		})
+
+		It("does not indicate that the response depends on the client if there is no support policy", func() {
+			Expect(send("batect/0.81.0", nil).Result().Header).ToNot(HaveKey("Vary"))
+		})
+	})
+
+	Context("when invoked with a HTTP GET for a particular channel", func() {
+		BeforeEach(func() {
+			latestVersionStoreMock.descriptorToReturn = storage.VersionDescriptor{
+				Info: exampleVersionInfo(),
+			}
+		})
+
+		examples := map[string]string{
+			"in the query string": "/v1/latest?channel=beta",
+			"in the path":         "/v1/channels/beta/latest",
+		}
+
+		for description, path := range examples {
+			path := path
+
+			Context("given a known channel "+description, func() {
+				BeforeEach(func() {
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+					req.Header.Set("User-Agent", "MyApp/1.2.3")
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns a HTTP 200 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusOK))
+				})
+
+				It("retrieves the descriptor for the requested channel", func() {
+					Expect(latestVersionStoreMock.channelsRequested).To(ConsistOf("beta"))
+				})
+
+				It("returns the version descriptor in the response body", func() {
+					Expect(resp.Body).To(MatchJSON(exampleVersionInfoJSON))
+				})
+
+				It("posts a 'latest version check' event with the requested channel", func() {
+					Expect(eventSink.LatestVersionCheckEventsPosted).To(ConsistOf(events.LatestVersionCheck{
+						Product:   "batect",
+						UserAgent: "MyApp/1.2.3",
+						Channel:   "beta",
+						Version:   "0.83.2",
+					}))
+				})
+			})
+		}
+
+		unknownChannelExamples := map[string]string{
+			"in the query string": "/v1/latest?channel=nightly",
+			"in the path":         "/v1/channels/nightly/latest",
+		}
+
+		for description, path := range unknownChannelExamples {
+			path := path
+
+			Context("given an unknown channel "+description, func() {
+				BeforeEach(func() {
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns a HTTP 404 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusNotFound))
+				})
+
+				It("returns a JSON error payload", func() {
+					Expect(resp.Body).To(MatchJSON(`{"message":"The channel 'nightly' does not exist"}`))
+				})
+
+				It("sets the response Content-Type header", func() {
+					Expect(resp.Result().Header).To(HaveKeyWithValue("Content-Type", []string{"application/json"}))
+				})
+
+				It("does not retrieve any descriptor", func() {
+					Expect(latestVersionStoreMock.channelsRequested).To(BeEmpty())
+				})
+
+				It("does not post any events", func() {
+					Expect(eventSink.LatestVersionCheckEventsPosted).To(BeEmpty())
+				})
+			})
+		}
+
+		invalidPathExamples := []string{
+			"/v1/channels",
+			"/v1/channels/",
+			"/v1/channels/beta",
+			"/v1/channels/beta/",
+			"/v1/channels/beta/latest/thing",
+			"/v1/channels//latest",
+		}
+
+		for _, path := range invalidPathExamples {
+			path := path
+
+			Context("given the invalid path '"+path+"'", func() {
+				BeforeEach(func() {
+					req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+					handler.ServeHTTP(resp, req)
+				})
+
+				It("returns a HTTP 404 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusNotFound))
+				})
+
+				It("includes the default Golang 404 error message in the body", func() {
+					Expect(resp.Body.String()).To(Equal("404 page not found\n"))
+				})
+
+				It("does not post any events", func() {
+					Expect(eventSink.LatestVersionCheckEventsPosted).To(BeEmpty())
+				})
+			})
+		}
 	})
 })
 
 type mockLatestVersionStore struct {
 	descriptorToReturn storage.VersionDescriptor
 	errorToReturn      error
+	channelsRequested  []string
 }
 
-func (m *mockLatestVersionStore) GetLatestVersionDescriptor(_ context.Context) (storage.VersionDescriptor, error) {
+func (m *mockLatestVersionStore) GetLatestVersionDescriptor(_ context.Context, channel string) (storage.VersionDescriptor, error) {
+	m.channelsRequested = append(m.channelsRequested, channel)
+
 	return m.descriptorToReturn, m.errorToReturn
 }
+
+const exampleVersionInfoJSON = `{
+	"version": "0.83.2",
+	"url": "https://github.com/batect/batect/releases/tag/0.83.2",
+	"files": [
+		{"type": "script", "name": "batect", "url": "https://github.com/batect/batect/releases/download/0.83.2/batect"},
+		{"type": "script", "name": "batect.cmd", "url": "https://github.com/batect/batect/releases/download/0.83.2/batect.cmd"}
+	]
+}`
+
+func exampleVersionInfo() storage.VersionInfo {
+	return storage.VersionInfo{
+		Version: semver.MustParse("0.83.2"),
+		URL:     "https://github.com/batect/batect/releases/tag/0.83.2",
+		Files: []storage.VersionFile{
+			{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.83.2/batect"},
+			{Type: "script", Name: "batect.cmd", URL: "https://github.com/batect/batect/releases/download/0.83.2/batect.cmd"},
+		},
+	}
+}
+
+const installationIDHeader = "X-Installation-ID"
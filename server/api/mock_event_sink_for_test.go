@@ -19,44 +19,55 @@
 
 package api_test
 
-import "context"
+import (
+	"context"
+	"sync"
 
-type mockEventSink struct {
-	LatestVersionCheckEventsPosted []latestVersionCheckEvent
-	FileDownloadEventsPosted       []fileDownloadEvent
-}
+	"github.com/batect/updates.batect.dev/server/events"
+)
 
-type latestVersionCheckEvent struct {
-	userAgent string
-}
+type mockEventSink struct {
+	lock sync.Mutex
 
-type fileDownloadEvent struct {
-	userAgent string
-	version   string
-	fileName  string
+	LatestVersionCheckEventsPosted []events.LatestVersionCheck
+	FileDownloadEventsPosted       []events.FileDownload
+	FileNotFoundEventsPosted       []events.FileNotFound
+	FileBlockedEventsPosted        []events.FileBlocked
 }
 
 func newMockEventSink() *mockEventSink {
 	return &mockEventSink{
-		LatestVersionCheckEventsPosted: []latestVersionCheckEvent{},
-		FileDownloadEventsPosted:       []fileDownloadEvent{},
+		LatestVersionCheckEventsPosted: []events.LatestVersionCheck{},
+		FileDownloadEventsPosted:       []events.FileDownload{},
+		FileNotFoundEventsPosted:       []events.FileNotFound{},
+		FileBlockedEventsPosted:        []events.FileBlocked{},
 	}
 }
 
-func (m *mockEventSink) PostLatestVersionCheck(_ context.Context, userAgent string) {
-	m.LatestVersionCheckEventsPosted = append(
-		m.LatestVersionCheckEventsPosted,
-		latestVersionCheckEvent{userAgent: userAgent},
-	)
+func (m *mockEventSink) PostLatestVersionCheck(_ context.Context, check events.LatestVersionCheck) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.LatestVersionCheckEventsPosted = append(m.LatestVersionCheckEventsPosted, check)
+}
+
+func (m *mockEventSink) PostFileDownload(_ context.Context, download events.FileDownload) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.FileDownloadEventsPosted = append(m.FileDownloadEventsPosted, download)
+}
+
+func (m *mockEventSink) PostFileNotFound(_ context.Context, notFound events.FileNotFound) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.FileNotFoundEventsPosted = append(m.FileNotFoundEventsPosted, notFound)
 }
 
-func (m *mockEventSink) PostFileDownload(_ context.Context, userAgent string, version string, fileName string) {
-	m.FileDownloadEventsPosted = append(
-		m.FileDownloadEventsPosted,
-		fileDownloadEvent{
-			userAgent: userAgent,
-			version:   version,
-			fileName:  fileName,
-		},
-	)
+func (m *mockEventSink) PostFileBlocked(_ context.Context, blocked events.FileBlocked) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.FileBlockedEventsPosted = append(m.FileBlockedEventsPosted, blocked)
 }
@@ -0,0 +1,123 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+type storageNotificationHandler struct {
+	verifier   PushVerifier
+	refreshers map[string]storage.LatestVersionRefresher
+}
+
+// Only the instance that receives a notification refreshes its cache: other instances check the descriptor's generation on request.
+func NewStorageNotificationHandler(verifier PushVerifier, refreshers map[string]storage.LatestVersionRefresher) http.Handler {
+	return &storageNotificationHandler{
+		verifier:   verifier,
+		refreshers: refreshers,
+	}
+}
+
+// See https://cloud.google.com/storage/docs/pubsub-notifications.
+type pushRequest struct {
+	Message struct {
+		Attributes map[string]string `json:"attributes"`
+		MessageID  string            `json:"messageId"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+}
+
+const maxPushRequestSize = 1024 * 1024
+
+const objectFinalizeEventType = "OBJECT_FINALIZE"
+
+func (h *storageNotificationHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !requireMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	log := middleware.LoggerFromContext(req.Context())
+
+	if err := h.verifier.VerifyPush(req); err != nil {
+		log.WithError(err).Warn("Rejected storage notification that could not be verified.")
+		unauthorized(req.Context(), w, "storage-notifications")
+
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxPushRequestSize))
+
+	if err != nil {
+		log.WithError(err).Error("Reading storage notification failed.")
+		badRequest(req.Context(), w, "Could not read request body")
+
+		return
+	}
+
+	var push pushRequest
+
+	if err := json.Unmarshal(body, &push); err != nil {
+		badRequest(req.Context(), w, "Request body is not a valid Pub/Sub push request")
+		return
+	}
+
+	objectName := push.Message.Attributes["objectId"]
+	eventType := push.Message.Attributes["eventType"]
+	product, nameWithinProduct := storage.ProductForObjectName(objectName)
+	channel, isDescriptor := storage.ChannelForLatestVersionDescriptorObjectName(nameWithinProduct)
+	refresher, knownProduct := h.refreshers[product]
+
+	log = log.WithField("messageId", push.Message.MessageID).WithField("objectId", objectName).WithField("eventType", eventType)
+
+	if eventType != objectFinalizeEventType || !isDescriptor || !knownProduct {
+		log.Debug("Ignored storage notification that is not for a new latest version descriptor.")
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	log = log.WithField("product", product).WithField("channel", channel)
+	err = refresher.RefreshLatestVersionDescriptor(req.Context(), channel)
+
+	if errors.Is(err, storage.ErrObjectNotFound) || errors.Is(err, storage.ErrInvalidVersionDescriptor) {
+		log.WithError(err).Warn("Could not refresh latest version descriptor after storage notification.")
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	// Returning an error causes Pub/Sub to redeliver the notification later.
+	if err != nil {
+		log.WithError(err).Error("Refreshing latest version descriptor after storage notification failed.")
+		serviceUnavailable(req.Context(), w)
+
+		return
+	}
+
+	log.Info("Refreshed latest version descriptor after storage notification.")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,255 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/api"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("Storage notification endpoint", func() {
+	var verifier *mockPushVerifier
+	var batectRefresher *mockLatestVersionRefresher
+	var pluginRefresher *mockLatestVersionRefresher
+	var handler http.Handler
+	var resp *httptest.ResponseRecorder
+	var hook *test.Hook
+
+	BeforeEach(func() {
+		verifier = &mockPushVerifier{}
+		batectRefresher = &mockLatestVersionRefresher{}
+		pluginRefresher = &mockLatestVersionRefresher{}
+		handler = api.NewStorageNotificationHandler(verifier, map[string]storage.LatestVersionRefresher{
+			"batect":    batectRefresher,
+			"my-plugin": pluginRefresher,
+		})
+		resp = httptest.NewRecorder()
+	})
+
+	post := func(body []byte) {
+		var req *http.Request
+		req, hook = testutils.RequestWithTestLogger(httptest.NewRequest("POST", "/internal/storage-notifications", bytes.NewReader(body)))
+		handler.ServeHTTP(resp, req)
+	}
+
+	notificationFor := func(objectID string) []byte {
+		sample, err := os.ReadFile(filepath.Join("testdata", "storage_notification.json"))
+		Expect(err).ToNot(HaveOccurred())
+
+		return []byte(strings.ReplaceAll(string(sample), "v1/channels/beta/latest.json", objectID))
+	}
+
+	deletionNotificationFor := func(objectID string) []byte {
+		return bytes.ReplaceAll(notificationFor(objectID), []byte("OBJECT_FINALIZE"), []byte("OBJECT_DELETE"))
+	}
+
+	Context("when invoked with a HTTP method other than POST", func() {
+		BeforeEach(func() {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", "/internal/storage-notifications", nil))
+			handler.ServeHTTP(resp, req)
+		})
+
+		It("returns a HTTP 405 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("returns a JSON error payload", func() {
+			Expect(resp.Body).To(MatchJSON(`{"message":"This endpoint only supports POST requests"}`))
+		})
+	})
+
+	Context("when the request cannot be verified", func() {
+		BeforeEach(func() {
+			verifier.errorToReturn = errors.New("request does not contain a push token")
+			post(notificationFor("v1/latest.json"))
+		})
+
+		It("returns a HTTP 401 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("does not refresh anything", func() {
+			Expect(batectRefresher.channelsRefreshed).To(BeEmpty())
+		})
+
+		It("logs a warning", func() {
+			Expect(hook.AllEntries()).To(ContainElement(SatisfyAll(
+				HaveField("Level", logrus.WarnLevel),
+				HaveField("Message", "Rejected storage notification that could not be verified."),
+			)))
+		})
+	})
+
+	Context("when the request is verified", func() {
+		Context("given the notification is for a channel's latest version descriptor", func() {
+			BeforeEach(func() {
+				post(notificationFor("v1/channels/beta/latest.json"))
+			})
+
+			It("returns a HTTP 204 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNoContent))
+			})
+
+			It("refreshes the descriptor for that channel", func() {
+				Expect(batectRefresher.channelsRefreshed).To(ConsistOf("beta"))
+				Expect(pluginRefresher.channelsRefreshed).To(BeEmpty())
+			})
+
+			It("logs that the descriptor was refreshed", func() {
+				Expect(hook.LastEntry().Message).To(Equal("Refreshed latest version descriptor after storage notification."))
+				Expect(hook.LastEntry().Data).To(HaveKeyWithValue("product", "batect"))
+				Expect(hook.LastEntry().Data).To(HaveKeyWithValue("channel", "beta"))
+				Expect(hook.LastEntry().Data).To(HaveKeyWithValue("messageId", "1234567890"))
+			})
+		})
+
+		Context("given the notification is for the stable channel's latest version descriptor", func() {
+			BeforeEach(func() {
+				post(notificationFor("v1/latest.json"))
+			})
+
+			It("refreshes the descriptor for the stable channel", func() {
+				Expect(batectRefresher.channelsRefreshed).To(ConsistOf("stable"))
+			})
+		})
+
+		Context("given the notification is for another product's latest version descriptor", func() {
+			BeforeEach(func() {
+				post(notificationFor("products/my-plugin/v1/latest.json"))
+			})
+
+			It("returns a HTTP 204 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNoContent))
+			})
+
+			It("refreshes the descriptor for that product", func() {
+				Expect(pluginRefresher.channelsRefreshed).To(ConsistOf("stable"))
+				Expect(batectRefresher.channelsRefreshed).To(BeEmpty())
+			})
+		})
+
+		DescribeTable("given the notification is for an object that is not a known latest version descriptor",
+			func(objectID string) {
+				post(notificationFor(objectID))
+
+				Expect(resp.Code).To(Equal(http.StatusNoContent))
+				Expect(batectRefresher.channelsRefreshed).To(BeEmpty())
+				Expect(pluginRefresher.channelsRefreshed).To(BeEmpty())
+			},
+			Entry("another object", "v1/releases.json"),
+			Entry("an unknown product", "products/other-plugin/v1/latest.json"),
+		)
+
+		Context("given the notification is for a latest version descriptor that has been deleted", func() {
+			BeforeEach(func() {
+				post(deletionNotificationFor("v1/latest.json"))
+			})
+
+			It("returns a HTTP 204 response so that the notification is not redelivered", func() {
+				Expect(resp.Code).To(Equal(http.StatusNoContent))
+			})
+
+			It("does not refresh anything", func() {
+				Expect(batectRefresher.channelsRefreshed).To(BeEmpty())
+			})
+		})
+
+		DescribeTable("given refreshing the descriptor fails in a way that redelivering the notification won't fix",
+			func(err error) {
+				batectRefresher.errorToReturn = err
+				post(notificationFor("v1/latest.json"))
+
+				Expect(resp.Code).To(Equal(http.StatusNoContent))
+				Expect(hook.AllEntries()).To(ContainElement(SatisfyAll(
+					HaveField("Level", logrus.WarnLevel),
+					HaveField("Message", "Could not refresh latest version descriptor after storage notification."),
+				)))
+			},
+			Entry("the descriptor no longer exists", fmt.Errorf("could not refresh: %w", storage.ErrObjectNotFound)),
+			Entry("the descriptor is invalid", fmt.Errorf("could not refresh: %w", storage.ErrInvalidVersionDescriptor)),
+		)
+
+		Context("given refreshing the descriptor fails", func() {
+			BeforeEach(func() {
+				batectRefresher.errorToReturn = errors.New("something went wrong")
+				post(notificationFor("v1/latest.json"))
+			})
+
+			It("returns a HTTP 503 response so that the notification is redelivered", func() {
+				Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			})
+
+			It("logs an error", func() {
+				Expect(hook.AllEntries()).To(ContainElement(SatisfyAll(
+					HaveField("Level", logrus.ErrorLevel),
+					HaveField("Message", "Refreshing latest version descriptor after storage notification failed."),
+				)))
+			})
+		})
+
+		Context("given the request body is not a valid push request", func() {
+			BeforeEach(func() {
+				post([]byte("this is not JSON"))
+			})
+
+			It("returns a HTTP 400 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("returns a JSON error payload", func() {
+				Expect(resp.Body).To(MatchJSON(`{"message":"Request body is not a valid Pub/Sub push request"}`))
+			})
+		})
+	})
+})
+
+type mockPushVerifier struct {
+	errorToReturn error
+}
+
+func (m *mockPushVerifier) VerifyPush(_ *http.Request) error {
+	return m.errorToReturn
+}
+
+type mockLatestVersionRefresher struct {
+	channelsRefreshed []string
+	errorToReturn     error
+}
+
+func (m *mockLatestVersionRefresher) RefreshLatestVersionDescriptor(_ context.Context, channel string) error {
+	m.channelsRefreshed = append(m.channelsRefreshed, channel)
+
+	return m.errorToReturn
+}
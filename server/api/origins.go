@@ -0,0 +1,95 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"crypto/rand"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+type DownloadOrigin struct {
+	Name string
+
+	// URLTemplate contains "{version}" and "{fileName}" placeholders.
+	URLTemplate string
+
+	// Origins without a weight are only used for clients that ask for them, unless no origin has a weight.
+	Weight int
+}
+
+const (
+	urlTemplateVersionPlaceholder  = "{version}"
+	urlTemplateFileNamePlaceholder = "{fileName}"
+)
+
+func (o DownloadOrigin) url(version string, fileName string) string {
+	return strings.NewReplacer(urlTemplateVersionPlaceholder, version, urlTemplateFileNamePlaceholder, fileName).Replace(o.URLTemplate)
+}
+
+// mirrorHeader is a comma-separated list of origin names, most preferred first.
+const mirrorHeader = "X-Batect-Mirror"
+
+// origins must not be empty.
+func selectOrigin(req *http.Request, origins []DownloadOrigin, random func(n int) int) DownloadOrigin {
+	for _, preference := range strings.Split(req.Header.Get(mirrorHeader), ",") {
+		preference = strings.TrimSpace(preference)
+
+		for _, origin := range origins {
+			if preference != "" && strings.EqualFold(origin.Name, preference) {
+				return origin
+			}
+		}
+	}
+
+	totalWeight := 0
+
+	for _, origin := range origins {
+		totalWeight += origin.Weight
+	}
+
+	if totalWeight == 0 {
+		return origins[0]
+	}
+
+	choice := random(totalWeight)
+
+	for _, origin := range origins {
+		if choice < origin.Weight {
+			return origin
+		}
+
+		choice -= origin.Weight
+	}
+
+	return origins[0]
+}
+
+// The global math/rand source isn't seeded automatically in this Go version, so every instance would make the same choices.
+func cryptoRandomInt(n int) int {
+	value, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+
+	if err != nil {
+		return 0
+	}
+
+	return int(value.Int64())
+}
@@ -0,0 +1,184 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+type Product struct {
+	Name    string
+	Origins []DownloadOrigin
+
+	// Artifacts are used for releases whose manifest doesn't list any artifacts of its own.
+	Artifacts []Artifact
+}
+
+type Artifact struct {
+	// FileName may contain a "{version}" placeholder.
+	FileName string
+	Kind     ArtifactKind
+
+	// Platform is empty if the file is not specific to any platform.
+	Platform string
+
+	// If RedirectTarget is set, the file is always downloaded from there rather than from the product's download origins.
+	RedirectTarget string
+}
+
+type ArtifactKind string
+
+const (
+	ArtifactKindJar           ArtifactKind = "jar"
+	ArtifactKindWrapperScript ArtifactKind = "wrapper-script"
+	ArtifactKindChecksum      ArtifactKind = "checksum"
+	ArtifactKindSBOM          ArtifactKind = "sbom"
+	ArtifactKindOther         ArtifactKind = "other"
+)
+
+func (k ArtifactKind) IsKnown() bool {
+	switch k {
+	case ArtifactKindJar, ArtifactKindWrapperScript, ArtifactKindChecksum, ArtifactKindSBOM, ArtifactKindOther:
+		return true
+	default:
+		return false
+	}
+}
+
+const artifactRedirectOrigin = "artifact-redirect"
+
+const fileNameVersionPlaceholder = "{version}"
+
+// The version in each file name is replaced with a placeholder, so that the artifacts can be found through version aliases.
+func manifestArtifacts(manifest storage.ReleaseManifest, version string) []Artifact {
+	artifacts := []Artifact{}
+
+	for _, file := range manifest.Files {
+		if file.Kind == "" {
+			continue
+		}
+
+		kind := ArtifactKind(file.Kind)
+
+		if !kind.IsKnown() {
+			kind = ArtifactKindOther
+		}
+
+		artifacts = append(artifacts, Artifact{
+			FileName:       strings.ReplaceAll(file.Name, version, fileNameVersionPlaceholder),
+			Kind:           kind,
+			Platform:       file.Platform,
+			RedirectTarget: file.RedirectTarget,
+		})
+	}
+
+	return artifacts
+}
+
+func findArtifact(artifacts []Artifact, version string, fileName string) (Artifact, bool) {
+	for _, artifact := range artifacts {
+		if artifact.fileNameForVersion(version) == fileName {
+			return artifact, true
+		}
+	}
+
+	return Artifact{}, false
+}
+
+// findVersionlessArtifact finds an artifact by its file name without the version, eg. "batect.jar" for "batect-{version}.jar".
+func findVersionlessArtifact(artifacts []Artifact, fileName string) (Artifact, bool) {
+	for _, artifact := range artifacts {
+		if artifact.versionlessFileName() == fileName {
+			return artifact, true
+		}
+	}
+
+	return Artifact{}, false
+}
+
+func (a Artifact) fileNameForVersion(version string) string {
+	return strings.ReplaceAll(a.FileName, fileNameVersionPlaceholder, version)
+}
+
+func (a Artifact) versionlessFileName() string {
+	for _, separator := range []string{"-", "_", "."} {
+		for _, versionWithSeparator := range []string{separator + fileNameVersionPlaceholder, fileNameVersionPlaceholder + separator} {
+			if strings.Contains(a.FileName, versionWithSeparator) {
+				return strings.ReplaceAll(a.FileName, versionWithSeparator, "")
+			}
+		}
+	}
+
+	return strings.ReplaceAll(a.FileName, fileNameVersionPlaceholder, "")
+}
+
+func (p Product) origin(req *http.Request, artifact Artifact, random func(n int) int) DownloadOrigin {
+	if artifact.RedirectTarget != "" {
+		return DownloadOrigin{Name: artifactRedirectOrigin, URLTemplate: artifact.RedirectTarget}
+	}
+
+	return selectOrigin(req, p.Origins, random)
+}
+
+type productsHandler struct {
+	urlPattern *regexp.Regexp
+	products   map[string]http.Handler
+}
+
+// Each product's handler receives requests as if they were made to the equivalent unscoped route, eg. /v1/latest.
+func NewProductsHandler(products map[string]http.Handler) http.Handler {
+	return &productsHandler{
+		urlPattern: regexp.MustCompile(`^/v1/products/(?P<product>[^/]+)(?P<rest>/.*)$`),
+		products:   products,
+	}
+}
+
+func (h *productsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	match := h.urlPattern.FindStringSubmatch(req.URL.Path)
+
+	if match == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	product, rest := match[1], match[2]
+	handler, ok := h.products[product]
+
+	if !ok {
+		notFound(req.Context(), w, fmt.Sprintf("The product '%v' does not exist", product))
+		return
+	}
+
+	// This is the same approach as http.StripPrefix.
+	scoped := new(http.Request)
+	*scoped = *req
+	scoped.URL = new(url.URL)
+	*scoped.URL = *req.URL
+	scoped.URL.Path = "/v1" + rest
+	scoped.URL.RawPath = ""
+
+	handler.ServeHTTP(w, scoped)
+}
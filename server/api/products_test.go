@@ -0,0 +1,100 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Products endpoint", func() {
+	var handler http.Handler
+	var resp *httptest.ResponseRecorder
+	var pathsSeenByPlugin []string
+
+	BeforeEach(func() {
+		pathsSeenByPlugin = nil
+
+		handler = api.NewProductsHandler(map[string]http.Handler{
+			"my-plugin": http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				pathsSeenByPlugin = append(pathsSeenByPlugin, req.URL.String())
+				w.WriteHeader(http.StatusTeapot)
+			}),
+		})
+
+		resp = httptest.NewRecorder()
+	})
+
+	get := func(path string) {
+		req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+		handler.ServeHTTP(resp, req)
+	}
+
+	Context("when invoked with a path for a known product", func() {
+		BeforeEach(func() {
+			get("/v1/products/my-plugin/channels/beta/latest?thing=value")
+		})
+
+		It("passes the request to the product's handler with the product removed from the path", func() {
+			Expect(pathsSeenByPlugin).To(ConsistOf("/v1/channels/beta/latest?thing=value"))
+		})
+
+		It("returns the response from the product's handler", func() {
+			Expect(resp.Code).To(Equal(http.StatusTeapot))
+		})
+	})
+
+	Context("when invoked with a path for an unknown product", func() {
+		BeforeEach(func() {
+			get("/v1/products/other-plugin/latest")
+		})
+
+		It("returns a HTTP 404 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("returns a JSON error payload", func() {
+			Expect(resp.Body).To(MatchJSON(`{"message":"The product 'other-plugin' does not exist"}`))
+		})
+
+		It("does not pass the request to any product's handler", func() {
+			Expect(pathsSeenByPlugin).To(BeEmpty())
+		})
+	})
+
+	Context("when invoked with a path that does not include a product", func() {
+		BeforeEach(func() {
+			get("/v1/products/my-plugin")
+		})
+
+		It("returns a HTTP 404 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("does not pass the request to any product's handler", func() {
+			Expect(pathsSeenByPlugin).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,112 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/idtoken"
+)
+
+type PushVerifier interface {
+	VerifyPush(req *http.Request) error
+}
+
+var (
+	errMissingPushToken = errors.New("request does not contain a push token")
+	errInvalidPushToken = errors.New("request contains an invalid push token")
+)
+
+type oidcPushVerifier struct {
+	audience            string
+	serviceAccountEmail string
+	validate            func(ctx context.Context, token string, audience string) (*idtoken.Payload, error)
+}
+
+func NewOIDCPushVerifier(audience string, serviceAccountEmail string) PushVerifier {
+	return NewOIDCPushVerifierWithSpecificDependencies(audience, serviceAccountEmail, idtoken.Validate)
+}
+
+func NewOIDCPushVerifierWithSpecificDependencies(
+	audience string,
+	serviceAccountEmail string,
+	validate func(ctx context.Context, token string, audience string) (*idtoken.Payload, error),
+) PushVerifier {
+	return &oidcPushVerifier{
+		audience:            audience,
+		serviceAccountEmail: serviceAccountEmail,
+		validate:            validate,
+	}
+}
+
+func (v *oidcPushVerifier) VerifyPush(req *http.Request) error {
+	header := req.Header.Get("Authorization")
+
+	if !strings.HasPrefix(header, "Bearer ") || strings.TrimPrefix(header, "Bearer ") == "" {
+		return errMissingPushToken
+	}
+
+	payload, err := v.validate(req.Context(), strings.TrimPrefix(header, "Bearer "), v.audience)
+
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidPushToken, err)
+	}
+
+	if email, _ := payload.Claims["email"].(string); email != v.serviceAccountEmail {
+		return fmt.Errorf("%w: token was issued to '%v', not '%v'", errInvalidPushToken, email, v.serviceAccountEmail)
+	}
+
+	if verified, _ := payload.Claims["email_verified"].(bool); !verified {
+		return fmt.Errorf("%w: token's email address is not verified", errInvalidPushToken)
+	}
+
+	return nil
+}
+
+type tokenPushVerifier struct {
+	token string
+}
+
+// NewTokenPushVerifier is intended for local testing, such as with scripts/post_storage_notification.sh.
+func NewTokenPushVerifier(token string) PushVerifier {
+	return &tokenPushVerifier{
+		token: token,
+	}
+}
+
+func (v *tokenPushVerifier) VerifyPush(req *http.Request) error {
+	header := req.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+
+	if !strings.HasPrefix(header, "Bearer ") || token == "" {
+		return errMissingPushToken
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(v.token)) != 1 {
+		return errInvalidPushToken
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+
+	"github.com/batect/updates.batect.dev/server/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/api/idtoken"
+)
+
+var _ = Describe("Verifying Pub/Sub push requests", func() {
+	Describe("with OIDC tokens", func() {
+		var verifier api.PushVerifier
+		var payloadToReturn *idtoken.Payload
+		var errorToReturn error
+		var tokensValidated []string
+		var audiencesValidated []string
+
+		BeforeEach(func() {
+			payloadToReturn = &idtoken.Payload{Claims: map[string]interface{}{"email": "pubsub@example.com", "email_verified": true}}
+			errorToReturn = nil
+			tokensValidated = nil
+			audiencesValidated = nil
+
+			validate := func(_ context.Context, token string, audience string) (*idtoken.Payload, error) {
+				tokensValidated = append(tokensValidated, token)
+				audiencesValidated = append(audiencesValidated, audience)
+
+				return payloadToReturn, errorToReturn
+			}
+
+			verifier = api.NewOIDCPushVerifierWithSpecificDependencies("https://updates.batect.dev/internal/storage-notifications", "pubsub@example.com", validate)
+		})
+
+		Context("given the request has a valid token issued to the expected service account", func() {
+			var err error
+
+			BeforeEach(func() {
+				req := httptest.NewRequest("POST", "/internal/storage-notifications", nil)
+				req.Header.Set("Authorization", "Bearer the-token")
+				err = verifier.VerifyPush(req)
+			})
+
+			It("accepts the request", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("validates the token for the expected audience", func() {
+				Expect(tokensValidated).To(ConsistOf("the-token"))
+				Expect(audiencesValidated).To(ConsistOf("https://updates.batect.dev/internal/storage-notifications"))
+			})
+		})
+
+		Context("given the request has no token", func() {
+			It("rejects the request without validating anything", func() {
+				err := verifier.VerifyPush(httptest.NewRequest("POST", "/internal/storage-notifications", nil))
+				Expect(err).To(MatchError("request does not contain a push token"))
+				Expect(tokensValidated).To(BeEmpty())
+			})
+		})
+
+		Context("given the request has a token that is not valid", func() {
+			BeforeEach(func() {
+				errorToReturn = errors.New("idtoken: token expired")
+			})
+
+			It("rejects the request", func() {
+				req := httptest.NewRequest("POST", "/internal/storage-notifications", nil)
+				req.Header.Set("Authorization", "Bearer the-token")
+				Expect(verifier.VerifyPush(req)).To(MatchError("request contains an invalid push token: idtoken: token expired"))
+			})
+		})
+
+		Context("given the request has a token issued to another service account", func() {
+			BeforeEach(func() {
+				payloadToReturn.Claims["email"] = "someone-else@example.com"
+			})
+
+			It("rejects the request", func() {
+				req := httptest.NewRequest("POST", "/internal/storage-notifications", nil)
+				req.Header.Set("Authorization", "Bearer the-token")
+				Expect(verifier.VerifyPush(req)).To(MatchError("request contains an invalid push token: token was issued to 'someone-else@example.com', not 'pubsub@example.com'"))
+			})
+		})
+
+		Context("given the request has a token with an unverified email address", func() {
+			BeforeEach(func() {
+				payloadToReturn.Claims["email_verified"] = false
+			})
+
+			It("rejects the request", func() {
+				req := httptest.NewRequest("POST", "/internal/storage-notifications", nil)
+				req.Header.Set("Authorization", "Bearer the-token")
+				Expect(verifier.VerifyPush(req)).To(MatchError("request contains an invalid push token: token's email address is not verified"))
+			})
+		})
+	})
+
+	Describe("with a shared token", func() {
+		verifier := api.NewTokenPushVerifier("the-token")
+
+		DescribeTable("verifying requests",
+			func(authorization string, expectedError string) {
+				req := httptest.NewRequest("POST", "/internal/storage-notifications", nil)
+
+				if authorization != "" {
+					req.Header.Set("Authorization", authorization)
+				}
+
+				err := verifier.VerifyPush(req)
+
+				if expectedError == "" {
+					Expect(err).ToNot(HaveOccurred())
+				} else {
+					Expect(err).To(MatchError(expectedError))
+				}
+			},
+			Entry("the expected token", "Bearer the-token", ""),
+			Entry("no token", "", "request does not contain a push token"),
+			Entry("an empty token", "Bearer ", "request does not contain a push token"),
+			Entry("a different token", "Bearer other-token", "request contains an invalid push token"),
+		)
+	})
+})
@@ -0,0 +1,65 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+const installationIDHeader = "X-Installation-ID"
+
+// The version is included in the hash so that a different set of clients goes first for each release.
+func receivesNewVersion(req *http.Request, rollout storage.Rollout, version string) bool {
+	hash := sha256.Sum256([]byte(version + "\n" + rolloutClientKey(req)))
+	bucket := binary.BigEndian.Uint64(hash[:8]) % 100
+
+	return bucket < uint64(rollout.Percentage)
+}
+
+func rolloutClientKey(req *http.Request) string {
+	if installationID := strings.TrimSpace(req.Header.Get(installationIDHeader)); installationID != "" {
+		return "installation:" + installationID
+	}
+
+	return "client:" + req.UserAgent() + "\n" + clientIP(req)
+}
+
+// Cloud Run appends the client's address to X-Forwarded-For, and anything earlier can't be trusted.
+func clientIP(req *http.Request) string {
+	if forwardedFor := req.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		addresses := strings.Split(forwardedFor, ",")
+
+		return strings.TrimSpace(addresses[len(addresses)-1])
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,107 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+type upgradeUrgency string
+
+const (
+	upgradeOptional    upgradeUrgency = "optional"
+	upgradeRecommended upgradeUrgency = "recommended"
+	upgradeRequired    upgradeUrgency = "required"
+)
+
+type upgradeAdvice struct {
+	Urgency upgradeUrgency `json:"urgency"`
+	Reason  string         `json:"reason"`
+}
+
+// batect's User-Agent header looks like 'batect/0.83.2 (Java HotSpot(TM) 64-Bit Server VM; ...)'.
+func clientVersion(userAgent string) (semver.Version, bool) {
+	fields := strings.Fields(userAgent)
+
+	if len(fields) == 0 {
+		return semver.Version{}, false
+	}
+
+	product, version, ok := strings.Cut(fields[0], "/")
+
+	if !ok || !strings.EqualFold(product, "batect") {
+		return semver.Version{}, false
+	}
+
+	parsed, err := semver.Parse(version)
+
+	if err != nil {
+		return semver.Version{}, false
+	}
+
+	return parsed, true
+}
+
+func adviseUpgrade(current semver.Version, latest semver.Version, policy storage.SupportPolicy) *upgradeAdvice {
+	for _, bad := range policy.BadVersions {
+		if bad.Range.Contains(current) {
+			return &upgradeAdvice{Urgency: upgradeRequired, Reason: bad.Reason}
+		}
+	}
+
+	// The client may not have been given anything newer, eg. because it is not part of a rollout yet.
+	if !current.LessThan(latest) {
+		return nil
+	}
+
+	if policy.MinimumVersion != nil && current.LessThan(*policy.MinimumVersion) {
+		reason := policy.MinimumVersionReason
+
+		if reason == "" {
+			reason = fmt.Sprintf("Version %v is no longer supported. Upgrade to version %v or later.", current, policy.MinimumVersion)
+		}
+
+		return &upgradeAdvice{Urgency: upgradeRequired, Reason: reason}
+	}
+
+	if policy.RecommendedMinimumVersion != nil && current.LessThan(*policy.RecommendedMinimumVersion) {
+		reason := policy.RecommendedMinimumVersionReason
+
+		if reason == "" {
+			reason = fmt.Sprintf("Version %v has known issues. Upgrading to version %v or later is recommended.", current, policy.RecommendedMinimumVersion)
+		}
+
+		return &upgradeAdvice{Urgency: upgradeRecommended, Reason: reason}
+	}
+
+	return &upgradeAdvice{Urgency: upgradeOptional, Reason: fmt.Sprintf("Version %v is available.", latest)}
+}
+
+func (a *upgradeAdvice) etagSuffix() string {
+	hash := sha256.Sum256([]byte(a.Reason))
+
+	return string(a.Urgency) + "-" + hex.EncodeToString(hash[:4])
+}
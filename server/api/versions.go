@@ -0,0 +1,156 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+const defaultVersionsPageSize = 50
+const maxVersionsPageSize = 500
+
+type versionsHandler struct {
+	store storage.ReleaseHistoryStore
+}
+
+// NewVersionsHandler returns a handler that lists every published release, newest first.
+//
+// The list can be filtered with the 'range' query parameter (a semantic version range such as '>=0.78.0 <0.80.0'),
+// and is paginated with the 'page' (starting from 1) and 'pageSize' query parameters.
+func NewVersionsHandler(store storage.ReleaseHistoryStore) http.Handler {
+	return &versionsHandler{
+		store: store,
+	}
+}
+
+type versionsResponse struct {
+	Versions   []storage.Release `json:"versions"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"pageSize"`
+	TotalCount int               `json:"totalCount"`
+	NextPage   *int              `json:"nextPage,omitempty"`
+}
+
+func (h *versionsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !requireMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	query := req.URL.Query()
+	versionRange, err := semver.ParseRange(query.Get("range"))
+
+	if err != nil {
+		badRequest(req.Context(), w, fmt.Sprintf("The range '%v' is not a valid semantic version range", query.Get("range")))
+		return
+	}
+
+	page, ok := positiveIntQueryParameter(query.Get("page"), 1)
+
+	if !ok {
+		badRequest(req.Context(), w, "The page must be a positive integer")
+		return
+	}
+
+	pageSize, ok := positiveIntQueryParameter(query.Get("pageSize"), defaultVersionsPageSize)
+
+	if !ok || pageSize > maxVersionsPageSize {
+		badRequest(req.Context(), w, fmt.Sprintf("The page size must be an integer between 1 and %v", maxVersionsPageSize))
+		return
+	}
+
+	log := middleware.LoggerFromContext(req.Context())
+	releases, err := h.store.GetReleases(req.Context())
+
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		notFound(req.Context(), w, "No release history has been published")
+		return
+	}
+
+	if err != nil {
+		log.WithError(err).Error("Getting release history failed.")
+		serviceUnavailable(req.Context(), w)
+
+		return
+	}
+
+	matching := []storage.Release{}
+
+	for _, release := range releases {
+		if versionRange.Contains(release.Version) {
+			matching = append(matching, release)
+		}
+	}
+
+	writeJSON(req.Context(), w, http.StatusOK, paginate(matching, page, pageSize))
+}
+
+func paginate(releases []storage.Release, page int, pageSize int) versionsResponse {
+	resp := versionsResponse{
+		Versions:   []storage.Release{},
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: len(releases),
+	}
+
+	// Check the page number before calculating the start index to avoid overflow with very large page numbers.
+	if page-1 > len(releases)/pageSize {
+		return resp
+	}
+
+	start := (page - 1) * pageSize
+
+	if start >= len(releases) {
+		return resp
+	}
+
+	end := start + pageSize
+
+	if end < len(releases) {
+		nextPage := page + 1
+		resp.NextPage = &nextPage
+	} else {
+		end = len(releases)
+	}
+
+	resp.Versions = releases[start:end]
+
+	return resp
+}
+
+func positiveIntQueryParameter(value string, fallback int) (int, bool) {
+	if value == "" {
+		return fallback, true
+	}
+
+	parsed, err := strconv.Atoi(value)
+
+	if err != nil || parsed < 1 {
+		return 0, false
+	}
+
+	return parsed, true
+}
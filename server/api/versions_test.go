@@ -0,0 +1,245 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/api"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Versions endpoint", func() {
+	var store *mockReleaseHistoryStore
+	var handler http.Handler
+	var resp *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		store = &mockReleaseHistoryStore{}
+		handler = api.NewVersionsHandler(store)
+		resp = httptest.NewRecorder()
+	})
+
+	Context("when invoked with a HTTP method other than GET", func() {
+		BeforeEach(func() {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("POST", "/v1/versions", nil))
+			handler.ServeHTTP(resp, req)
+		})
+
+		It("returns a HTTP 405 response", func() {
+			Expect(resp.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("returns a JSON error payload", func() {
+			Expect(resp.Body).To(MatchJSON(`{"message":"This endpoint only supports GET requests"}`))
+		})
+	})
+
+	Context("when invoked with a HTTP GET", func() {
+		get := func(path string) {
+			req, _ := testutils.RequestWithTestLogger(httptest.NewRequest("GET", path, nil))
+			handler.ServeHTTP(resp, req)
+		}
+
+		Context("given retrieving the release history succeeds", func() {
+			BeforeEach(func() {
+				store.releasesToReturn = []storage.Release{
+					release("0.83.2", storage.ReleaseStatusCurrent),
+					release("0.83.1", storage.ReleaseStatusDeprecated),
+					release("0.80.0", storage.ReleaseStatusYanked),
+					release("0.79.1", storage.ReleaseStatusDeprecated),
+				}
+			})
+
+			Context("when no parameters are provided", func() {
+				BeforeEach(func() {
+					get("/v1/versions")
+				})
+
+				It("returns a HTTP 200 response", func() {
+					Expect(resp.Code).To(Equal(http.StatusOK))
+				})
+
+				It("sets the response Content-Type header", func() {
+					Expect(resp.Result().Header).To(HaveKeyWithValue("Content-Type", []string{"application/json"}))
+				})
+
+				It("returns all releases in a single page", func() {
+					Expect(resp.Body).To(MatchJSON(`{
+						"versions": [
+							{"version": "0.83.2", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.83.2", "status": "current"},
+							{"version": "0.83.1", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.83.1", "status": "deprecated"},
+							{"version": "0.80.0", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.80.0", "status": "yanked"},
+							{"version": "0.79.1", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.79.1", "status": "deprecated"}
+						],
+						"page": 1,
+						"pageSize": 50,
+						"totalCount": 4
+					}`))
+				})
+			})
+
+			Context("when a range is provided", func() {
+				BeforeEach(func() {
+					get("/v1/versions?range=%3E%3D0.80.0+%3C0.83.2")
+				})
+
+				It("returns only the releases in the range", func() {
+					Expect(resp.Body).To(MatchJSON(`{
+						"versions": [
+							{"version": "0.83.1", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.83.1", "status": "deprecated"},
+							{"version": "0.80.0", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.80.0", "status": "yanked"}
+						],
+						"page": 1,
+						"pageSize": 50,
+						"totalCount": 2
+					}`))
+				})
+			})
+
+			Context("when the first page of several is requested", func() {
+				BeforeEach(func() {
+					get("/v1/versions?pageSize=3")
+				})
+
+				It("returns the first page of releases and the number of the next page", func() {
+					Expect(resp.Body).To(MatchJSON(`{
+						"versions": [
+							{"version": "0.83.2", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.83.2", "status": "current"},
+							{"version": "0.83.1", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.83.1", "status": "deprecated"},
+							{"version": "0.80.0", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.80.0", "status": "yanked"}
+						],
+						"page": 1,
+						"pageSize": 3,
+						"totalCount": 4,
+						"nextPage": 2
+					}`))
+				})
+			})
+
+			Context("when the last page is requested", func() {
+				BeforeEach(func() {
+					get("/v1/versions?pageSize=3&page=2")
+				})
+
+				It("returns the remaining releases without a next page", func() {
+					Expect(resp.Body).To(MatchJSON(`{
+						"versions": [
+							{"version": "0.79.1", "releaseDate": "2023-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.79.1", "status": "deprecated"}
+						],
+						"page": 2,
+						"pageSize": 3,
+						"totalCount": 4
+					}`))
+				})
+			})
+
+			Context("when a page beyond the last page is requested", func() {
+				BeforeEach(func() {
+					get("/v1/versions?pageSize=3&page=9223372036854775807")
+				})
+
+				It("returns no releases", func() {
+					Expect(resp.Body).To(MatchJSON(`{
+						"versions": [],
+						"page": 9223372036854775807,
+						"pageSize": 3,
+						"totalCount": 4
+					}`))
+				})
+			})
+		})
+
+		DescribeTable("given invalid parameters",
+			func(path string, expectedMessage string) {
+				get(path)
+
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp.Body).To(MatchJSON(fmt.Sprintf(`{"message":"%v"}`, expectedMessage)))
+				Expect(store.called).To(BeFalse())
+			},
+			Entry("invalid range", "/v1/versions?range=blah", "The range 'blah' is not a valid semantic version range"),
+			Entry("non-numeric page", "/v1/versions?page=blah", "The page must be a positive integer"),
+			Entry("zero page", "/v1/versions?page=0", "The page must be a positive integer"),
+			Entry("negative page size", "/v1/versions?pageSize=-1", "The page size must be an integer between 1 and 500"),
+			Entry("page size too large", "/v1/versions?pageSize=501", "The page size must be an integer between 1 and 500"),
+		)
+
+		Context("given no release history has been published", func() {
+			BeforeEach(func() {
+				store.errorToReturn = fmt.Errorf("could not get release history: %w", storage.ErrObjectNotFound)
+				get("/v1/versions")
+			})
+
+			It("returns a HTTP 404 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("returns a JSON error payload", func() {
+				Expect(resp.Body).To(MatchJSON(`{"message":"No release history has been published"}`))
+			})
+		})
+
+		Context("given retrieving the release history fails", func() {
+			BeforeEach(func() {
+				store.errorToReturn = errors.New("something went wrong")
+				get("/v1/versions")
+			})
+
+			It("returns a HTTP 503 response", func() {
+				Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			})
+
+			It("returns a JSON error payload", func() {
+				Expect(resp.Body).To(MatchJSON(`{"message":"Service unavailable"}`))
+			})
+		})
+	})
+})
+
+func release(version string, status storage.ReleaseStatus) storage.Release {
+	return storage.Release{
+		Version:     semver.MustParse(version),
+		ReleaseDate: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		URL:         "https://github.com/batect/batect/releases/tag/" + version,
+		Status:      status,
+	}
+}
+
+type mockReleaseHistoryStore struct {
+	releasesToReturn []storage.Release
+	errorToReturn    error
+	called           bool
+}
+
+func (m *mockReleaseHistoryStore) GetReleases(_ context.Context) ([]storage.Release, error) {
+	m.called = true
+
+	return m.releasesToReturn, m.errorToReturn
+}
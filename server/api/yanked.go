@@ -0,0 +1,106 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/batect/updates.batect.dev/server/events"
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+type yankedVersionResponse struct {
+	Message     string `json:"message"`
+	Version     string `json:"version"`
+	Reason      string `json:"reason"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// getYankedVersions fails open in the same way as isReleased.
+func (h *filesHandler) getYankedVersions(req *http.Request) []storage.YankedVersion {
+	yanked, err := h.yanked.GetYankedVersions(req.Context())
+
+	if err != nil {
+		log := middleware.LoggerFromContext(req.Context())
+		log.WithError(err).Warn("Getting yanked versions failed, assuming no versions have been yanked.")
+
+		return nil
+	}
+
+	return yanked
+}
+
+// The registry is checked before the release history, as it can give a reason and a replacement.
+func (h *filesHandler) findYankedVersion(req *http.Request, version string) (storage.YankedVersion, bool) {
+	if yanked, ok := storage.FindYankedVersion(h.getYankedVersions(req), version); ok {
+		return yanked, true
+	}
+
+	// isReleased has already logged any failure to read the release history.
+	releases, _ := h.releases.GetReleases(req.Context())
+
+	for _, release := range releases {
+		if release.Status == storage.ReleaseStatusYanked && release.Version.String() == version {
+			return storage.YankedVersion{Version: release.Version, Reason: "This version is marked as yanked in the release history."}, true
+		}
+	}
+
+	return storage.YankedVersion{}, false
+}
+
+func (h *filesHandler) serveYankedVersion(w http.ResponseWriter, req *http.Request, yanked storage.YankedVersion, alias string, artifact Artifact) {
+	version := yanked.Version.String()
+	blocked := events.FileBlocked{
+		Product:   h.product.Name,
+		UserAgent: req.UserAgent(),
+		Version:   version,
+		FileName:  artifact.fileNameForVersion(version),
+		Alias:     alias,
+	}
+
+	resp := yankedVersionResponse{
+		Message: fmt.Sprintf("Version %v of %v has been yanked and can no longer be downloaded", version, h.product.Name),
+		Version: version,
+		Reason:  yanked.Reason,
+	}
+
+	// The registry can change at any time, and every attempt should be recorded.
+	w.Header().Set("Cache-Control", "no-store, max-age=0")
+
+	if yanked.Replacement == nil {
+		h.eventSink.PostFileBlocked(req.Context(), blocked)
+		writeJSON(req.Context(), w, http.StatusGone, resp)
+
+		return
+	}
+
+	replacement := yanked.Replacement.String()
+	blocked.Replacement = replacement
+	resp.Replacement = replacement
+	resp.Message = fmt.Sprintf("Version %v of %v has been yanked, use version %v instead", version, h.product.Name, replacement)
+
+	h.eventSink.PostFileBlocked(req.Context(), blocked)
+
+	// A relative reference works for both unscoped and product-scoped requests.
+	w.Header().Set("Location", "../"+replacement+"/"+artifact.fileNameForVersion(replacement))
+	writeJSON(req.Context(), w, http.StatusFound, resp)
+}
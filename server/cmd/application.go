@@ -22,6 +22,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"time"
@@ -33,6 +34,8 @@ import (
 	"github.com/batect/services-common/tracing"
 	"github.com/batect/updates.batect.dev/server/api"
 	"github.com/batect/updates.batect.dev/server/events"
+	"github.com/batect/updates.batect.dev/server/github"
+	"github.com/batect/updates.batect.dev/server/signing"
 	"github.com/batect/updates.batect.dev/server/storage"
 	"github.com/sirupsen/logrus"
 	"github.com/unrolled/secure"
@@ -58,11 +61,49 @@ func main() {
 
 	defer flush()
 
+	if len(os.Args) > 1 && os.Args[1] == syncGitHubReleasesCommand {
+		runGitHubSync(config)
+
+		return
+	}
+
 	runServer(config)
 }
 
+// Syncing runs as a scheduled job rather than in the service, so that only one sync runs at a time.
+const syncGitHubReleasesCommand = "sync-github-releases"
+
+const gitHubSyncTimeout = 2 * time.Minute
+
+func runGitHubSync(config *serviceConfig) {
+	if err := syncGitHubReleases(config); err != nil {
+		logrus.WithError(err).Error("Syncing latest version descriptors from GitHub releases failed.")
+		os.Exit(1)
+	}
+}
+
+func syncGitHubReleases(config *serviceConfig) error {
+	var cloudStorageClient *cloudstorage.Client
+
+	if config.StorageBackend == cloudStorageStorageBackend {
+		client, err := createCloudStorageClient()
+
+		if err != nil {
+			return fmt.Errorf("could not create Cloud Storage client: %w", err)
+		}
+
+		cloudStorageClient = client
+	}
+
+	ctx, cancel := context.WithTimeout(backgroundContext(), gitHubSyncTimeout)
+	defer cancel()
+
+	// GitHub releases are only synced for the default product: other products are published through the admin API.
+	return createGitHubSyncer(createObjectStore(cloudStorageClient, config), config).Sync(ctx)
+}
+
 func runServer(config *serviceConfig) {
-	srv, err := createServer(config)
+	srv, eventSinks, err := createServer(config)
 
 	if err != nil {
 		logrus.WithError(err).Error("Could not create server.")
@@ -73,22 +114,96 @@ func runServer(config *serviceConfig) {
 		logrus.WithError(err).Error("Could not run server.")
 		os.Exit(1)
 	}
+
+	drainEvents(eventSinks)
 }
 
-func createServer(config *serviceConfig) (*http.Server, error) {
-	cloudStorageClient, err := createCloudStorageClient()
+// Cloud Run allows ten seconds for an instance to stop, so leave time for everything else to shut down.
+const eventDrainTimeout = 5 * time.Second
+
+type eventSinks struct {
+	async      events.AsyncEventSink
+	underlying events.EventSink
+}
+
+func drainEvents(sinks eventSinks) {
+	ctx, cancel := context.WithTimeout(context.Background(), eventDrainTimeout)
+	defer cancel()
+
+	err := sinks.async.Shutdown(ctx)
+	stats := sinks.async.Stats()
+	log := logrus.WithField("succeeded", stats.Succeeded).WithField("failed", stats.Failed).WithField("dropped", stats.Dropped)
 
 	if err != nil {
-		return nil, fmt.Errorf("could not create Cloud Storage client: %w", err)
+		log.WithError(err).Error("Could not post all queued events before shutting down.")
+	} else {
+		log.Info("Posted all queued events.")
+	}
+
+	if closer, ok := sinks.underlying.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logrus.WithError(err).Error("Could not close event sink.")
+		}
+	}
+}
+
+func createServer(config *serviceConfig) (*http.Server, eventSinks, error) {
+	var cloudStorageClient *cloudstorage.Client
+
+	if config.RequiresCloudStorage() {
+		client, err := createCloudStorageClient()
+
+		if err != nil {
+			return nil, eventSinks{}, fmt.Errorf("could not create Cloud Storage client: %w", err)
+		}
+
+		cloudStorageClient = client
 	}
 
-	eventSink := createEventSink(cloudStorageClient, config)
+	underlyingEventSink := createEventSink(cloudStorageClient, config)
+	eventSink := events.NewAsyncEventSink(underlyingEventSink, eventQueueSize, eventWorkers)
+	objectStore := createObjectStore(cloudStorageClient, config)
+	artifactObjects := createArtifactObjectStore(cloudStorageClient, config)
 
 	mux := http.NewServeMux()
 	mux.Handle("/", otelhttp.WithRouteTag("/", http.HandlerFunc(api.Home)))
 	mux.Handle("/ping", otelhttp.WithRouteTag("/ping", http.HandlerFunc(api.Ping)))
-	mux.Handle("/v1/latest", otelhttp.WithRouteTag("/v1/latest", createLatestHandler(cloudStorageClient, eventSink, config)))
-	mux.Handle("/v1/files/", otelhttp.WithRouteTag("/v1/files", api.NewFilesHandler(eventSink)))
+
+	var signer signing.Signer
+
+	if len(config.SigningKeys) > 0 {
+		s, err := signing.NewSigner(config.SigningKeys)
+
+		if err != nil {
+			return nil, eventSinks{}, fmt.Errorf("could not create signer: %w", err)
+		}
+
+		signer = s
+		mux.Handle("/v1/keys", otelhttp.WithRouteTag("/v1/keys", api.NewKeysHandler(signer)))
+	}
+
+	productHandlers := make(map[string]http.Handler, len(config.Products))
+	latestVersionRefreshers := make(map[string]storage.LatestVersionRefresher, len(config.Products))
+
+	for _, product := range config.Products {
+		handlers := createProductHandlers(product, storage.NewProductObjectStore(objectStore, product.Name), artifactObjects, eventSink, signer, config)
+		productMux := http.NewServeMux()
+		handlers.register(productMux, "/v1/products/:product")
+		productHandlers[product.Name] = productMux
+		latestVersionRefreshers[product.Name] = handlers.latestVersionCache
+
+		// The default product's routes are also available without a product in the path, as they were before products were introduced.
+		if product.Name == storage.DefaultProduct {
+			handlers.register(mux, "/v1")
+		}
+	}
+
+	mux.Handle("/v1/products/", api.NewProductsHandler(productHandlers))
+
+	if verifier := createStorageNotificationVerifier(config); verifier != nil {
+		notificationHandler := api.NewStorageNotificationHandler(verifier, latestVersionRefreshers)
+		mux.Handle("/internal/storage-notifications", otelhttp.WithRouteTag("/internal/storage-notifications", notificationHandler))
+	}
 
 	securityHeaders := secure.New(secure.Options{
 		FrameDeny:             true,
@@ -116,20 +231,203 @@ func createServer(config *serviceConfig) (*http.Server, error) {
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
-	return srv, nil
+	return srv, eventSinks{async: eventSink, underlying: underlyingEventSink}, nil
 }
 
+const maxEventFileSize = 64 * 1024 * 1024
+const inMemoryEventCapacity = 1000
+
+const eventQueueSize = 1000
+const eventWorkers = 8
+
 func createEventSink(cloudStorageClient *cloudstorage.Client, config *serviceConfig) events.EventSink {
-	bucketName := fmt.Sprintf("%v-events", config.ProjectID)
+	switch config.EventSinkBackend {
+	case filesystemEventSinkBackend:
+		return events.NewFilesystemEventSink(config.EventSinkDirectory, maxEventFileSize)
+	case memoryEventSinkBackend:
+		return events.NewInMemoryEventSink(inMemoryEventCapacity)
+	case cloudStorageEventSinkBackend:
+		bucketName := fmt.Sprintf("%v-events", config.ProjectID)
+
+		return events.NewCloudStorageEventSink(bucketName, cloudStorageClient)
+	default:
+		panic(fmt.Sprintf("unknown event sink backend '%v'", config.EventSinkBackend))
+	}
+}
+
+func createObjectStore(cloudStorageClient *cloudstorage.Client, config *serviceConfig) storage.ObjectStore {
+	switch config.StorageBackend {
+	case filesystemStorageBackend:
+		return storage.NewFilesystemObjectStore(config.StorageDirectory)
+	case cloudStorageStorageBackend:
+		bucketName := fmt.Sprintf("%v-public", config.ProjectID)
 
-	return events.NewCloudStorageEventSink(bucketName, cloudStorageClient)
+		return storage.NewCloudStorageObjectStore(bucketName, cloudStorageClient)
+	default:
+		panic(fmt.Sprintf("unknown storage backend '%v'", config.StorageBackend))
+	}
+}
+
+const releaseHistoryRefreshInterval = time.Minute
+
+const yankedVersionsRefreshInterval = time.Minute
+
+func createArtifactObjectStore(cloudStorageClient *cloudstorage.Client, config *serviceConfig) storage.ObjectStore {
+	switch config.ArtifactCacheBackend {
+	case noArtifactCacheBackend:
+		return nil
+	case filesystemArtifactCacheBackend:
+		return storage.NewFilesystemObjectStore(config.ArtifactCacheLocation)
+	case cloudStorageArtifactCacheBackend:
+		return storage.NewCloudStorageObjectStore(config.ArtifactCacheLocation, cloudStorageClient)
+	default:
+		panic(fmt.Sprintf("unknown artifact cache backend '%v'", config.ArtifactCacheBackend))
+	}
+}
+
+type productHandlers struct {
+	latestVersionCache storage.CachingLatestVersionStore
+
+	latest     http.Handler
+	versions   http.Handler
+	changes    http.Handler
+	advisories http.Handler
+	files      http.Handler
+
+	// admin is nil if the admin API is disabled.
+	admin http.Handler
+}
+
+func createProductHandlers(
+	product api.Product,
+	objectStore storage.ObjectStore,
+	artifactObjects storage.ObjectStore,
+	eventSink events.EventSink,
+	signer signing.Signer,
+	config *serviceConfig,
+) productHandlers {
+	latestVersionCache := storage.NewCachingLatestVersionStore(
+		backgroundContext(),
+		storage.NewLatestVersionStore(objectStore),
+		storage.NewLatestVersionGenerationStore(objectStore),
+		config.Channels,
+		config.LatestVersionRefreshInterval,
+	)
+	latestVersionStore := createFallbackLatestVersionStore(product, latestVersionCache, config)
+	releases := storage.NewCachingReleaseHistoryStore(backgroundContext(), storage.NewReleaseHistoryStore(objectStore), releaseHistoryRefreshInterval)
+	yanked := storage.NewCachingYankedVersionStore(backgroundContext(), storage.NewYankedVersionStore(objectStore), yankedVersionsRefreshInterval)
+
+	handlers := productHandlers{
+		latestVersionCache: latestVersionCache,
+		latest:             api.NewLatestHandler(product.Name, latestVersionStore, eventSink, config.Channels, config.LatestVersionCacheControl, signer),
+		versions:           api.NewVersionsHandler(storage.NewReleaseHistoryStore(objectStore)),
+		changes:            api.NewChangesHandler(storage.NewReleaseHistoryStore(objectStore)),
+		advisories:         api.NewAdvisoriesHandler(storage.NewAdvisoryStore(objectStore)),
+		files:              createFilesHandler(product, releases, yanked, storage.NewReleaseManifestStore(objectStore), artifactObjects, eventSink),
+	}
+
+	if len(config.AdminTokens) > 0 {
+		handlers.admin = createAdminHandler(objectStore, config)
+	}
+
+	return handlers
+}
+
+func createFallbackLatestVersionStore(product api.Product, primary storage.LatestVersionStore, config *serviceConfig) storage.LatestVersionStore {
+	var snapshotObjects storage.ObjectStore
+
+	if config.DescriptorSnapshotDirectory != "" {
+		snapshotObjects = storage.NewProductObjectStore(storage.NewFilesystemObjectStore(config.DescriptorSnapshotDirectory), product.Name)
+	}
+
+	embeddedObjects := storage.NewProductObjectStore(storage.NewEmbeddedObjectStore(), product.Name)
+
+	return storage.NewFallbackLatestVersionStore(primary, snapshotObjects, embeddedObjects)
 }
 
-func createLatestHandler(cloudStorageClient *cloudstorage.Client, eventSink events.EventSink, config *serviceConfig) http.Handler {
-	bucketName := fmt.Sprintf("%v-public", config.ProjectID)
-	store := storage.NewCloudStorageLatestVersionStore(bucketName, cloudStorageClient)
+func (h productHandlers) register(mux *http.ServeMux, routePrefix string) {
+	mux.Handle("/v1/latest", otelhttp.WithRouteTag(routePrefix+"/latest", h.latest))
+	mux.Handle("/v1/channels/", otelhttp.WithRouteTag(routePrefix+"/channels/:channel/latest", h.latest))
+	mux.Handle("/v1/versions", otelhttp.WithRouteTag(routePrefix+"/versions", h.versions))
+	mux.Handle("/v1/changes", otelhttp.WithRouteTag(routePrefix+"/changes", h.changes))
+	mux.Handle("/v1/advisories", otelhttp.WithRouteTag(routePrefix+"/advisories", h.advisories))
+	mux.Handle("/v1/files/", otelhttp.WithRouteTag(routePrefix+"/files", h.files))
+
+	if h.admin != nil {
+		mux.Handle("/v1/admin/", otelhttp.WithRouteTag(routePrefix+"/admin", h.admin))
+	}
+}
+
+func createStorageNotificationVerifier(config *serviceConfig) api.PushVerifier {
+	if config.StorageNotificationServiceAccount != "" {
+		return api.NewOIDCPushVerifier(config.StorageNotificationAudience, config.StorageNotificationServiceAccount)
+	}
+
+	if config.StorageNotificationToken != "" {
+		return api.NewTokenPushVerifier(config.StorageNotificationToken)
+	}
+
+	return nil
+}
+
+// The admin API bypasses the cache, so that preconditions are checked against the current generation.
+func createAdminHandler(objectStore storage.ObjectStore, config *serviceConfig) http.Handler {
+	return api.NewAdminHandler(
+		storage.NewLatestVersionStore(objectStore),
+		storage.NewLatestVersionGenerationStore(objectStore),
+		storage.NewLatestVersionPublisher(objectStore),
+		storage.NewDescriptorHistoryStore(objectStore),
+		storage.NewAuditTrail(objectStore),
+		config.Channels,
+		config.AdminTokens,
+	)
+}
+
+const artifactRequestTimeout = 5 * time.Minute
+
+func createFilesHandler(
+	product api.Product,
+	releases storage.ReleaseHistoryStore,
+	yanked storage.YankedVersionStore,
+	manifests storage.ReleaseManifestStore,
+	artifactObjects storage.ObjectStore,
+	eventSink events.EventSink,
+) http.Handler {
+	if artifactObjects == nil {
+		return api.NewFilesHandler(product, eventSink, releases, yanked, manifests)
+	}
+
+	cache := storage.NewArtifactCache(storage.NewProductObjectStore(artifactObjects, product.Name))
+	client := &http.Client{Timeout: artifactRequestTimeout}
+
+	return api.NewProxyingFilesHandler(product, eventSink, releases, yanked, manifests, cache, client)
+}
+
+const gitHubRequestTimeout = 30 * time.Second
+
+func createGitHubSyncer(objectStore storage.ObjectStore, config *serviceConfig) github.Syncer {
+	client := github.NewClient(config.GitHubAPIURL, config.GitHubRepository, config.GitHubToken, &http.Client{Timeout: gitHubRequestTimeout})
+	rules := []github.ChannelRule{{Channel: storage.StableChannel, IncludePrereleases: false}}
+
+	for _, channel := range config.GitHubPrereleaseChannels {
+		rules = append(rules, github.ChannelRule{Channel: channel, IncludePrereleases: true})
+	}
+
+	return github.NewSyncer(
+		client,
+		storage.NewLatestVersionStore(objectStore),
+		storage.NewLatestVersionGenerationStore(objectStore),
+		storage.NewLatestVersionPublisher(objectStore),
+		storage.NewDescriptorHistoryStore(objectStore),
+		storage.NewAuditTrail(objectStore),
+		storage.NewReleaseHistoryUpdater(objectStore),
+		rules,
+	)
+}
 
-	return api.NewLatestHandler(store, eventSink)
+// backgroundContext returns a context for work that happens outside of a request, such as refreshing caches.
+func backgroundContext() context.Context {
+	return middleware.ContextWithLogger(context.Background(), logrus.StandardLogger())
 }
 
 func createCloudStorageClient() (*cloudstorage.Client, error) {
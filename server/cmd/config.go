@@ -20,9 +20,17 @@
 package main
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/batect/updates.batect.dev/server/api"
+	"github.com/batect/updates.batect.dev/server/signing"
+	"github.com/batect/updates.batect.dev/server/storage"
 	"github.com/sirupsen/logrus"
 )
 
@@ -32,15 +40,68 @@ type serviceConfig struct {
 	Port            string
 	ProjectID       string
 	HoneycombAPIKey string
+
+	LatestVersionRefreshInterval time.Duration
+	LatestVersionCacheControl    string
+	Channels                     []string
+
+	// Snapshots of latest version descriptors are disabled if DescriptorSnapshotDirectory is empty.
+	DescriptorSnapshotDirectory string
+
+	// Products always includes the default product first.
+	Products []api.Product
+
+	StorageBackend   storageBackend
+	StorageDirectory string
+
+	EventSinkBackend   eventSinkBackend
+	EventSinkDirectory string
+
+	// Files are only proxied if ArtifactCacheBackend is set.
+	ArtifactCacheBackend  artifactCacheBackend
+	ArtifactCacheLocation string
+
+	GitHubAPIURL             string
+	GitHubRepository         string
+	GitHubToken              string
+	GitHubPrereleaseChannels []string
+
+	// The storage notification endpoint is disabled if neither StorageNotificationServiceAccount nor StorageNotificationToken is set.
+	StorageNotificationAudience       string
+	StorageNotificationServiceAccount string
+	StorageNotificationToken          string
+
+	// Only the first of SigningKeys is used for signing. Signing is disabled if this is empty.
+	SigningKeys []signing.Key
+
+	// The admin API is disabled if AdminTokens is empty.
+	AdminTokens map[string]string
 }
 
-func getConfig() (*serviceConfig, error) {
-	port, err := getPort()
+type storageBackend string
 
-	if err != nil {
-		return nil, fmt.Errorf("could not get port for service to listen to: %w", err)
-	}
+const (
+	cloudStorageStorageBackend storageBackend = "cloudstorage"
+	filesystemStorageBackend   storageBackend = "filesystem"
+)
+
+type eventSinkBackend string
+
+const (
+	cloudStorageEventSinkBackend eventSinkBackend = "cloudstorage"
+	filesystemEventSinkBackend   eventSinkBackend = "filesystem"
+	memoryEventSinkBackend       eventSinkBackend = "memory"
+)
+
+type artifactCacheBackend string
 
+const (
+	noArtifactCacheBackend           artifactCacheBackend = ""
+	cloudStorageArtifactCacheBackend artifactCacheBackend = "cloudstorage"
+	filesystemArtifactCacheBackend   artifactCacheBackend = "filesystem"
+)
+
+func getConfig() (*serviceConfig, error) {
 	projectID, err := getProjectID()
 
 	if err != nil {
@@ -53,12 +114,78 @@ func getConfig() (*serviceConfig, error) {
 		return nil, fmt.Errorf("could not get Honeycomb API key: %w", err)
 	}
 
+	latestVersionRefreshInterval, err := getLatestVersionRefreshInterval()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get latest version refresh interval: %w", err)
+	}
+
+	storageBackend, storageDirectory, err := getStorageBackend()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get storage backend: %w", err)
+	}
+
+	eventSinkBackend, eventSinkDirectory, err := getEventSinkBackend()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get event sink backend: %w", err)
+	}
+
+	artifactCacheBackend, artifactCacheLocation, err := getArtifactCacheBackend()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get artifact cache backend: %w", err)
+	}
+
+	products, err := getProducts()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get products: %w", err)
+	}
+
+	if err := validateStorageNotificationConfig(); err != nil {
+		return nil, fmt.Errorf("could not get storage notification configuration: %w", err)
+	}
+
+	signingKeys, err := getSigningKeys()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get signing keys: %w", err)
+	}
+
+	adminTokens, err := getAdminTokens()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get admin tokens: %w", err)
+	}
+
 	return &serviceConfig{
-		ServiceName:     getServiceName(),
-		ServiceVersion:  getServiceVersion(),
-		Port:            port,
-		ProjectID:       projectID,
-		HoneycombAPIKey: honeycombAPIKey,
+		ServiceName:                       getServiceName(),
+		ServiceVersion:                    getServiceVersion(),
+		Port:                              getPort(),
+		ProjectID:                         projectID,
+		HoneycombAPIKey:                   honeycombAPIKey,
+		LatestVersionRefreshInterval:      latestVersionRefreshInterval,
+		LatestVersionCacheControl:         getLatestVersionCacheControl(),
+		Channels:                          getChannels(),
+		DescriptorSnapshotDirectory:       os.Getenv("DESCRIPTOR_SNAPSHOT_DIRECTORY"),
+		Products:                          products,
+		StorageBackend:                    storageBackend,
+		StorageDirectory:                  storageDirectory,
+		EventSinkBackend:                  eventSinkBackend,
+		EventSinkDirectory:                eventSinkDirectory,
+		ArtifactCacheBackend:              artifactCacheBackend,
+		ArtifactCacheLocation:             artifactCacheLocation,
+		GitHubAPIURL:                      getEnvOrDefault("GITHUB_API_URL", "https://api.github.com"),
+		GitHubRepository:                  getEnvOrDefault("GITHUB_REPOSITORY", "batect/batect"),
+		GitHubToken:                       os.Getenv("GITHUB_TOKEN"),
+		GitHubPrereleaseChannels:          getGitHubPrereleaseChannels(),
+		StorageNotificationAudience:       os.Getenv("STORAGE_NOTIFICATION_AUDIENCE"),
+		StorageNotificationServiceAccount: os.Getenv("STORAGE_NOTIFICATION_SERVICE_ACCOUNT"),
+		StorageNotificationToken:          os.Getenv("STORAGE_NOTIFICATION_TOKEN"),
+		SigningKeys:                       signingKeys,
+		AdminTokens:                       adminTokens,
 	}, nil
 }
 
@@ -78,8 +205,29 @@ func getEnvOrDefault(name string, fallback string) string {
 	return fallback
 }
 
-func getPort() (string, error) {
-	return getEnv("PORT")
+func getDurationEnvOrDefault(name string, fallback time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(name)
+
+	if !ok {
+		return fallback, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil {
+		return 0, fmt.Errorf("environment variable '%v' is not a valid duration: %w", name, err)
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("environment variable '%v' must be a positive duration", name)
+	}
+
+	return duration, nil
+}
+
+// Cloud Run sets PORT for the service, but not for jobs, which don't listen for requests.
+func getPort() string {
+	return getEnvOrDefault("PORT", "8080")
 }
 
 func getProjectID() (string, error) {
@@ -90,6 +238,342 @@ func getHoneycombAPIKey() (string, error) {
 	return getEnv("HONEYCOMB_API_KEY")
 }
 
+func getLatestVersionRefreshInterval() (time.Duration, error) {
+	return getDurationEnvOrDefault("LATEST_VERSION_REFRESH_INTERVAL", time.Minute)
+}
+
+// Why default to no-cache? It allows clients and intermediate caches to store the descriptor, but requires them to revalidate it with us
+// (which is cheap, thanks to ETag and Last-Modified) before using it, so new releases are seen immediately.
+func getLatestVersionCacheControl() string {
+	return getEnvOrDefault("LATEST_VERSION_CACHE_CONTROL", "no-cache")
+}
+
+// Only the stable channel is published by default, so other channels must be enabled once their descriptors have been published.
+func getChannels() []string {
+	return append([]string{storage.StableChannel}, getChannelListEnvOrDefault("CHANNELS", "")...)
+}
+
+func getChannelListEnvOrDefault(name string, fallback string) []string {
+	channels := []string{}
+
+	for _, channel := range strings.Split(getEnvOrDefault(name, fallback), ",") {
+		if channel = strings.TrimSpace(channel); channel != "" && channel != storage.StableChannel {
+			channels = append(channels, channel)
+		}
+	}
+
+	return channels
+}
+
+//nolint:gochecknoglobals
+var defaultProduct = api.Product{
+	Name: storage.DefaultProduct,
+	Origins: []api.DownloadOrigin{
+		{Name: "github", URLTemplate: "https://github.com/batect/batect/releases/download/{version}/{fileName}"},
+	},
+	Artifacts: []api.Artifact{
+		{FileName: "batect-{version}.jar", Kind: api.ArtifactKindJar},
+		{FileName: "batect", Kind: api.ArtifactKindWrapperScript, Platform: "unix"},
+		{FileName: "batect.cmd", Kind: api.ArtifactKindWrapperScript, Platform: "windows"},
+	},
+}
+
+// Product and download origin names share the same rules.
+//
+//nolint:gochecknoglobals
+var productNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type productConfig struct {
+	Name      string           `json:"name"`
+	Origins   []originConfig   `json:"origins"`
+	Artifacts []artifactConfig `json:"artifacts"`
+}
+
+type artifactConfig struct {
+	FileName       string           `json:"fileName"`
+	Kind           api.ArtifactKind `json:"kind"`
+	Platform       string           `json:"platform"`
+	RedirectTarget string           `json:"redirectTarget"`
+}
+
+type originConfig struct {
+	Name        string `json:"name"`
+	URLTemplate string `json:"urlTemplate"`
+	Weight      int    `json:"weight"`
+}
+
+// PRODUCTS is a JSON array of additional products, eg.
+// [{"name": "my-plugin", "origins": [{"name": "github", "urlTemplate": "https://github.com/batect/my-plugin/releases/download/{version}/{fileName}"}],
+// "artifacts": [{"fileName": "my-plugin-{version}.zip", "kind": "other"}, {"fileName": "my-plugin-{version}.spdx.json", "kind": "sbom",
+// "redirectTarget": "https://sboms.example.com/my-plugin/{version}/{fileName}"}]}]
+//
+// DOWNLOAD_ORIGINS replaces GitHub as the origin of the default product's files, in the same format as in PRODUCTS.
+func getProducts() ([]api.Product, error) {
+	product := defaultProduct
+
+	if value := os.Getenv("DOWNLOAD_ORIGINS"); strings.TrimSpace(value) != "" {
+		var configs []originConfig
+
+		if err := json.Unmarshal([]byte(value), &configs); err != nil {
+			return nil, fmt.Errorf("environment variable 'DOWNLOAD_ORIGINS' is not a valid JSON array of download origins: %w", err)
+		}
+
+		if err := validateOriginConfigs(product.Name, configs); err != nil {
+			return nil, fmt.Errorf("environment variable 'DOWNLOAD_ORIGINS' is invalid: %w", err)
+		}
+
+		product.Origins = toDownloadOrigins(configs)
+	}
+
+	products := []api.Product{product}
+	value, ok := os.LookupEnv("PRODUCTS")
+
+	if !ok || strings.TrimSpace(value) == "" {
+		return products, nil
+	}
+
+	var configs []productConfig
+
+	if err := json.Unmarshal([]byte(value), &configs); err != nil {
+		return nil, fmt.Errorf("environment variable 'PRODUCTS' is not a valid JSON array of products: %w", err)
+	}
+
+	seen := map[string]struct{}{storage.DefaultProduct: {}}
+
+	for _, config := range configs {
+		if err := validateProductConfig(config); err != nil {
+			return nil, fmt.Errorf("environment variable 'PRODUCTS' contains an invalid product: %w", err)
+		}
+
+		if _, duplicate := seen[config.Name]; duplicate {
+			return nil, fmt.Errorf("environment variable 'PRODUCTS' contains more than one product named '%v'", config.Name)
+		}
+
+		seen[config.Name] = struct{}{}
+		products = append(products, api.Product{Name: config.Name, Origins: toDownloadOrigins(config.Origins), Artifacts: toArtifacts(config.Artifacts)})
+	}
+
+	return products, nil
+}
+
+func validateProductConfig(config productConfig) error {
+	if !productNamePattern.MatchString(config.Name) {
+		return fmt.Errorf("product name '%v' must contain only lowercase letters, digits and single hyphens", config.Name)
+	}
+
+	if err := validateOriginConfigs(config.Name, config.Origins); err != nil {
+		return err
+	}
+
+	return validateArtifactConfigs(config.Name, config.Artifacts)
+}
+
+func validateArtifactConfigs(product string, configs []artifactConfig) error {
+	if len(configs) == 0 {
+		return fmt.Errorf("product '%v' must have at least one artifact", product)
+	}
+
+	seen := map[string]struct{}{}
+
+	for _, config := range configs {
+		if config.FileName == "" || strings.Contains(config.FileName, "/") {
+			return fmt.Errorf("file name '%v' for product '%v' must not be empty and must not contain '/'", config.FileName, product)
+		}
+
+		if _, duplicate := seen[config.FileName]; duplicate {
+			return fmt.Errorf("product '%v' has more than one artifact named '%v'", product, config.FileName)
+		}
+
+		seen[config.FileName] = struct{}{}
+
+		if !config.Kind.IsKnown() {
+			return fmt.Errorf("artifact '%v' of product '%v' has unknown kind '%v'", config.FileName, product, config.Kind)
+		}
+
+		if config.RedirectTarget != "" && (!strings.HasPrefix(config.RedirectTarget, "https://") || !strings.Contains(config.RedirectTarget, "{version}")) {
+			return fmt.Errorf("redirect target for artifact '%v' of product '%v' must be a HTTPS URL containing '{version}'", config.FileName, product)
+		}
+	}
+
+	return nil
+}
+
+func toArtifacts(configs []artifactConfig) []api.Artifact {
+	artifacts := make([]api.Artifact, 0, len(configs))
+
+	for _, config := range configs {
+		artifacts = append(artifacts, api.Artifact(config))
+	}
+
+	return artifacts
+}
+
+func validateOriginConfigs(product string, configs []originConfig) error {
+	if len(configs) == 0 {
+		return fmt.Errorf("product '%v' must have at least one download origin", product)
+	}
+
+	seen := map[string]struct{}{}
+
+	for _, config := range configs {
+		if !productNamePattern.MatchString(config.Name) {
+			return fmt.Errorf("download origin name '%v' for product '%v' must contain only lowercase letters, digits and single hyphens", config.Name, product)
+		}
+
+		if _, duplicate := seen[config.Name]; duplicate {
+			return fmt.Errorf("product '%v' has more than one download origin named '%v'", product, config.Name)
+		}
+
+		seen[config.Name] = struct{}{}
+
+		if !strings.HasPrefix(config.URLTemplate, "https://") || !strings.Contains(config.URLTemplate, "{version}") || !strings.Contains(config.URLTemplate, "{fileName}") {
+			return fmt.Errorf("URL template for download origin '%v' of product '%v' must be a HTTPS URL containing '{version}' and '{fileName}'", config.Name, product)
+		}
+
+		if config.Weight < 0 {
+			return fmt.Errorf("weight for download origin '%v' of product '%v' must not be negative", config.Name, product)
+		}
+	}
+
+	return nil
+}
+
+func toDownloadOrigins(configs []originConfig) []api.DownloadOrigin {
+	origins := make([]api.DownloadOrigin, 0, len(configs))
+
+	for _, config := range configs {
+		origins = append(origins, api.DownloadOrigin(config))
+	}
+
+	return origins
+}
+
+// The stable channel never includes pre-releases, so there's no way to opt it in here.
+func getGitHubPrereleaseChannels() []string {
+	return getChannelListEnvOrDefault("GITHUB_PRERELEASE_CHANNELS", "")
+}
+
+// ADMIN_TOKENS is a comma-separated list of name=token pairs, eg. "alice=abc123,bob=def456".
+func getAdminTokens() (map[string]string, error) {
+	tokens := map[string]string{}
+
+	for _, pair := range strings.Split(os.Getenv("ADMIN_TOKENS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		name, token, ok := strings.Cut(pair, "=")
+
+		if !ok || name == "" || token == "" {
+			return nil, fmt.Errorf("environment variable 'ADMIN_TOKENS' must be a comma-separated list of name=token pairs")
+		}
+
+		if _, duplicate := tokens[name]; duplicate {
+			return nil, fmt.Errorf("environment variable 'ADMIN_TOKENS' contains more than one token for '%v'", name)
+		}
+
+		tokens[name] = token
+	}
+
+	return tokens, nil
+}
+
+func validateStorageNotificationConfig() error {
+	if os.Getenv("STORAGE_NOTIFICATION_SERVICE_ACCOUNT") != "" && os.Getenv("STORAGE_NOTIFICATION_AUDIENCE") == "" {
+		return fmt.Errorf("environment variable 'STORAGE_NOTIFICATION_AUDIENCE' must be set if 'STORAGE_NOTIFICATION_SERVICE_ACCOUNT' is set")
+	}
+
+	return nil
+}
+
+// SIGNING_KEYS is a comma-separated list of id=seed pairs, where each seed is a base64-encoded Ed25519 private key seed.
+func getSigningKeys() ([]signing.Key, error) {
+	keys := []signing.Key{}
+
+	for _, pair := range strings.Split(os.Getenv("SIGNING_KEYS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		id, encodedSeed, ok := strings.Cut(pair, "=")
+
+		if !ok {
+			return nil, fmt.Errorf("environment variable 'SIGNING_KEYS' must be a comma-separated list of id=seed pairs")
+		}
+
+		seed, err := base64.StdEncoding.DecodeString(encodedSeed)
+
+		if err != nil {
+			return nil, fmt.Errorf("environment variable 'SIGNING_KEYS' contains a seed for key '%v' that is not valid base64: %w", id, err)
+		}
+
+		key, err := signing.NewKeyFromSeed(id, seed)
+
+		if err != nil {
+			return nil, fmt.Errorf("environment variable 'SIGNING_KEYS' contains an invalid key: %w", err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func getStorageBackend() (storageBackend, string, error) {
+	backend := storageBackend(getEnvOrDefault("STORAGE_BACKEND", string(cloudStorageStorageBackend)))
+
+	switch backend {
+	case cloudStorageStorageBackend:
+		return backend, "", nil
+	case filesystemStorageBackend:
+		directory, err := getEnv("STORAGE_DIRECTORY")
+
+		return backend, directory, err
+	default:
+		return "", "", fmt.Errorf("unknown storage backend '%v'", backend)
+	}
+}
+
+func getEventSinkBackend() (eventSinkBackend, string, error) {
+	backend := eventSinkBackend(getEnvOrDefault("EVENT_SINK", string(cloudStorageEventSinkBackend)))
+
+	switch backend {
+	case cloudStorageEventSinkBackend, memoryEventSinkBackend:
+		return backend, "", nil
+	case filesystemEventSinkBackend:
+		directory, err := getEnv("EVENT_SINK_DIRECTORY")
+
+		return backend, directory, err
+	default:
+		return "", "", fmt.Errorf("unknown event sink '%v'", backend)
+	}
+}
+
+func getArtifactCacheBackend() (artifactCacheBackend, string, error) {
+	backend := artifactCacheBackend(os.Getenv("ARTIFACT_CACHE"))
+
+	switch backend {
+	case noArtifactCacheBackend:
+		return backend, "", nil
+	case filesystemArtifactCacheBackend:
+		directory, err := getEnv("ARTIFACT_CACHE_DIRECTORY")
+
+		return backend, directory, err
+	case cloudStorageArtifactCacheBackend:
+		bucketName, err := getEnv("ARTIFACT_CACHE_BUCKET")
+
+		return backend, bucketName, err
+	default:
+		return "", "", fmt.Errorf("unknown artifact cache '%v'", backend)
+	}
+}
+
+func (c *serviceConfig) RequiresCloudStorage() bool {
+	return c.StorageBackend == cloudStorageStorageBackend ||
+		c.EventSinkBackend == cloudStorageEventSinkBackend ||
+		c.ArtifactCacheBackend == cloudStorageArtifactCacheBackend
+}
+
 func getCredentialsFilePath() string {
 	variableName := "GOOGLE_APPLICATION_CREDENTIALS"
 	value := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
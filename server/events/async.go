@@ -0,0 +1,226 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+type AsyncEventSink interface {
+	EventSink
+
+	// Shutdown waits until every queued event has been posted or ctx is done.
+	Shutdown(ctx context.Context) error
+
+	Stats() AsyncEventSinkStats
+}
+
+type AsyncEventSinkStats struct {
+	Succeeded uint64
+	Failed    uint64
+	Dropped   uint64
+}
+
+const postTimeout = 30 * time.Second
+
+const defaultDropReportInterval = time.Minute
+
+type queuedEvent struct {
+	logger logrus.FieldLogger
+	post   func(ctx context.Context)
+}
+
+type writeFailureCounter interface {
+	WriteFailures() uint64
+}
+
+type asyncSink struct {
+	underlying EventSink
+	queue      chan queuedEvent
+
+	lock   sync.RWMutex
+	closed bool
+
+	workers       sync.WaitGroup
+	workerContext context.Context
+	stopWorkers   context.CancelFunc
+
+	attempted atomic.Uint64
+	panicked  atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// Events are dropped if the queue is full, rather than making the caller wait.
+func NewAsyncEventSink(underlying EventSink, queueSize int, workers int) AsyncEventSink {
+	return NewAsyncEventSinkWithSpecificDependencies(underlying, queueSize, workers, defaultDropReportInterval, logrus.StandardLogger())
+}
+
+func NewAsyncEventSinkWithSpecificDependencies(
+	underlying EventSink,
+	queueSize int,
+	workers int,
+	dropReportInterval time.Duration,
+	logger logrus.FieldLogger,
+) AsyncEventSink {
+	workerContext, stopWorkers := context.WithCancel(context.Background())
+
+	s := &asyncSink{
+		underlying:    underlying,
+		queue:         make(chan queuedEvent, queueSize),
+		workerContext: workerContext,
+		stopWorkers:   stopWorkers,
+	}
+
+	s.workers.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+
+	go s.reportDropsPeriodically(dropReportInterval, logger)
+
+	return s
+}
+
+func (s *asyncSink) PostLatestVersionCheck(ctx context.Context, check LatestVersionCheck) {
+	s.enqueue(ctx, func(ctx context.Context) { s.underlying.PostLatestVersionCheck(ctx, check) })
+}
+
+func (s *asyncSink) PostFileDownload(ctx context.Context, download FileDownload) {
+	s.enqueue(ctx, func(ctx context.Context) { s.underlying.PostFileDownload(ctx, download) })
+}
+
+func (s *asyncSink) PostFileNotFound(ctx context.Context, notFound FileNotFound) {
+	s.enqueue(ctx, func(ctx context.Context) { s.underlying.PostFileNotFound(ctx, notFound) })
+}
+
+func (s *asyncSink) PostFileBlocked(ctx context.Context, blocked FileBlocked) {
+	s.enqueue(ctx, func(ctx context.Context) { s.underlying.PostFileBlocked(ctx, blocked) })
+}
+
+func (s *asyncSink) enqueue(ctx context.Context, post func(ctx context.Context)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		s.dropped.Add(1)
+
+		return
+	}
+
+	select {
+	case s.queue <- queuedEvent{logger: middleware.LoggerFromContext(ctx), post: post}:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *asyncSink) work() {
+	defer s.workers.Done()
+
+	for e := range s.queue {
+		s.attempted.Add(1)
+		s.post(e)
+	}
+}
+
+func (s *asyncSink) post(e queuedEvent) {
+	ctx, cancel := context.WithTimeout(middleware.ContextWithLogger(s.workerContext, e.logger), postTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.panicked.Add(1)
+			e.logger.WithField("panic", r).Error("Posting event panicked.")
+		}
+	}()
+
+	e.post(ctx)
+}
+
+func (s *asyncSink) reportDropsPeriodically(interval time.Duration, logger logrus.FieldLogger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reported := uint64(0)
+
+	for {
+		select {
+		case <-s.workerContext.Done():
+			return
+		case <-ticker.C:
+			dropped := s.dropped.Load()
+
+			if dropped > reported {
+				logger.WithField("dropped", dropped-reported).Warn("Dropped events that could not be queued.")
+				reported = dropped
+			}
+		}
+	}
+}
+
+func (s *asyncSink) Shutdown(ctx context.Context) error {
+	s.lock.Lock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+
+	s.lock.Unlock()
+
+	drained := make(chan struct{})
+
+	go func() {
+		s.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		s.stopWorkers()
+		return nil
+	case <-ctx.Done():
+		s.stopWorkers()
+		return fmt.Errorf("could not post all queued events before the shutdown deadline: %w", ctx.Err())
+	}
+}
+
+func (s *asyncSink) Stats() AsyncEventSinkStats {
+	// Failures must be read before attempts, as each failure is counted after its attempt.
+	failed := s.panicked.Load()
+
+	if counter, ok := s.underlying.(writeFailureCounter); ok {
+		failed += counter.WriteFailures()
+	}
+
+	return AsyncEventSinkStats{
+		Succeeded: s.attempted.Load() - failed,
+		Failed:    failed,
+		Dropped:   s.dropped.Load(),
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package events_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/events"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("Posting events asynchronously", func() {
+	var underlying *blockingEventSink
+	var sink events.AsyncEventSink
+	var ctx context.Context
+	var hook *test.Hook
+	var dropReportHook *test.Hook
+
+	BeforeEach(func() {
+		var dropReportLogger *logrus.Logger
+		dropReportLogger, dropReportHook = test.NewNullLogger()
+
+		underlying = newBlockingEventSink()
+		sink = events.NewAsyncEventSinkWithSpecificDependencies(underlying, 1, 1, 10*time.Millisecond, dropReportLogger)
+		ctx, hook = testutils.ContextWithTestLogger(context.Background())
+	})
+
+	AfterEach(func() {
+		underlying.Unblock()
+		_ = sink.Shutdown(context.Background())
+	})
+
+	Context("posting an event", func() {
+		BeforeEach(func() {
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", Version: "0.83.2"})
+		})
+
+		It("returns before the underlying sink has finished posting the event", func() {
+			Eventually(underlying.Started).Should(Receive(Equal("0.83.2")))
+			Expect(underlying.Posted()).To(BeEmpty())
+		})
+
+		It("posts the event to the underlying sink in the background", func() {
+			underlying.Unblock()
+
+			Eventually(underlying.Posted).Should(ConsistOf("0.83.2"))
+			Eventually(sink.Stats).Should(Equal(events.AsyncEventSinkStats{Succeeded: 1}))
+		})
+
+		It("posts the event with a context that is not cancelled when the request's context is", func() {
+			underlying.Unblock()
+
+			Eventually(underlying.Posted).Should(HaveLen(1))
+			Expect(underlying.ContextErrors()).To(ConsistOf(BeNil()))
+		})
+	})
+
+	Context("posting more events than the queue can hold", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", Version: "0.83.0"})
+			Eventually(underlying.Started).Should(Receive(Equal("0.83.0")))
+
+			sink.PostFileNotFound(ctx, events.FileNotFound{Product: "batect", Version: "0.83.1"})
+			sink.PostFileBlocked(ctx, events.FileBlocked{Product: "batect", Version: "0.83.2"})
+		})
+
+		It("drops the events that do not fit in the queue", func() {
+			underlying.Unblock()
+
+			Eventually(underlying.Posted).Should(ConsistOf("0.83.0", "0.83.1"))
+			Consistently(underlying.Posted, "20ms").Should(HaveLen(2))
+			Eventually(sink.Stats).Should(Equal(events.AsyncEventSinkStats{Succeeded: 2, Dropped: 1}))
+		})
+
+		It("periodically logs the number of dropped events", func() {
+			Eventually(dropReportHook.LastEntry).ShouldNot(BeNil())
+
+			entry := dropReportHook.LastEntry()
+			Expect(entry.Message).To(Equal("Dropped events that could not be queued."))
+			Expect(entry.Data).To(HaveKeyWithValue("dropped", uint64(1)))
+		})
+
+		It("does not log dropped events again once they have been reported", func() {
+			Eventually(dropReportHook.AllEntries).Should(HaveLen(1))
+			Consistently(dropReportHook.AllEntries, "50ms").Should(HaveLen(1))
+		})
+	})
+
+	Context("posting an event that the underlying sink fails to post", func() {
+		BeforeEach(func() {
+			_ = sink.Shutdown(context.Background())
+
+			// A sink that tries to write events inside a file, rather than a directory, will always fail.
+			file := filepath.Join(GinkgoT().TempDir(), "not-a-directory")
+			Expect(os.WriteFile(file, nil, 0o600)).To(Succeed())
+
+			sink = events.NewAsyncEventSink(events.NewFilesystemEventSink(file, 1000), 1, 1)
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", Version: "0.83.2"})
+		})
+
+		It("counts the event as failed", func() {
+			Eventually(sink.Stats).Should(Equal(events.AsyncEventSinkStats{Failed: 1}))
+		})
+
+		It("logs the failure with the logger from the context the event was posted with", func() {
+			Eventually(func() []string {
+				messages := []string{}
+
+				for _, e := range hook.AllEntries() {
+					messages = append(messages, e.Message)
+				}
+
+				return messages
+			}).Should(ContainElement("Failed to post file download event."))
+		})
+	})
+
+	Context("posting an event that causes the underlying sink to panic", func() {
+		BeforeEach(func() {
+			_ = sink.Shutdown(context.Background())
+			sink = events.NewAsyncEventSink(underlying, 10, 1)
+			underlying.Unblock()
+			underlying.PanicOnNextEvent()
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", Version: "0.83.2"})
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", Version: "0.83.3"})
+		})
+
+		It("counts the event as failed and continues posting other events", func() {
+			Eventually(sink.Stats).Should(Equal(events.AsyncEventSinkStats{Succeeded: 1, Failed: 1}))
+			Expect(underlying.Posted()).To(ConsistOf("0.83.3"))
+		})
+	})
+
+	Context("shutting down", func() {
+		Context("when all queued events are posted before the deadline", func() {
+			var err error
+
+			BeforeEach(func() {
+				_ = sink.Shutdown(context.Background())
+				sink = events.NewAsyncEventSink(underlying, 10, 2)
+
+				for _, version := range []string{"0.83.0", "0.83.1", "0.83.2"} {
+					sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", Version: version})
+				}
+
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					underlying.Unblock()
+				}()
+
+				err = sink.Shutdown(context.Background())
+			})
+
+			It("waits for all queued events to be posted", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(underlying.Posted()).To(ConsistOf("0.83.0", "0.83.1", "0.83.2"))
+			})
+
+			It("drops events posted after shutting down", func() {
+				sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", Version: "0.84.0"})
+
+				Expect(sink.Stats()).To(Equal(events.AsyncEventSinkStats{Succeeded: 3, Dropped: 1}))
+			})
+		})
+
+		Context("when the deadline passes before all queued events are posted", func() {
+			var err error
+
+			BeforeEach(func() {
+				sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", Version: "0.83.0"})
+				Eventually(underlying.Started).Should(Receive())
+
+				deadline, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+
+				err = sink.Shutdown(deadline)
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("could not post all queued events before the shutdown deadline")))
+				Expect(err).To(MatchError(context.DeadlineExceeded))
+			})
+
+			It("cancels the context of events that are still being posted", func() {
+				underlying.Unblock()
+
+				Eventually(underlying.ContextErrors).Should(ConsistOf(MatchError(context.Canceled)))
+			})
+		})
+	})
+})
+
+// blockingEventSink records the version of each event posted to it, but does not finish posting any event until Unblock is called.
+type blockingEventSink struct {
+	Started chan string
+	release chan struct{}
+	once    sync.Once
+
+	lock          sync.Mutex
+	posted        []string
+	contextErrors []error
+	panicNext     bool
+}
+
+func newBlockingEventSink() *blockingEventSink {
+	return &blockingEventSink{
+		Started: make(chan string, 100),
+		release: make(chan struct{}),
+		posted:  []string{},
+	}
+}
+
+func (b *blockingEventSink) Unblock() {
+	b.once.Do(func() { close(b.release) })
+}
+
+func (b *blockingEventSink) PanicOnNextEvent() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.panicNext = true
+}
+
+func (b *blockingEventSink) Posted() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return append([]string{}, b.posted...)
+}
+
+func (b *blockingEventSink) ContextErrors() []error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return append([]error{}, b.contextErrors...)
+}
+
+func (b *blockingEventSink) post(ctx context.Context, version string) {
+	b.Started <- version
+	<-b.release
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.panicNext {
+		b.panicNext = false
+		panic("something went wrong")
+	}
+
+	b.posted = append(b.posted, version)
+	b.contextErrors = append(b.contextErrors, ctx.Err())
+}
+
+func (b *blockingEventSink) PostLatestVersionCheck(ctx context.Context, check events.LatestVersionCheck) {
+	b.post(ctx, check.Version)
+}
+
+func (b *blockingEventSink) PostFileDownload(ctx context.Context, download events.FileDownload) {
+	b.post(ctx, download.Version)
+}
+
+func (b *blockingEventSink) PostFileNotFound(ctx context.Context, notFound events.FileNotFound) {
+	b.post(ctx, notFound.Version)
+}
+
+func (b *blockingEventSink) PostFileBlocked(ctx context.Context, blocked events.FileBlocked) {
+	b.post(ctx, blocked.Version)
+}
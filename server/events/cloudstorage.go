@@ -27,69 +27,30 @@ import (
 	"time"
 
 	cloudstorage "cloud.google.com/go/storage"
-	"github.com/batect/services-common/middleware"
 	"github.com/google/uuid"
 )
 
-type cloudStorageEventSink struct {
-	client     *cloudstorage.Client
-	bucket     *cloudstorage.BucketHandle
-	timeSource func() time.Time
-	uuidSource func() uuid.UUID
+type cloudStorageEventWriter struct {
+	client *cloudstorage.Client
+	bucket *cloudstorage.BucketHandle
 }
 
 func NewCloudStorageEventSink(bucketName string, client *cloudstorage.Client) EventSink {
-	timeSource := func() time.Time { return time.Now().UTC() }
-
-	return NewCloudStorageEventSinkWithSpecificDependencies(bucketName, client, timeSource, uuid.New)
+	return NewCloudStorageEventSinkWithSpecificDependencies(bucketName, client, defaultTimeSource, uuid.New)
 }
 
 func NewCloudStorageEventSinkWithSpecificDependencies(bucketName string, client *cloudstorage.Client, timeSource func() time.Time, uuidSource func() uuid.UUID) EventSink {
-	return &cloudStorageEventSink{
-		client:     client,
-		bucket:     client.Bucket(bucketName),
-		timeSource: timeSource,
-		uuidSource: uuidSource,
-	}
-}
-
-func (c *cloudStorageEventSink) PostLatestVersionCheck(ctx context.Context, userAgent string) {
-	timestamp := c.timeSource()
-	eventID := c.uuidSource()
-
-	event := map[string]interface{}{
-		"eventId":   eventID,
-		"timestamp": timestamp,
-		"userAgent": userAgent,
-	}
-
-	if err := c.postEvent(ctx, "v1/latest", timestamp, eventID, event); err != nil {
-		log := middleware.LoggerFromContext(ctx)
-		log.WithError(err).Error("Failed to post latest version check event.")
-	}
-}
-
-func (c *cloudStorageEventSink) PostFileDownload(ctx context.Context, userAgent string, version string, fileName string) {
-	timestamp := c.timeSource()
-	eventID := c.uuidSource()
-
-	event := map[string]interface{}{
-		"eventId":   eventID,
-		"timestamp": timestamp,
-		"userAgent": userAgent,
-		"version":   version,
-		"fileName":  fileName,
+	writer := &cloudStorageEventWriter{
+		client: client,
+		bucket: client.Bucket(bucketName),
 	}
 
-	if err := c.postEvent(ctx, "v1/files", timestamp, eventID, event); err != nil {
-		log := middleware.LoggerFromContext(ctx)
-		log.WithError(err).Error("Failed to post file download event.")
-	}
+	return newSink(writer, timeSource, uuidSource)
 }
 
-func (c *cloudStorageEventSink) postEvent(ctx context.Context, prefix string, timestamp time.Time, eventID uuid.UUID, event map[string]interface{}) error {
+func (c *cloudStorageEventWriter) Write(ctx context.Context, e event) error {
 	w := c.bucket.
-		Object(fmt.Sprintf("%v/%v/%02d/%02d/%v.json", prefix, timestamp.Year(), timestamp.Month(), timestamp.Day(), eventID)).
+		Object(fmt.Sprintf("%v/%v/%02d/%02d/%v.json", e.Type, e.Timestamp.Year(), e.Timestamp.Month(), e.Timestamp.Day(), e.ID)).
 		If(cloudstorage.Conditions{DoesNotExist: true}).
 		NewWriter(ctx)
 
@@ -97,7 +58,7 @@ func (c *cloudStorageEventSink) postEvent(ctx context.Context, prefix string, ti
 	w.ContentEncoding = "gzip"
 	gzipper := gzip.NewWriter(w)
 
-	bytes, err := json.Marshal(event)
+	bytes, err := json.Marshal(e.Fields)
 
 	if err != nil {
 		return fmt.Errorf("converting event to JSON failed: %w", err)
@@ -74,7 +74,7 @@ var _ = Describe("Posting events to Cloud Storage", func() {
 			ctx := context.Background()
 			ctx, hook = testutils.ContextWithTestLogger(ctx)
 
-			sink.PostLatestVersionCheck(ctx, "MyCoolThing/1.2.3")
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "MyCoolThing/1.2.3", Channel: "beta", Version: "0.83.2"})
 		})
 
 		It("logs no messages", func() {
@@ -86,7 +86,10 @@ var _ = Describe("Posting events to Cloud Storage", func() {
 				{
 					"timestamp": "2021-03-01T09:54:40.123456789Z",
 					"eventId": "11112222-3333-4444-5555-666677778888",
-					"userAgent": "MyCoolThing/1.2.3"
+					"product": "batect",
+					"userAgent": "MyCoolThing/1.2.3",
+					"channel": "beta",
+					"version": "0.83.2"
 				}
 			`)))
 		})
@@ -107,7 +110,7 @@ var _ = Describe("Posting events to Cloud Storage", func() {
 			ctx := context.Background()
 			ctx, hook = testutils.ContextWithTestLogger(ctx)
 
-			sink.PostFileDownload(ctx, "MyCoolThing/1.2.3", "4.5.6", "batect-7.8.9.jar")
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", UserAgent: "MyCoolThing/1.2.3", Version: "4.5.6", FileName: "batect-7.8.9.jar", Origin: "github", ArtifactKind: "jar"})
 		})
 
 		It("logs no messages", func() {
@@ -119,9 +122,13 @@ var _ = Describe("Posting events to Cloud Storage", func() {
 				{
 					"timestamp": "2021-03-01T09:54:40.123456789Z",
 					"eventId": "11112222-3333-4444-5555-666677778888",
+					"product": "batect",
 					"userAgent": "MyCoolThing/1.2.3",
 					"version": "4.5.6",
-					"fileName": "batect-7.8.9.jar"
+					"fileName": "batect-7.8.9.jar",
+					"origin": "github",
+					"artifactKind": "jar",
+					"versionClass": "release"
 				}
 			`)))
 		})
@@ -0,0 +1,195 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package events
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/google/uuid"
+)
+
+type event struct {
+	// Type is the kind of event, and is used as the prefix for the location where it is stored.
+	Type      string
+	ID        uuid.UUID
+	Timestamp time.Time
+	Fields    map[string]interface{}
+}
+
+const (
+	latestVersionCheckEventType = "v1/latest"
+	fileDownloadEventType       = "v1/files"
+	fileNotFoundEventType       = "v1/files-not-found"
+	fileBlockedEventType        = "v1/files-blocked"
+)
+
+type versionClass string
+
+const (
+	versionClassRelease    versionClass = "release"
+	versionClassPrerelease versionClass = "prerelease"
+)
+
+// eventWriter stores events in a particular location, such as a Cloud Storage bucket or the local filesystem.
+type eventWriter interface {
+	Write(ctx context.Context, e event) error
+}
+
+// sink is the common implementation of EventSink used for all storage locations.
+type sink struct {
+	writer     eventWriter
+	timeSource func() time.Time
+	uuidSource func() uuid.UUID
+	failures   atomic.Uint64
+}
+
+func newSink(writer eventWriter, timeSource func() time.Time, uuidSource func() uuid.UUID) *sink {
+	return &sink{
+		writer:     writer,
+		timeSource: timeSource,
+		uuidSource: uuidSource,
+	}
+}
+
+func defaultTimeSource() time.Time {
+	return time.Now().UTC()
+}
+
+func (s *sink) PostLatestVersionCheck(ctx context.Context, check LatestVersionCheck) {
+	fields := map[string]interface{}{
+		"product":   check.Product,
+		"userAgent": check.UserAgent,
+		"channel":   check.Channel,
+		"version":   check.Version,
+	}
+
+	if check.RolloutDecision != "" {
+		fields["rolloutDecision"] = check.RolloutDecision
+		fields["rolloutPercentage"] = check.RolloutPercentage
+	}
+
+	e := s.newEvent(latestVersionCheckEventType, fields)
+
+	s.write(ctx, e, "latest version check")
+}
+
+func (s *sink) PostFileDownload(ctx context.Context, download FileDownload) {
+	fields := map[string]interface{}{
+		"product":      download.Product,
+		"userAgent":    download.UserAgent,
+		"version":      download.Version,
+		"fileName":     download.FileName,
+		"origin":       download.Origin,
+		"artifactKind": download.ArtifactKind,
+	}
+
+	if download.Platform != "" {
+		fields["platform"] = download.Platform
+	}
+
+	if download.Alias != "" {
+		fields["alias"] = download.Alias
+	}
+
+	if class, ok := classifyVersion(download.Version); ok {
+		fields["versionClass"] = string(class)
+	}
+
+	e := s.newEvent(fileDownloadEventType, fields)
+
+	s.write(ctx, e, "file download")
+}
+
+func (s *sink) PostFileNotFound(ctx context.Context, notFound FileNotFound) {
+	e := s.newEvent(fileNotFoundEventType, map[string]interface{}{
+		"product":   notFound.Product,
+		"userAgent": notFound.UserAgent,
+		"version":   notFound.Version,
+		"fileName":  notFound.FileName,
+	})
+
+	s.write(ctx, e, "file not found")
+}
+
+func (s *sink) PostFileBlocked(ctx context.Context, blocked FileBlocked) {
+	fields := map[string]interface{}{
+		"product":   blocked.Product,
+		"userAgent": blocked.UserAgent,
+		"version":   blocked.Version,
+		"fileName":  blocked.FileName,
+	}
+
+	if blocked.Replacement != "" {
+		fields["replacement"] = blocked.Replacement
+	}
+
+	if blocked.Alias != "" {
+		fields["alias"] = blocked.Alias
+	}
+
+	e := s.newEvent(fileBlockedEventType, fields)
+
+	s.write(ctx, e, "file blocked")
+}
+
+func (s *sink) write(ctx context.Context, e event, description string) {
+	if err := s.writer.Write(ctx, e); err != nil {
+		s.failures.Add(1)
+
+		log := middleware.LoggerFromContext(ctx)
+		log.WithError(err).Errorf("Failed to post %v event.", description)
+	}
+}
+
+func (s *sink) WriteFailures() uint64 {
+	return s.failures.Load()
+}
+
+func classifyVersion(version string) (versionClass, bool) {
+	parsed, err := semver.Parse(version)
+
+	if err != nil {
+		return "", false
+	}
+
+	if parsed.IsPrerelease() {
+		return versionClassPrerelease, true
+	}
+
+	return versionClassRelease, true
+}
+
+func (s *sink) newEvent(eventType string, fields map[string]interface{}) event {
+	e := event{
+		Type:      eventType,
+		ID:        s.uuidSource(),
+		Timestamp: s.timeSource(),
+		Fields:    fields,
+	}
+
+	e.Fields["eventId"] = e.ID
+	e.Fields["timestamp"] = e.Timestamp
+
+	return e
+}
@@ -0,0 +1,183 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errFilesystemEventSinkClosed = errors.New("event sink has been closed")
+
+// FilesystemEventSink writes events to files on the local filesystem. It must be closed once no more events will be posted.
+type FilesystemEventSink struct {
+	*sink
+	writer *filesystemEventWriter
+}
+
+type filesystemEventWriter struct {
+	directory   string
+	maxFileSize int64
+
+	lock         sync.Mutex
+	currentFiles map[string]*eventFile
+	closed       bool
+}
+
+type eventFile struct {
+	file *os.File
+	day  string
+	size int64
+}
+
+// NewFilesystemEventSink returns a sink that appends events as newline-delimited JSON to files in directory.
+//
+// Events are written to a separate set of files for each type of event, using the same layout as the Cloud Storage sink
+// (eg. 'v1/latest/2021/03/01/<ID of first event in file>.ndjson'). A new file is started each day, and whenever the current
+// file would grow beyond maxFileSize bytes.
+func NewFilesystemEventSink(directory string, maxFileSize int64) *FilesystemEventSink {
+	return NewFilesystemEventSinkWithSpecificDependencies(directory, maxFileSize, defaultTimeSource, uuid.New)
+}
+
+func NewFilesystemEventSinkWithSpecificDependencies(
+	directory string,
+	maxFileSize int64,
+	timeSource func() time.Time,
+	uuidSource func() uuid.UUID,
+) *FilesystemEventSink {
+	writer := &filesystemEventWriter{
+		directory:    directory,
+		maxFileSize:  maxFileSize,
+		currentFiles: map[string]*eventFile{},
+	}
+
+	return &FilesystemEventSink{
+		sink:   newSink(writer, timeSource, uuidSource),
+		writer: writer,
+	}
+}
+
+// Close flushes the files currently being written to disk and closes them. Events posted after Close is called are not written.
+func (f *FilesystemEventSink) Close() error {
+	f.writer.lock.Lock()
+	defer f.writer.lock.Unlock()
+
+	f.writer.closed = true
+	failed := []string{}
+
+	for eventType, current := range f.writer.currentFiles {
+		if err := current.close(); err != nil {
+			failed = append(failed, fmt.Sprintf("%v (%v)", current.file.Name(), err))
+		}
+
+		delete(f.writer.currentFiles, eventType)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not close event files: %v", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+func (f *filesystemEventWriter) Write(_ context.Context, e event) error {
+	bytes, err := json.Marshal(e.Fields)
+
+	if err != nil {
+		return fmt.Errorf("converting event to JSON failed: %w", err)
+	}
+
+	bytes = append(bytes, '\n')
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return errFilesystemEventSinkClosed
+	}
+
+	current, err := f.fileFor(e, int64(len(bytes)))
+
+	if err != nil {
+		return err
+	}
+
+	written, err := current.file.Write(bytes)
+	current.size += int64(written)
+
+	if err != nil {
+		return fmt.Errorf("writing event to file failed: %w", err)
+	}
+
+	return nil
+}
+
+func (f *filesystemEventWriter) fileFor(e event, bytesToWrite int64) (*eventFile, error) {
+	day := e.Timestamp.Format("2006/01/02")
+	current, haveCurrent := f.currentFiles[e.Type]
+
+	if haveCurrent && current.day == day && (current.size == 0 || current.size+bytesToWrite <= f.maxFileSize) {
+		return current, nil
+	}
+
+	if haveCurrent {
+		delete(f.currentFiles, e.Type)
+
+		if err := current.close(); err != nil {
+			return nil, fmt.Errorf("closing previous event file failed: %w", err)
+		}
+	}
+
+	path := filepath.Join(f.directory, filepath.FromSlash(e.Type), filepath.FromSlash(day), e.ID.String()+".ndjson")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating event directory failed: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o644)
+
+	if err != nil {
+		return nil, fmt.Errorf("creating event file failed: %w", err)
+	}
+
+	current = &eventFile{file: file, day: day}
+	f.currentFiles[e.Type] = current
+
+	return current, nil
+}
+
+func (f *eventFile) close() error {
+	if err := f.file.Sync(); err != nil {
+		_ = f.file.Close()
+
+		return fmt.Errorf("syncing event file failed: %w", err)
+	}
+
+	return f.file.Close()
+}
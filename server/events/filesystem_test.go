@@ -0,0 +1,273 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package events_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/events"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("Posting events to the local filesystem", func() {
+	var directory string
+	var currentTime time.Time
+	var nextID int
+	var sink *events.FilesystemEventSink
+	var ctx context.Context
+	var hook *test.Hook
+
+	BeforeEach(func() {
+		directory = GinkgoT().TempDir()
+		currentTime = time.Date(2021, 3, 1, 9, 54, 40, 123456789, time.UTC)
+		nextID = 1
+
+		timeSource := func() time.Time { return currentTime }
+		uuidSource := func() uuid.UUID {
+			id := uuid.MustParse("11112222-3333-4444-5555-00000000000" + string(rune('0'+nextID)))
+			nextID++
+
+			return id
+		}
+
+		sink = events.NewFilesystemEventSinkWithSpecificDependencies(directory, 400, timeSource, uuidSource)
+		ctx, hook = testutils.ContextWithTestLogger(context.Background())
+	})
+
+	readFile := func(path string) string {
+		content, err := os.ReadFile(filepath.Join(directory, filepath.FromSlash(path)))
+		Expect(err).ToNot(HaveOccurred())
+
+		return string(content)
+	}
+
+	Context("posting a latest version check event", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "MyCoolThing/1.2.3", Channel: "stable", Version: "0.83.2"})
+		})
+
+		It("logs no messages", func() {
+			Expect(hook.Entries).To(BeEmpty())
+		})
+
+		It("writes the event as a line of JSON to a file named after the event at the expected path", func() {
+			Expect(readFile("v1/latest/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(Equal(
+				`{"channel":"stable","eventId":"11112222-3333-4444-5555-000000000001","product":"batect","timestamp":"2021-03-01T09:54:40.123456789Z","userAgent":"MyCoolThing/1.2.3","version":"0.83.2"}` + "\n",
+			))
+		})
+	})
+
+	Context("posting a latest version check event during a staged rollout", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{
+				Product:           "batect",
+				UserAgent:         "MyCoolThing/1.2.3",
+				Channel:           "stable",
+				Version:           "0.83.1",
+				RolloutDecision:   events.RolloutDecisionPrevious,
+				RolloutPercentage: 25,
+			})
+		})
+
+		It("includes the rollout decision in the event", func() {
+			Expect(readFile("v1/latest/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(Equal(
+				`{"channel":"stable","eventId":"11112222-3333-4444-5555-000000000001","product":"batect","rolloutDecision":"previous","rolloutPercentage":25,` +
+					`"timestamp":"2021-03-01T09:54:40.123456789Z","userAgent":"MyCoolThing/1.2.3","version":"0.83.1"}` + "\n",
+			))
+		})
+	})
+
+	Context("posting a file download event", func() {
+		BeforeEach(func() {
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", UserAgent: "MyCoolThing/1.2.3", Version: "4.5.6", FileName: "batect-7.8.9.jar", Origin: "github", ArtifactKind: "jar"})
+		})
+
+		It("logs no messages", func() {
+			Expect(hook.Entries).To(BeEmpty())
+		})
+
+		It("writes the event as a line of JSON to a file named after the event at the expected path", func() {
+			Expect(readFile("v1/files/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(Equal(
+				`{"artifactKind":"jar","eventId":"11112222-3333-4444-5555-000000000001","fileName":"batect-7.8.9.jar","origin":"github","product":"batect",` +
+					`"timestamp":"2021-03-01T09:54:40.123456789Z","userAgent":"MyCoolThing/1.2.3","version":"4.5.6","versionClass":"release"}` + "\n",
+			))
+		})
+	})
+
+	Context("posting a file download event for a platform-specific artifact", func() {
+		BeforeEach(func() {
+			sink.PostFileDownload(ctx, events.FileDownload{
+				Product:      "batect",
+				UserAgent:    "MyCoolThing/1.2.3",
+				Version:      "4.5.6",
+				FileName:     "batect.cmd",
+				Origin:       "github",
+				ArtifactKind: "wrapper-script",
+				Platform:     "windows",
+			})
+		})
+
+		It("includes the kind and platform of the artifact in the event", func() {
+			Expect(readFile("v1/files/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(And(
+				ContainSubstring(`"artifactKind":"wrapper-script"`),
+				ContainSubstring(`"platform":"windows"`),
+			))
+		})
+	})
+
+	Context("posting a file download event for a file requested through a version alias", func() {
+		BeforeEach(func() {
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", UserAgent: "MyCoolThing/1.2.3", Version: "0.83.2", FileName: "batect-0.83.2.jar", Origin: "github", Alias: "0.83"})
+		})
+
+		It("includes the alias in the event", func() {
+			Expect(readFile("v1/files/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(ContainSubstring(`"alias":"0.83"`))
+		})
+	})
+
+	Context("posting a file download event for a pre-release version", func() {
+		BeforeEach(func() {
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", UserAgent: "MyCoolThing/1.2.3", Version: "0.85.0-rc.1", FileName: "batect-0.85.0-rc.1.jar", Origin: "github"})
+		})
+
+		It("classifies the version as a pre-release in the event", func() {
+			Expect(readFile("v1/files/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(ContainSubstring(`"versionClass":"prerelease"`))
+		})
+	})
+
+	Context("posting a file blocked event", func() {
+		BeforeEach(func() {
+			sink.PostFileBlocked(ctx, events.FileBlocked{Product: "batect", UserAgent: "MyCoolThing/1.2.3", Version: "0.83.1", FileName: "batect-0.83.1.jar", Replacement: "0.83.2"})
+		})
+
+		It("writes the event as a line of JSON to a file named after the event at the expected path", func() {
+			Expect(readFile("v1/files-blocked/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(Equal(
+				`{"eventId":"11112222-3333-4444-5555-000000000001","fileName":"batect-0.83.1.jar","product":"batect","replacement":"0.83.2",` +
+					`"timestamp":"2021-03-01T09:54:40.123456789Z","userAgent":"MyCoolThing/1.2.3","version":"0.83.1"}` + "\n",
+			))
+		})
+	})
+
+	Context("posting a file not found event", func() {
+		BeforeEach(func() {
+			sink.PostFileNotFound(ctx, events.FileNotFound{Product: "batect", UserAgent: "MyCoolThing/1.2.3", Version: "999.0.0", FileName: "batect-999.0.0.jar"})
+		})
+
+		It("logs no messages", func() {
+			Expect(hook.Entries).To(BeEmpty())
+		})
+
+		It("writes the event as a line of JSON to a file named after the event at the expected path", func() {
+			Expect(readFile("v1/files-not-found/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(Equal(
+				`{"eventId":"11112222-3333-4444-5555-000000000001","fileName":"batect-999.0.0.jar","product":"batect",` +
+					`"timestamp":"2021-03-01T09:54:40.123456789Z","userAgent":"MyCoolThing/1.2.3","version":"999.0.0"}` + "\n",
+			))
+		})
+	})
+
+	Context("posting multiple events that fit within the maximum file size", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "First/1.0.0", Channel: "stable", Version: "0.83.2"})
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "Second/1.0.0", Channel: "stable", Version: "0.83.2"})
+		})
+
+		It("appends all events to the same file", func() {
+			Expect(readFile("v1/latest/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(Equal(
+				`{"channel":"stable","eventId":"11112222-3333-4444-5555-000000000001","product":"batect","timestamp":"2021-03-01T09:54:40.123456789Z","userAgent":"First/1.0.0","version":"0.83.2"}` + "\n" +
+					`{"channel":"stable","eventId":"11112222-3333-4444-5555-000000000002","product":"batect","timestamp":"2021-03-01T09:54:40.123456789Z","userAgent":"Second/1.0.0","version":"0.83.2"}` + "\n",
+			))
+		})
+	})
+
+	Context("posting multiple events that do not fit within the maximum file size", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "First/1.0.0", Channel: "stable", Version: "0.83.2"})
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "Second/1.0.0", Channel: "stable", Version: "0.83.2"})
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "Third/1.0.0", Channel: "stable", Version: "0.83.2"})
+		})
+
+		It("starts a new file once the current file is full", func() {
+			Expect(readFile("v1/latest/2021/03/01/11112222-3333-4444-5555-000000000003.ndjson")).To(Equal(
+				`{"channel":"stable","eventId":"11112222-3333-4444-5555-000000000003","product":"batect","timestamp":"2021-03-01T09:54:40.123456789Z","userAgent":"Third/1.0.0","version":"0.83.2"}` + "\n",
+			))
+		})
+	})
+
+	Context("posting events on different days", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "First/1.0.0", Channel: "stable", Version: "0.83.2"})
+			currentTime = time.Date(2021, 3, 2, 0, 0, 1, 0, time.UTC)
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "Second/1.0.0", Channel: "stable", Version: "0.83.2"})
+		})
+
+		It("writes the events to separate files", func() {
+			Expect(readFile("v1/latest/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(ContainSubstring("First/1.0.0"))
+			Expect(readFile("v1/latest/2021/03/02/11112222-3333-4444-5555-000000000002.ndjson")).To(ContainSubstring("Second/1.0.0"))
+		})
+	})
+
+	Context("posting events of different types", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "First/1.0.0", Channel: "stable", Version: "0.83.2"})
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", UserAgent: "Second/1.0.0", Version: "4.5.6", FileName: "batect-4.5.6.jar"})
+		})
+
+		It("writes the events to separate files", func() {
+			Expect(readFile("v1/latest/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(ContainSubstring("First/1.0.0"))
+			Expect(readFile("v1/files/2021/03/01/11112222-3333-4444-5555-000000000002.ndjson")).To(ContainSubstring("Second/1.0.0"))
+		})
+	})
+
+	Context("closing the sink after posting events", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "First/1.0.0", Channel: "stable", Version: "0.83.2"})
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", UserAgent: "Second/1.0.0", Version: "4.5.6", FileName: "batect-4.5.6.jar"})
+
+			Expect(sink.Close()).To(Succeed())
+		})
+
+		It("leaves every event in its file", func() {
+			Expect(readFile("v1/latest/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).To(ContainSubstring("First/1.0.0"))
+			Expect(readFile("v1/files/2021/03/01/11112222-3333-4444-5555-000000000002.ndjson")).To(ContainSubstring("Second/1.0.0"))
+		})
+
+		Context("posting an event after the sink has been closed", func() {
+			BeforeEach(func() {
+				sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "Third/1.0.0", Channel: "stable", Version: "0.83.2"})
+			})
+
+			It("does not write the event", func() {
+				Expect(readFile("v1/latest/2021/03/01/11112222-3333-4444-5555-000000000001.ndjson")).ToNot(ContainSubstring("Third/1.0.0"))
+				Expect(filepath.Join(directory, "v1", "latest", "2021", "03", "01", "11112222-3333-4444-5555-000000000003.ndjson")).ToNot(BeAnExistingFile())
+			})
+
+			It("logs an error", func() {
+				Expect(hook.LastEntry().Message).To(Equal("Failed to post latest version check event."))
+			})
+		})
+	})
+})
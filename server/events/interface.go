@@ -27,6 +27,65 @@ import (
 // They are intended to be fire-and-forget, best-effort methods that should not cause a user-facing error
 // if they fail.
 type EventSink interface {
-	PostLatestVersionCheck(ctx context.Context, userAgent string)
-	PostFileDownload(ctx context.Context, userAgent string, version string, fileName string)
+	PostLatestVersionCheck(ctx context.Context, check LatestVersionCheck)
+	PostFileDownload(ctx context.Context, download FileDownload)
+	PostFileNotFound(ctx context.Context, notFound FileNotFound)
+	PostFileBlocked(ctx context.Context, blocked FileBlocked)
+}
+
+type LatestVersionCheck struct {
+	Product   string
+	UserAgent string
+	Channel   string
+	Version   string
+
+	// RolloutDecision is empty if there is no rollout in progress.
+	RolloutDecision   RolloutDecision
+	RolloutPercentage int
+}
+
+type RolloutDecision string
+
+const (
+	RolloutDecisionNew      RolloutDecision = "new"
+	RolloutDecisionPrevious RolloutDecision = "previous"
+)
+
+type FileDownload struct {
+	Product   string
+	UserAgent string
+	Version   string
+	FileName  string
+
+	// Origin is "cache" if the file was served from the artifact cache.
+	Origin string
+
+	// Platform is empty if the file is not specific to any platform.
+	ArtifactKind string
+	Platform     string
+
+	// Alias is empty if the client requested Version directly.
+	Alias string
+}
+
+// FileNotFound is kept separate from FileDownload so that requests for versions that have never been released don't skew download statistics.
+type FileNotFound struct {
+	Product   string
+	UserAgent string
+	Version   string
+	FileName  string
+}
+
+// FileBlocked is kept separate from FileDownload, as the client wasn't given the file it asked for.
+type FileBlocked struct {
+	Product   string
+	UserAgent string
+	Version   string
+	FileName  string
+
+	// Replacement is empty if the version has no replacement.
+	Replacement string
+
+	// Alias is empty if the client requested Version directly.
+	Alias string
 }
@@ -0,0 +1,87 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// InMemoryEventSink holds the most recently posted events in memory, and is intended for local development and testing.
+type InMemoryEventSink struct {
+	*sink
+	writer *inMemoryEventWriter
+}
+
+type inMemoryEventWriter struct {
+	capacity int
+
+	lock   sync.Mutex
+	events []map[string]interface{}
+}
+
+// NewInMemoryEventSink returns a sink that retains up to capacity of the most recently posted events.
+func NewInMemoryEventSink(capacity int) *InMemoryEventSink {
+	writer := &inMemoryEventWriter{
+		capacity: capacity,
+		events:   make([]map[string]interface{}, 0, capacity),
+	}
+
+	return &InMemoryEventSink{
+		sink:   newSink(writer, defaultTimeSource, uuid.New),
+		writer: writer,
+	}
+}
+
+// Events returns the retained events, oldest first.
+func (m *InMemoryEventSink) Events() []map[string]interface{} {
+	m.writer.lock.Lock()
+	defer m.writer.lock.Unlock()
+
+	events := make([]map[string]interface{}, len(m.writer.events))
+	copy(events, m.writer.events)
+
+	return events
+}
+
+func (w *inMemoryEventWriter) Write(_ context.Context, e event) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.capacity <= 0 {
+		return nil
+	}
+
+	if len(w.events) == w.capacity {
+		w.events = w.events[1:]
+	}
+
+	fields := map[string]interface{}{"type": e.Type}
+
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+
+	w.events = append(w.events, fields)
+
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package events_test
+
+import (
+	"context"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/events"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Posting events to memory", func() {
+	var sink *events.InMemoryEventSink
+	var ctx context.Context
+
+	BeforeEach(func() {
+		sink = events.NewInMemoryEventSink(2)
+		ctx, _ = testutils.ContextWithTestLogger(context.Background())
+	})
+
+	Context("posting fewer events than the capacity of the sink", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "First/1.0.0", Channel: "stable", Version: "0.83.2"})
+			sink.PostFileDownload(ctx, events.FileDownload{Product: "batect", UserAgent: "Second/1.0.0", Version: "4.5.6", FileName: "batect-4.5.6.jar", Origin: "github", ArtifactKind: "jar"})
+		})
+
+		It("retains all events, in the order they were posted", func() {
+			posted := sink.Events()
+
+			Expect(posted).To(HaveLen(2))
+			Expect(posted[0]).To(HaveKeyWithValue("type", "v1/latest"))
+			Expect(posted[0]).To(HaveKeyWithValue("userAgent", "First/1.0.0"))
+			Expect(posted[0]).To(HaveKeyWithValue("channel", "stable"))
+			Expect(posted[0]).To(HaveKeyWithValue("product", "batect"))
+			Expect(posted[1]).To(HaveKeyWithValue("type", "v1/files"))
+			Expect(posted[1]).To(HaveKeyWithValue("userAgent", "Second/1.0.0"))
+			Expect(posted[1]).To(HaveKeyWithValue("version", "4.5.6"))
+			Expect(posted[1]).To(HaveKeyWithValue("fileName", "batect-4.5.6.jar"))
+			Expect(posted[1]).To(HaveKeyWithValue("origin", "github"))
+			Expect(posted[1]).To(HaveKeyWithValue("artifactKind", "jar"))
+			Expect(posted[1]).To(HaveKeyWithValue("versionClass", "release"))
+		})
+	})
+
+	Context("posting more events than the capacity of the sink", func() {
+		BeforeEach(func() {
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "First/1.0.0", Channel: "stable", Version: "0.83.2"})
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "Second/1.0.0", Channel: "stable", Version: "0.83.2"})
+			sink.PostLatestVersionCheck(ctx, events.LatestVersionCheck{Product: "batect", UserAgent: "Third/1.0.0", Channel: "stable", Version: "0.83.2"})
+		})
+
+		It("retains only the most recent events", func() {
+			posted := sink.Events()
+
+			Expect(posted).To(HaveLen(2))
+			Expect(posted[0]).To(HaveKeyWithValue("userAgent", "Second/1.0.0"))
+			Expect(posted[1]).To(HaveKeyWithValue("userAgent", "Third/1.0.0"))
+		})
+	})
+})
@@ -0,0 +1,139 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Client interface {
+	// GetReleases includes drafts and pre-releases.
+	GetReleases(ctx context.Context) ([]Release, error)
+}
+
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	HTMLURL     string    `json:"html_url"`
+	Body        string    `json:"body"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []Asset   `json:"assets"`
+}
+
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+type client struct {
+	apiURL     string
+	repository string
+	token      string
+	httpClient *http.Client
+}
+
+// token is optional, but unauthenticated requests have a much lower rate limit.
+func NewClient(apiURL string, repository string, token string, httpClient *http.Client) Client {
+	return &client{
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		repository: repository,
+		token:      token,
+		httpClient: httpClient,
+	}
+}
+
+// The API returns at most 100 releases per page, so we follow the Link header to get the rest.
+const releasesPerPage = 100
+
+func (c *client) GetReleases(ctx context.Context) ([]Release, error) {
+	releases := []Release{}
+	url := fmt.Sprintf("%v/repos/%v/releases?per_page=%v", c.apiURL, c.repository, releasesPerPage)
+
+	for url != "" {
+		page, nextURL, err := c.getReleasesPage(ctx, url)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not get releases for %v: %w", c.repository, err)
+		}
+
+		releases = append(releases, page...)
+		url = nextURL
+	}
+
+	return releases, nil
+}
+
+func (c *client) getReleasesPage(ctx context.Context, url string) ([]Release, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("request failed: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return nil, "", fmt.Errorf("request to %v failed with HTTP %v: %v", url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var releases []Release
+
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, "", fmt.Errorf("could not decode response from %v: %w", url, err)
+	}
+
+	return releases, nextPageURL(resp.Header.Get("Link")), nil
+}
+
+//nolint:gochecknoglobals
+var nextLinkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+func nextPageURL(linkHeader string) string {
+	for _, link := range strings.Split(linkHeader, ",") {
+		if match := nextLinkRegex.FindStringSubmatch(link); match != nil {
+			return match[1]
+		}
+	}
+
+	return ""
+}
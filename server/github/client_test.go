@@ -0,0 +1,101 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package github_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/batect/updates.batect.dev/server/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Getting releases from GitHub", func() {
+	Context("given the releases span multiple pages", func() {
+		var server *fakeGitHubServer
+		var releases []github.Release
+		var err error
+
+		BeforeEach(func() {
+			server = newFakeGitHubServer("releases_page_1.json", "releases_page_2.json")
+			DeferCleanup(server.Close)
+
+			client := github.NewClient(server.URL, "batect/batect", "", server.Client())
+			releases, err = client.GetReleases(context.Background())
+		})
+
+		It("does not return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the releases from every page, in the order returned by the API", func() {
+			tags := []string{}
+
+			for _, release := range releases {
+				tags = append(tags, release.TagName)
+			}
+
+			Expect(tags).To(Equal([]string{"0.85.0", "0.84.0-rc.1", "0.83.3", "0.83.2", "tooling-2021-02", "0.83.1"}))
+		})
+
+		It("returns the details of each release", func() {
+			Expect(releases[1].HTMLURL).To(Equal("https://github.com/batect/batect/releases/tag/0.84.0-rc.1"))
+			Expect(releases[1].Body).To(Equal("Release candidate for 0.84.0."))
+			Expect(releases[1].Prerelease).To(BeTrue())
+			Expect(releases[1].Draft).To(BeFalse())
+			Expect(releases[1].Assets).To(Equal([]github.Asset{
+				{Name: "batect", BrowserDownloadURL: "https://github.com/batect/batect/releases/download/0.84.0-rc.1/batect"},
+				{Name: "batect.cmd", BrowserDownloadURL: "https://github.com/batect/batect/releases/download/0.84.0-rc.1/batect.cmd"},
+			}))
+			Expect(releases[0].Draft).To(BeTrue())
+		})
+
+		It("does not send any credentials", func() {
+			Expect(server.authorizationHeaders).To(Equal([]string{"", ""}))
+		})
+	})
+
+	Context("given a token", func() {
+		It("sends the token with every request", func() {
+			server := newFakeGitHubServer("releases_page_1.json", "releases_page_2.json")
+			DeferCleanup(server.Close)
+
+			client := github.NewClient(server.URL+"/", "batect/batect", "the-token", server.Client())
+			_, err := client.GetReleases(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(server.authorizationHeaders).To(Equal([]string{"Bearer the-token", "Bearer the-token"}))
+		})
+	})
+
+	Context("given the API returns an error", func() {
+		It("returns an error that includes the response", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				http.Error(w, `{"message": "API rate limit exceeded"}`, http.StatusForbidden)
+			}))
+			DeferCleanup(server.Close)
+
+			client := github.NewClient(server.URL, "batect/batect", "", server.Client())
+			_, err := client.GetReleases(context.Background())
+			Expect(err).To(MatchError(`could not get releases for batect/batect: request to ` + server.URL + `/repos/batect/batect/releases?per_page=100 failed with HTTP 403: {"message": "API rate limit exceeded"}`))
+		})
+	})
+})
@@ -0,0 +1,80 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package github_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+)
+
+// fakeGitHubServer serves recorded responses from the GitHub Releases API for the batect/batect repository.
+type fakeGitHubServer struct {
+	*httptest.Server
+
+	// pages are the names of the files in testdata to serve, in order.
+	pages []string
+
+	authorizationHeaders []string
+}
+
+func newFakeGitHubServer(pages ...string) *fakeGitHubServer {
+	s := &fakeGitHubServer{pages: pages}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveReleases))
+
+	return s
+}
+
+func (s *fakeGitHubServer) serveReleases(w http.ResponseWriter, req *http.Request) {
+	s.authorizationHeaders = append(s.authorizationHeaders, req.Header.Get("Authorization"))
+
+	if req.URL.Path != "/repos/batect/batect/releases" || req.URL.Query().Get("per_page") != "100" {
+		http.NotFound(w, req)
+
+		return
+	}
+
+	page := 1
+
+	if pageParam := req.URL.Query().Get("page"); pageParam != "" {
+		if _, err := fmt.Sscan(pageParam, &page); err != nil || page < 1 || page > len(s.pages) {
+			http.NotFound(w, req)
+
+			return
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join("testdata", s.pages[page-1]))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if page < len(s.pages) {
+		w.Header().Set("Link", fmt.Sprintf(`<%v/repos/batect/batect/releases?per_page=100&page=%v>; rel="next", <%v/repos/batect/batect/releases?per_page=100&page=%v>; rel="last"`, s.URL, page+1, s.URL, len(s.pages)))
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(content)
+}
@@ -0,0 +1,374 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/batect/services-common/middleware"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+)
+
+type ChannelRule struct {
+	Channel            string
+	IncludePrereleases bool
+}
+
+type Syncer interface {
+	Sync(ctx context.Context) error
+}
+
+type syncer struct {
+	client      Client
+	store       storage.LatestVersionStore
+	generations storage.LatestVersionGenerationStore
+	publisher   storage.LatestVersionPublisher
+	history     storage.DescriptorHistoryStore
+	audit       storage.AuditTrail
+	releases    storage.ReleaseHistoryUpdater
+	rules       []ChannelRule
+}
+
+// store must not be cached, so that the generation used in the conditional write is current.
+//
+// Releases whose descriptor is in history are never re-published, so that a channel that has been rolled back isn't rolled forward again.
+func NewSyncer(
+	client Client,
+	store storage.LatestVersionStore,
+	generations storage.LatestVersionGenerationStore,
+	publisher storage.LatestVersionPublisher,
+	history storage.DescriptorHistoryStore,
+	audit storage.AuditTrail,
+	releases storage.ReleaseHistoryUpdater,
+	rules []ChannelRule,
+) Syncer {
+	return &syncer{
+		client:      client,
+		store:       store,
+		generations: generations,
+		publisher:   publisher,
+		history:     history,
+		audit:       audit,
+		releases:    releases,
+		rules:       rules,
+	}
+}
+
+const syncActor = "github-sync"
+
+// A release missing any of these (eg. because its assets are still being uploaded) is not eligible.
+var scriptAssetNames = []string{"batect", "batect.cmd"} //nolint:gochecknoglobals
+
+var errNoEligibleRelease = errors.New("no eligible release")
+
+func (s *syncer) Sync(ctx context.Context) error {
+	releases, err := s.client.GetReleases(ctx)
+
+	if err != nil {
+		return fmt.Errorf("could not sync latest version descriptors: %w", err)
+	}
+
+	// The release history is updated first, so that it includes any version that is about to become the latest version.
+	if err := s.syncReleaseHistory(ctx, releases); err != nil {
+		return fmt.Errorf("could not sync release history: %w", err)
+	}
+
+	failedChannels := []string{}
+
+	for _, rule := range s.rules {
+		if err := s.syncChannel(ctx, releases, rule); err != nil {
+			middleware.LoggerFromContext(ctx).WithError(err).WithField("channel", rule.Channel).Error("Syncing latest version descriptor failed.")
+			failedChannels = append(failedChannels, rule.Channel)
+		}
+	}
+
+	if len(failedChannels) > 0 {
+		return fmt.Errorf("could not sync latest version descriptors for channels: %v", strings.Join(failedChannels, ", "))
+	}
+
+	return nil
+}
+
+func (s *syncer) syncReleaseHistory(ctx context.Context, releases []Release) error {
+	updated, err := s.releases.UpdateReleaseHistory(ctx, func(current []storage.Release) []storage.Release {
+		return releaseHistory(releases, current)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if updated {
+		middleware.LoggerFromContext(ctx).Info("Updated release history.")
+	}
+
+	return nil
+}
+
+func (s *syncer) syncChannel(ctx context.Context, releases []Release, rule ChannelRule) error {
+	info, err := latestVersionInfo(releases, rule)
+
+	if errors.Is(err, errNoEligibleRelease) {
+		middleware.LoggerFromContext(ctx).WithField("channel", rule.Channel).Warn("No eligible release found for channel, leaving latest version descriptor unchanged.")
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	current, err := s.store.GetLatestVersionDescriptor(ctx, rule.Channel)
+	currentIsValid := err == nil
+
+	if errors.Is(err, storage.ErrInvalidVersionDescriptor) {
+		middleware.LoggerFromContext(ctx).WithError(err).WithField("channel", rule.Channel).Warn("Current latest version descriptor is invalid, replacing it.")
+
+		current, err = s.currentGeneration(ctx, rule.Channel)
+	}
+
+	// Staged rollouts and support policies are managed through the admin API, so keep them rather than replacing them.
+	if currentIsValid {
+		info.Support = current.Info.Support
+
+		if current.Info.Version.Equal(info.Version) {
+			info.Rollout = current.Info.Rollout
+		}
+	}
+
+	var precondition storage.Precondition
+
+	switch {
+	case errors.Is(err, storage.ErrObjectNotFound):
+		precondition = storage.Precondition{DoesNotExist: true}
+	case err != nil:
+		return err
+	case currentIsValid && reflect.DeepEqual(current.Info, info):
+		return nil
+	default:
+		precondition = storage.PreconditionForGeneration(current.Generation)
+	}
+
+	if replaced, err := s.wasReplaced(ctx, rule.Channel, info); err != nil || replaced {
+		return err
+	}
+
+	published, err := s.publisher.PublishLatestVersionDescriptor(ctx, rule.Channel, info, precondition)
+
+	if errors.Is(err, storage.ErrPreconditionFailed) {
+		middleware.LoggerFromContext(ctx).WithError(err).WithField("channel", rule.Channel).Warn("Latest version descriptor changed while syncing, will retry on next sync.")
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	middleware.LoggerFromContext(ctx).
+		WithField("channel", rule.Channel).
+		WithField("version", published.Info.Version.String()).
+		Info("Published new latest version descriptor.")
+
+	return s.recordReplacement(ctx, rule.Channel, published, current, currentIsValid)
+}
+
+func (s *syncer) currentGeneration(ctx context.Context, channel string) (storage.VersionDescriptor, error) {
+	generation, err := s.generations.GetLatestVersionDescriptorGeneration(ctx, channel)
+
+	if err != nil {
+		return storage.VersionDescriptor{}, err
+	}
+
+	return storage.VersionDescriptor{Generation: generation}, nil
+}
+
+func (s *syncer) wasReplaced(ctx context.Context, channel string, info storage.VersionInfo) (bool, error) {
+	history, err := s.history.GetDescriptorHistory(ctx, channel)
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range history {
+		if entry.Info.Version.Equal(info.Version) {
+			middleware.LoggerFromContext(ctx).
+				WithField("channel", channel).
+				WithField("version", info.Version.String()).
+				Info("Latest eligible release was previously replaced, so it will not be re-published automatically.")
+
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *syncer) recordReplacement(ctx context.Context, channel string, published storage.VersionDescriptor, replaced storage.VersionDescriptor, replacedIsValid bool) error {
+	action := storage.AdminAction{
+		Actor:              syncActor,
+		Action:             storage.AdminActionPublish,
+		Channel:            channel,
+		Version:            published.Info.Version.String(),
+		Generation:         published.Generation,
+		PreviousGeneration: replaced.Generation,
+	}
+
+	if replacedIsValid {
+		action.PreviousVersion = replaced.Info.Version.String()
+
+		entry := storage.HistoricalDescriptor{Info: replaced.Info, Generation: replaced.Generation, ReplacedAt: time.Now().UTC(), ReplacedBy: syncActor}
+
+		if err := s.history.AddToDescriptorHistory(ctx, channel, entry); err != nil {
+			return fmt.Errorf("published %v, but could not add replaced descriptor to history: %w", published.Info.Version, err)
+		}
+	}
+
+	if err := s.audit.RecordAdminAction(ctx, action); err != nil {
+		return fmt.Errorf("published %v, but could not record it in the audit trail: %w", published.Info.Version, err)
+	}
+
+	return nil
+}
+
+func latestVersionInfo(releases []Release, rule ChannelRule) (storage.VersionInfo, error) {
+	var latest *storage.VersionInfo
+
+	for _, release := range releases {
+		info, ok := versionInfoForRelease(release, rule)
+
+		if ok && (latest == nil || latest.Version.LessThan(info.Version)) {
+			latest = &info
+		}
+	}
+
+	if latest == nil {
+		return storage.VersionInfo{}, errNoEligibleRelease
+	}
+
+	if err := latest.Validate(); err != nil {
+		return storage.VersionInfo{}, fmt.Errorf("release %v produces an invalid version descriptor: %w", latest.Version, err)
+	}
+
+	return *latest, nil
+}
+
+func versionInfoForRelease(release Release, rule ChannelRule) (storage.VersionInfo, bool) {
+	if release.Draft {
+		return storage.VersionInfo{}, false
+	}
+
+	// Tags that aren't versions (eg. for tooling releases) are never eligible.
+	version, err := semver.Parse(strings.TrimPrefix(release.TagName, "v"))
+
+	if err != nil {
+		return storage.VersionInfo{}, false
+	}
+
+	if (release.Prerelease || version.IsPrerelease()) && !rule.IncludePrereleases {
+		return storage.VersionInfo{}, false
+	}
+
+	files, ok := scriptFiles(release.Assets)
+
+	if !ok {
+		return storage.VersionInfo{}, false
+	}
+
+	return storage.VersionInfo{
+		Version: version,
+		URL:     release.HTMLURL,
+		Files:   files,
+		Notes:   release.Body,
+	}, true
+}
+
+// The status of releases already in current is kept, as it is managed by hand (eg. to deprecate a release).
+func releaseHistory(releases []Release, current []storage.Release) []storage.Release {
+	history := current
+
+	for _, release := range releases {
+		info, ok := versionInfoForRelease(release, ChannelRule{IncludePrereleases: true})
+
+		if !ok {
+			continue
+		}
+
+		entry := storage.Release{
+			Version:     info.Version,
+			ReleaseDate: release.PublishedAt.UTC(),
+			URL:         release.HTMLURL,
+			Status:      storage.ReleaseStatusCurrent,
+			Notes:       release.Body,
+		}
+
+		if i, found := findRelease(history, info.Version); found {
+			entry.Status = history[i].Status
+			history[i] = entry
+		} else {
+			history = append(history, entry)
+		}
+	}
+
+	return history
+}
+
+// Versions that differ only in build metadata are separate releases.
+func findRelease(releases []storage.Release, version semver.Version) (int, bool) {
+	for i, release := range releases {
+		if release.Version.String() == version.String() {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func scriptFiles(assets []Asset) ([]storage.VersionFile, bool) {
+	files := make([]storage.VersionFile, 0, len(scriptAssetNames))
+
+	for _, name := range scriptAssetNames {
+		asset, ok := findAsset(assets, name)
+
+		if !ok {
+			return nil, false
+		}
+
+		files = append(files, storage.VersionFile{Type: "script", Name: asset.Name, URL: asset.BrowserDownloadURL})
+	}
+
+	return files, true
+}
+
+func findAsset(assets []Asset, name string) (Asset, bool) {
+	for _, asset := range assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+
+	return Asset{}, false
+}
@@ -0,0 +1,363 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package github_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/github"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("Syncing latest version descriptors from GitHub releases", func() {
+	var ctx context.Context
+	var hook *test.Hook
+	var server *fakeGitHubServer
+	var objects storage.ObjectStore
+	var store storage.LatestVersionStore
+	var generations storage.LatestVersionGenerationStore
+	var publisher storage.LatestVersionPublisher
+	var history storage.DescriptorHistoryStore
+	var audit storage.AuditTrail
+	var releases storage.ReleaseHistoryUpdater
+	var client github.Client
+
+	rules := []github.ChannelRule{
+		{Channel: storage.StableChannel, IncludePrereleases: false},
+		{Channel: "beta", IncludePrereleases: true},
+	}
+
+	stableInfo := storage.VersionInfo{
+		Version: semver.MustParse("0.83.2"),
+		URL:     "https://github.com/batect/batect/releases/tag/0.83.2",
+		Files: []storage.VersionFile{
+			{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.83.2/batect"},
+			{Type: "script", Name: "batect.cmd", URL: "https://github.com/batect/batect/releases/download/0.83.2/batect.cmd"},
+		},
+		Notes: "Fixes a bug in the previous release.",
+	}
+
+	betaInfo := storage.VersionInfo{
+		Version: semver.MustParse("0.84.0-rc.1"),
+		URL:     "https://github.com/batect/batect/releases/tag/0.84.0-rc.1",
+		Files: []storage.VersionFile{
+			{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.84.0-rc.1/batect"},
+			{Type: "script", Name: "batect.cmd", URL: "https://github.com/batect/batect/releases/download/0.84.0-rc.1/batect.cmd"},
+		},
+		Notes: "Release candidate for 0.84.0.",
+	}
+
+	BeforeEach(func() {
+		ctx, hook = testutils.ContextWithTestLogger(context.Background())
+		server = newFakeGitHubServer("releases_page_1.json", "releases_page_2.json")
+		DeferCleanup(server.Close)
+
+		objects = storage.NewInMemoryObjectStore(nil)
+		store = storage.NewLatestVersionStore(objects)
+		generations = storage.NewLatestVersionGenerationStore(objects)
+		publisher = storage.NewLatestVersionPublisher(objects)
+		history = storage.NewDescriptorHistoryStore(objects)
+		audit = storage.NewAuditTrail(objects)
+		releases = storage.NewReleaseHistoryUpdater(objects)
+		client = github.NewClient(server.URL, "batect/batect", "", server.Client())
+	})
+
+	Context("given no descriptors have been published yet", func() {
+		var err error
+
+		BeforeEach(func() {
+			err = github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules).Sync(ctx)
+		})
+
+		It("does not return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("publishes the highest non-draft, non-prerelease version with all of its files to the stable channel", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, storage.StableChannel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Info).To(Equal(stableInfo))
+		})
+
+		It("publishes the highest non-draft version, including pre-releases, to channels that include pre-releases", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, "beta")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Info).To(Equal(betaInfo))
+		})
+	})
+
+	Context("given no release history has been published yet", func() {
+		BeforeEach(func() {
+			Expect(github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules).Sync(ctx)).To(Succeed())
+		})
+
+		It("publishes every non-draft release with all of its files, including pre-releases", func() {
+			Expect(storage.NewReleaseHistoryStore(objects).GetReleases(ctx)).To(Equal([]storage.Release{
+				{
+					Version:     semver.MustParse("0.84.0-rc.1"),
+					ReleaseDate: time.Date(2021, 3, 15, 10, 0, 0, 0, time.UTC),
+					URL:         "https://github.com/batect/batect/releases/tag/0.84.0-rc.1",
+					Status:      storage.ReleaseStatusCurrent,
+					Notes:       "Release candidate for 0.84.0.",
+				},
+				{
+					Version:     semver.MustParse("0.83.2"),
+					ReleaseDate: time.Date(2021, 3, 1, 9, 54, 40, 0, time.UTC),
+					URL:         "https://github.com/batect/batect/releases/tag/0.83.2",
+					Status:      storage.ReleaseStatusCurrent,
+					Notes:       "Fixes a bug in the previous release.",
+				},
+				{
+					Version:     semver.MustParse("0.83.1"),
+					ReleaseDate: time.Date(2021, 2, 15, 10, 0, 0, 0, time.UTC),
+					URL:         "https://github.com/batect/batect/releases/tag/0.83.1",
+					Status:      storage.ReleaseStatusCurrent,
+				},
+			}))
+		})
+	})
+
+	Context("given a release history has already been published", func() {
+		olderRelease := storage.Release{
+			Version:     semver.MustParse("0.1.0"),
+			ReleaseDate: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+			URL:         "https://github.com/batect/batect/releases/tag/0.1.0",
+			Status:      storage.ReleaseStatusDeprecated,
+		}
+
+		BeforeEach(func() {
+			_, err := releases.UpdateReleaseHistory(ctx, func([]storage.Release) []storage.Release {
+				return []storage.Release{
+					olderRelease,
+					{
+						Version:     semver.MustParse("0.83.1"),
+						ReleaseDate: time.Date(2021, 2, 15, 10, 0, 0, 0, time.UTC),
+						URL:         "https://github.com/batect/batect/releases/tag/0.83.1",
+						Status:      storage.ReleaseStatusDeprecated,
+					},
+				}
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules).Sync(ctx)).To(Succeed())
+		})
+
+		It("adds new releases, and keeps the status of existing releases and releases that are not on GitHub", func() {
+			published, err := storage.NewReleaseHistoryStore(objects).GetReleases(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(published).To(HaveLen(4))
+			Expect(published[0].Version).To(Equal(semver.MustParse("0.84.0-rc.1")))
+			Expect(published[2]).To(HaveField("Status", storage.ReleaseStatusDeprecated))
+			Expect(published[3]).To(Equal(olderRelease))
+		})
+	})
+
+	Context("given the current descriptor is already up to date", func() {
+		var existing storage.VersionDescriptor
+
+		BeforeEach(func() {
+			var err error
+			existing, err = publisher.PublishLatestVersionDescriptor(ctx, storage.StableChannel, stableInfo, storage.Precondition{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules).Sync(ctx)).To(Succeed())
+		})
+
+		It("does not replace the descriptor", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, storage.StableChannel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Generation).To(Equal(existing.Generation))
+		})
+	})
+
+	Context("given a staged rollout of the latest eligible release is in progress", func() {
+		BeforeEach(func() {
+			olderInfo := stableInfo
+			olderInfo.Version = semver.MustParse("0.83.1")
+			olderInfo.Notes = ""
+
+			withRollout := stableInfo
+			withRollout.Notes = "Notes that have since been changed on GitHub."
+			withRollout.Rollout = &storage.Rollout{Percentage: 10, Previous: olderInfo}
+
+			_, err := publisher.PublishLatestVersionDescriptor(ctx, storage.StableChannel, withRollout, storage.Precondition{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules).Sync(ctx)).To(Succeed())
+		})
+
+		It("updates the descriptor but retains the rollout", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, storage.StableChannel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Info.Notes).To(Equal(stableInfo.Notes))
+			Expect(descriptor.Info.Rollout).ToNot(BeNil())
+			Expect(descriptor.Info.Rollout.Percentage).To(Equal(10))
+		})
+	})
+
+	Context("given the current descriptor is for an older version", func() {
+		minimumVersion := semver.MustParse("0.80.0")
+
+		BeforeEach(func() {
+			olderInfo := stableInfo
+			olderInfo.Version = semver.MustParse("0.83.1")
+			olderInfo.Support = &storage.SupportPolicy{MinimumVersion: &minimumVersion}
+
+			_, err := publisher.PublishLatestVersionDescriptor(ctx, storage.StableChannel, olderInfo, storage.Precondition{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules).Sync(ctx)).To(Succeed())
+		})
+
+		It("replaces the descriptor, retaining the support policy", func() {
+			expected := stableInfo
+			expected.Support = &storage.SupportPolicy{MinimumVersion: &minimumVersion}
+
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, storage.StableChannel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Info).To(Equal(expected))
+		})
+
+		It("adds the replaced descriptor to the history", func() {
+			entries, err := history.GetDescriptorHistory(ctx, storage.StableChannel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Info.Version).To(Equal(semver.MustParse("0.83.1")))
+			Expect(entries[0].ReplacedBy).To(Equal("github-sync"))
+		})
+	})
+
+	Context("given the current descriptor is invalid", func() {
+		BeforeEach(func() {
+			_, err := objects.PutObject(ctx, "v1/latest.json", []byte(`{"version":"0.83.1"}`), "application/json", storage.Precondition{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules[:1]).Sync(ctx)).To(Succeed())
+		})
+
+		It("replaces the descriptor", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, storage.StableChannel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Info).To(Equal(stableInfo))
+		})
+
+		It("does not add the invalid descriptor to the history", func() {
+			Expect(history.GetDescriptorHistory(ctx, storage.StableChannel)).To(BeEmpty())
+		})
+	})
+
+	Context("given the latest eligible release was previously replaced, such as when a channel has been rolled back", func() {
+		var rolledBackTo storage.VersionDescriptor
+
+		BeforeEach(func() {
+			olderInfo := stableInfo
+			olderInfo.Version = semver.MustParse("0.83.1")
+
+			var err error
+			rolledBackTo, err = publisher.PublishLatestVersionDescriptor(ctx, storage.StableChannel, olderInfo, storage.Precondition{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history.AddToDescriptorHistory(ctx, storage.StableChannel, storage.HistoricalDescriptor{Info: stableInfo, Generation: 1})).To(Succeed())
+
+			Expect(github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules[:1]).Sync(ctx)).To(Succeed())
+		})
+
+		It("does not re-publish the release", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, storage.StableChannel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Generation).To(Equal(rolledBackTo.Generation))
+		})
+	})
+
+	Context("given the descriptor is changed by someone else during the sync", func() {
+		var err error
+
+		BeforeEach(func() {
+			outdated := &staleLatestVersionStore{descriptor: storage.VersionDescriptor{Generation: 999}}
+
+			_, err = publisher.PublishLatestVersionDescriptor(ctx, storage.StableChannel, betaInfo, storage.Precondition{})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = github.NewSyncer(client, outdated, generations, publisher, history, audit, releases, rules[:1]).Sync(ctx)
+		})
+
+		It("does not return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not overwrite the other change", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, storage.StableChannel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Info).To(Equal(betaInfo))
+		})
+
+		It("logs a warning", func() {
+			Expect(hook.LastEntry()).ToNot(BeNil())
+			Expect(hook.LastEntry().Level).To(Equal(logrus.WarnLevel))
+			Expect(hook.LastEntry().Message).To(Equal("Latest version descriptor changed while syncing, will retry on next sync."))
+			Expect(hook.LastEntry().Data).To(HaveKeyWithValue("channel", storage.StableChannel))
+		})
+	})
+
+	Context("given there are no eligible releases for a channel", func() {
+		var err error
+
+		BeforeEach(func() {
+			server = newFakeGitHubServer("releases_page_1.json")
+			DeferCleanup(server.Close)
+
+			client = github.NewClient(server.URL, "batect/batect", "", server.Client())
+			err = github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules[:1]).Sync(ctx)
+		})
+
+		It("does not return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not publish a descriptor", func() {
+			_, err := store.GetLatestVersionDescriptor(ctx, storage.StableChannel)
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+		})
+	})
+
+	Context("given the releases cannot be retrieved", func() {
+		It("returns an error", func() {
+			client := github.NewClient(server.URL, "batect/something-else", "", server.Client())
+			err := github.NewSyncer(client, store, generations, publisher, history, audit, releases, rules).Sync(ctx)
+
+			Expect(err).To(MatchError(ContainSubstring("could not sync latest version descriptors: could not get releases for batect/something-else")))
+		})
+	})
+})
+
+// staleLatestVersionStore always returns the same descriptor, simulating a descriptor that has changed since it was read.
+type staleLatestVersionStore struct {
+	descriptor storage.VersionDescriptor
+}
+
+func (s *staleLatestVersionStore) GetLatestVersionDescriptor(_ context.Context, _ string) (storage.VersionDescriptor, error) {
+	return s.descriptor, nil
+}
@@ -0,0 +1,299 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package semver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Range is a set of versions, expressed using the same syntax as npm (https://github.com/npm/node-semver#ranges).
+//
+// Comparators separated by whitespace must all be satisfied, and sets of comparators separated by '||' are alternatives.
+// The supported comparators are =, <, <=, >, >=, ~ (patch-level changes), ^ (changes that do not modify the left-most non-zero
+// component) and partial versions or wildcards such as '1.2', '1.2.x' or '*'.
+//
+// Unlike npm, pre-release versions are not treated specially: a pre-release version is in the range if it satisfies the comparators.
+type Range struct {
+	alternatives [][]comparator
+	original     string
+}
+
+type operator int
+
+const (
+	equal operator = iota
+	lessThan
+	lessThanOrEqual
+	greaterThan
+	greaterThanOrEqual
+)
+
+type comparator struct {
+	op      operator
+	version Version
+}
+
+var ErrInvalidRange = errors.New("not a valid version range")
+
+//nolint:gochecknoglobals
+var (
+	operatorSpacingPattern = regexp.MustCompile(`(<=|>=|<|>|=|~|\^)\s+`)
+	partialVersionPattern  = regexp.MustCompile(`^(<=|>=|<|>|=|~|\^)?v?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*])(-[0-9A-Za-z.-]+)?)?(\+[0-9A-Za-z.-]+)?$`)
+)
+
+func ParseRange(s string) (Range, error) {
+	r := Range{original: s}
+	normalised := operatorSpacingPattern.ReplaceAllString(strings.TrimSpace(s), "$1")
+
+	for _, set := range strings.Split(normalised, "||") {
+		comparators := []comparator{}
+
+		for _, field := range strings.Fields(set) {
+			expanded, err := expandComparator(field)
+
+			if err != nil {
+				return Range{}, fmt.Errorf("'%v' is %w: %v", s, ErrInvalidRange, err)
+			}
+
+			comparators = append(comparators, expanded...)
+		}
+
+		r.alternatives = append(r.alternatives, comparators)
+	}
+
+	return r, nil
+}
+
+func MustParseRange(s string) Range {
+	r, err := ParseRange(s)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+func (r Range) Contains(v Version) bool {
+	for _, comparators := range r.alternatives {
+		if allSatisfied(comparators, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Range) String() string {
+	return r.original
+}
+
+func (r Range) MarshalText() ([]byte, error) {
+	return []byte(r.original), nil
+}
+
+func (r *Range) UnmarshalText(text []byte) error {
+	parsed, err := ParseRange(string(text))
+
+	if err != nil {
+		return err
+	}
+
+	*r = parsed
+
+	return nil
+}
+
+func allSatisfied(comparators []comparator, v Version) bool {
+	for _, c := range comparators {
+		if !c.satisfiedBy(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c comparator) satisfiedBy(v Version) bool {
+	result := v.Compare(c.version)
+
+	switch c.op {
+	case equal:
+		return result == 0
+	case lessThan:
+		return result < 0
+	case lessThanOrEqual:
+		return result <= 0
+	case greaterThan:
+		return result > 0
+	case greaterThanOrEqual:
+		return result >= 0
+	default:
+		panic(fmt.Sprintf("unknown operator %v", c.op))
+	}
+}
+
+type partialVersion struct {
+	components []uint64 // Only the components that were specified (ie. not wildcards), from major to patch.
+	prerelease []string
+}
+
+func (p partialVersion) version() Version {
+	v := Version{Prerelease: p.prerelease}
+	components := []*uint64{&v.Major, &v.Minor, &v.Patch}
+
+	for i, c := range p.components {
+		*components[i] = c
+	}
+
+	return v
+}
+
+// bump returns the lowest version greater than all versions matching the first n components of p.
+func (p partialVersion) bump(n int) Version {
+	v := Version{Prerelease: []string{"0"}}
+	components := []*uint64{&v.Major, &v.Minor, &v.Patch}
+
+	for i := 0; i < n; i++ {
+		*components[i] = p.components[i]
+	}
+
+	*components[n-1]++
+
+	return v
+}
+
+func expandComparator(s string) ([]comparator, error) {
+	match := partialVersionPattern.FindStringSubmatch(s)
+
+	if match == nil {
+		return nil, fmt.Errorf("'%v' is not a valid comparator", s)
+	}
+
+	op := match[1]
+	p, err := parsePartialVersion(match[2:5], match[5])
+
+	if err != nil {
+		return nil, err
+	}
+
+	specified := len(p.components)
+
+	if specified == 0 {
+		if op == "<" || op == ">" {
+			// Nothing is less than or greater than every version.
+			return []comparator{{lessThan, Version{Prerelease: []string{"0"}}}}, nil
+		}
+
+		return []comparator{}, nil
+	}
+
+	switch op {
+	case "", "=":
+		if specified == 3 {
+			return []comparator{{equal, p.version()}}, nil
+		}
+
+		return []comparator{{greaterThanOrEqual, p.version()}, {lessThan, p.bump(specified)}}, nil
+	case "<":
+		if specified == 3 {
+			return []comparator{{lessThan, p.version()}}, nil
+		}
+
+		// Exclude pre-releases of the lower bound (eg. '<1.2' should not contain '1.2.0-rc.1').
+		lower := p.version()
+		lower.Prerelease = []string{"0"}
+
+		return []comparator{{lessThan, lower}}, nil
+	case "<=":
+		if specified == 3 {
+			return []comparator{{lessThanOrEqual, p.version()}}, nil
+		}
+
+		return []comparator{{lessThan, p.bump(specified)}}, nil
+	case ">":
+		if specified == 3 {
+			return []comparator{{greaterThan, p.version()}}, nil
+		}
+
+		return []comparator{{greaterThanOrEqual, p.bump(specified)}}, nil
+	case ">=":
+		return []comparator{{greaterThanOrEqual, p.version()}}, nil
+	case "~":
+		upper := p.bump(minInt(specified, 2))
+
+		return []comparator{{greaterThanOrEqual, p.version()}, {lessThan, upper}}, nil
+	case "^":
+		return []comparator{{greaterThanOrEqual, p.version()}, {lessThan, p.bump(caretBumpComponent(p))}}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator '%v'", op)
+	}
+}
+
+// caretBumpComponent returns the number of components that must not change for a version to satisfy a caret comparator.
+func caretBumpComponent(p partialVersion) int {
+	for i, c := range p.components {
+		if c != 0 || i == len(p.components)-1 {
+			return i + 1
+		}
+	}
+
+	return len(p.components)
+}
+
+func parsePartialVersion(components []string, prerelease string) (partialVersion, error) {
+	p := partialVersion{}
+
+	for _, c := range components {
+		if c == "" || c == "x" || c == "X" || c == "*" {
+			break
+		}
+
+		value, err := strconv.ParseUint(c, 10, 64)
+
+		if err != nil {
+			return partialVersion{}, fmt.Errorf("'%v' is not a valid version component: %w", c, err)
+		}
+
+		p.components = append(p.components, value)
+	}
+
+	if prerelease != "" {
+		if len(p.components) != 3 {
+			return partialVersion{}, errors.New("pre-release identifiers can only be used with a full version")
+		}
+
+		p.prerelease = strings.Split(strings.TrimPrefix(prerelease, "-"), ".")
+	}
+
+	return p, nil
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,120 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package semver_test
+
+import (
+	"encoding/json"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Semantic version ranges", func() {
+	DescribeTable("checking whether a range contains a version",
+		func(rangeString string, version string, expected bool) {
+			r, err := semver.ParseRange(rangeString)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.Contains(semver.MustParse(version))).To(Equal(expected))
+		},
+		Entry(nil, "", "1.2.3", true),
+		Entry(nil, "*", "1.2.3", true),
+		Entry(nil, "x", "0.0.0", true),
+		Entry(nil, "1.2.3", "1.2.3", true),
+		Entry(nil, "1.2.3", "1.2.4", false),
+		Entry(nil, "=1.2.3", "1.2.3", true),
+		Entry(nil, "v1.2.3", "1.2.3", true),
+		Entry(nil, "1.2.3", "1.2.3+build.1", true),
+		Entry(nil, "1.2", "1.2.0", true),
+		Entry(nil, "1.2", "1.2.99", true),
+		Entry(nil, "1.2", "1.3.0", false),
+		Entry(nil, "1.2", "1.3.0-rc.1", false),
+		Entry(nil, "1.2.x", "1.2.7", true),
+		Entry(nil, "1.x", "1.99.0", true),
+		Entry(nil, "1", "2.0.0", false),
+		Entry(nil, ">1.2.3", "1.2.3", false),
+		Entry(nil, ">1.2.3", "1.2.4", true),
+		Entry(nil, ">1.2", "1.2.9", false),
+		Entry(nil, ">1.2", "1.3.0", true),
+		Entry(nil, ">=1.2.3", "1.2.3", true),
+		Entry(nil, ">=1.2.3", "1.2.2", false),
+		Entry(nil, ">= 1.2.3", "1.2.3", true),
+		Entry(nil, "<1.2.3", "1.2.2", true),
+		Entry(nil, "<1.2.3", "1.2.3", false),
+		Entry(nil, "<1.2.3", "1.2.3-rc.1", true),
+		Entry(nil, "<1.2", "1.1.99", true),
+		Entry(nil, "<1.2", "1.2.0-rc.1", false),
+		Entry(nil, "<=1.2.3", "1.2.3", true),
+		Entry(nil, "<=1.2", "1.2.99", true),
+		Entry(nil, "<=1.2", "1.3.0", false),
+		Entry(nil, "~1.2.3", "1.2.9", true),
+		Entry(nil, "~1.2.3", "1.2.2", false),
+		Entry(nil, "~1.2.3", "1.3.0", false),
+		Entry(nil, "~1", "1.9.0", true),
+		Entry(nil, "~1", "2.0.0", false),
+		Entry(nil, "^1.2.3", "1.9.0", true),
+		Entry(nil, "^1.2.3", "2.0.0", false),
+		Entry(nil, "^1.2.3", "1.2.2", false),
+		Entry(nil, "^0.2.3", "0.2.9", true),
+		Entry(nil, "^0.2.3", "0.3.0", false),
+		Entry(nil, "^0.0.3", "0.0.3", true),
+		Entry(nil, "^0.0.3", "0.0.4", false),
+		Entry(nil, "^0.0", "0.0.9", true),
+		Entry(nil, "^0.0", "0.1.0", false),
+		Entry(nil, ">=0.78.0 <0.80.0", "0.79.1", true),
+		Entry(nil, ">=0.78.0 <0.80.0", "0.80.0", false),
+		Entry(nil, ">=0.78.0 <0.80.0", "0.77.0", false),
+		Entry(nil, "0.78.x || >=0.80.0", "0.78.5", true),
+		Entry(nil, "0.78.x || >=0.80.0", "0.79.0", false),
+		Entry(nil, "0.78.x || >=0.80.0", "0.81.0", true),
+		Entry(nil, "<x", "0.0.0", false),
+	)
+
+	DescribeTable("parsing invalid ranges",
+		func(rangeString string) {
+			_, err := semver.ParseRange(rangeString)
+			Expect(err).To(MatchError(semver.ErrInvalidRange))
+		},
+		Entry(nil, "blah"),
+		Entry(nil, "1.2.3.4"),
+		Entry(nil, "=>1.2.3"),
+		Entry(nil, "1.2-rc.1"),
+		Entry(nil, ">=1.2.3 <"),
+	)
+
+	It("returns the original range when formatted", func() {
+		Expect(semver.MustParseRange(">= 1.2.3 <2").String()).To(Equal(">= 1.2.3 <2"))
+	})
+
+	It("can be converted to and from JSON", func() {
+		bytes, err := json.Marshal(semver.MustParseRange(">= 1.2.3 <2"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bytes).To(MatchJSON(`">= 1.2.3 <2"`))
+
+		var r semver.Range
+		Expect(json.Unmarshal(bytes, &r)).To(Succeed())
+		Expect(r).To(Equal(semver.MustParseRange(">= 1.2.3 <2")))
+	})
+
+	It("returns an error when converting an invalid range from JSON", func() {
+		var r semver.Range
+		Expect(json.Unmarshal([]byte(`"=>1.2.3"`), &r)).To(MatchError(semver.ErrInvalidRange))
+	})
+})
@@ -0,0 +1,216 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package semver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a version number as defined by Semantic Versioning 2.0.0 (https://semver.org/spec/v2.0.0.html).
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      []string
+}
+
+var ErrInvalidVersion = errors.New("not a valid semantic version")
+
+//nolint:gochecknoglobals
+var versionPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+func Parse(s string) (Version, error) {
+	match := versionPattern.FindStringSubmatch(s)
+
+	if match == nil {
+		return Version{}, fmt.Errorf("'%v' is %w", s, ErrInvalidVersion)
+	}
+
+	v := Version{}
+	var err error
+
+	if v.Major, err = strconv.ParseUint(match[1], 10, 64); err != nil {
+		return Version{}, fmt.Errorf("'%v' is %w: %v", s, ErrInvalidVersion, err)
+	}
+
+	if v.Minor, err = strconv.ParseUint(match[2], 10, 64); err != nil {
+		return Version{}, fmt.Errorf("'%v' is %w: %v", s, ErrInvalidVersion, err)
+	}
+
+	if v.Patch, err = strconv.ParseUint(match[3], 10, 64); err != nil {
+		return Version{}, fmt.Errorf("'%v' is %w: %v", s, ErrInvalidVersion, err)
+	}
+
+	if match[4] != "" {
+		v.Prerelease = strings.Split(match[4], ".")
+
+		for _, identifier := range v.Prerelease {
+			if isNumeric(identifier) && len(identifier) > 1 && identifier[0] == '0' {
+				return Version{}, fmt.Errorf("'%v' is %w: numeric pre-release identifiers must not have leading zeroes", s, ErrInvalidVersion)
+			}
+		}
+	}
+
+	if match[5] != "" {
+		v.Build = strings.Split(match[5], ".")
+	}
+
+	return v, nil
+}
+
+func MustParse(s string) Version {
+	v, err := Parse(s)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+
+	return s
+}
+
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare returns a negative number if v has lower precedence than other, zero if they have the same precedence,
+// and a positive number if v has higher precedence than other.
+//
+// As per the specification, build metadata is ignored when determining precedence.
+func (v Version) Compare(other Version) int {
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+func (v Version) LessThan(other Version) bool {
+	return v.Compare(other) < 0
+}
+
+func (v Version) Equal(other Version) bool {
+	return v.Compare(other) == 0
+}
+
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *Version) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+
+	if err != nil {
+		return err
+	}
+
+	*v = parsed
+
+	return nil
+}
+
+func compareUint(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// A version without a pre-release has higher precedence than the same version with a pre-release, and pre-releases
+// are compared identifier by identifier, with numeric identifiers compared numerically and always having lower
+// precedence than alphanumeric identifiers.
+func comparePrerelease(a []string, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareUint(uint64(len(a)), uint64(len(b)))
+}
+
+func compareIdentifier(a string, b string) int {
+	aIsNumeric := isNumeric(a)
+	bIsNumeric := isNumeric(b)
+
+	switch {
+	case aIsNumeric && bIsNumeric:
+		if c := compareUint(uint64(len(a)), uint64(len(b))); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a, b)
+	case aIsNumeric:
+		return -1
+	case bIsNumeric:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func isNumeric(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return s != ""
+}
@@ -0,0 +1,129 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package semver_test
+
+import (
+	"encoding/json"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Semantic versions", func() {
+	DescribeTable("parsing valid versions",
+		func(input string, expected semver.Version) {
+			Expect(semver.Parse(input)).To(Equal(expected))
+		},
+		Entry("release version", "1.2.3", semver.Version{Major: 1, Minor: 2, Patch: 3}),
+		Entry("zero version", "0.0.0", semver.Version{}),
+		Entry("multi-digit components", "10.20.30", semver.Version{Major: 10, Minor: 20, Patch: 30}),
+		Entry("pre-release version", "0.85.0-rc.1", semver.Version{Minor: 85, Prerelease: []string{"rc", "1"}}),
+		Entry("pre-release version with hyphen", "1.0.0-alpha-beta", semver.Version{Major: 1, Prerelease: []string{"alpha-beta"}}),
+		Entry("build metadata", "1.0.0+20130313144700", semver.Version{Major: 1, Build: []string{"20130313144700"}}),
+		Entry("pre-release and build metadata", "1.0.0-beta+exp.sha.5114f85", semver.Version{Major: 1, Prerelease: []string{"beta"}, Build: []string{"exp", "sha", "5114f85"}}),
+	)
+
+	DescribeTable("parsing invalid versions",
+		func(input string) {
+			_, err := semver.Parse(input)
+			Expect(err).To(MatchError(semver.ErrInvalidVersion))
+		},
+		Entry("empty string", ""),
+		Entry("missing patch", "1.2"),
+		Entry("extra component", "1.2.3.4"),
+		Entry("leading zero", "01.2.3"),
+		Entry("leading v", "v1.2.3"),
+		Entry("non-numeric component", "1.x.3"),
+		Entry("empty pre-release", "1.2.3-"),
+		Entry("empty pre-release identifier", "1.2.3-rc..1"),
+		Entry("numeric pre-release identifier with leading zero", "1.2.3-rc.01"),
+		Entry("empty build metadata", "1.2.3+"),
+		Entry("invalid characters", "1.2.3-rc_1"),
+	)
+
+	DescribeTable("formatting versions",
+		func(input string) {
+			Expect(semver.MustParse(input).String()).To(Equal(input))
+		},
+		Entry("release version", "1.2.3"),
+		Entry("pre-release version", "1.2.3-rc.1"),
+		Entry("build metadata", "1.2.3+build.5"),
+		Entry("pre-release and build metadata", "1.2.3-rc.1+build.5"),
+	)
+
+	It("orders versions according to the specification", func() {
+		ordered := []string{
+			"0.9.9",
+			"1.0.0-alpha",
+			"1.0.0-alpha.1",
+			"1.0.0-alpha.beta",
+			"1.0.0-beta",
+			"1.0.0-beta.2",
+			"1.0.0-beta.11",
+			"1.0.0-rc.1",
+			"1.0.0",
+			"1.0.1",
+			"1.1.0",
+			"1.10.0",
+			"2.0.0",
+		}
+
+		for i := range ordered {
+			for j := range ordered {
+				a := semver.MustParse(ordered[i])
+				b := semver.MustParse(ordered[j])
+
+				switch {
+				case i < j:
+					Expect(a.Compare(b)).To(BeNumerically("<", 0), "expected %v < %v", a, b)
+				case i > j:
+					Expect(a.Compare(b)).To(BeNumerically(">", 0), "expected %v > %v", a, b)
+				default:
+					Expect(a.Compare(b)).To(BeZero(), "expected %v = %v", a, b)
+				}
+			}
+		}
+	})
+
+	It("ignores build metadata when comparing versions", func() {
+		Expect(semver.MustParse("1.2.3+build.1").Equal(semver.MustParse("1.2.3+build.2"))).To(BeTrue())
+	})
+
+	It("reports whether a version is a pre-release", func() {
+		Expect(semver.MustParse("1.2.3-rc.1").IsPrerelease()).To(BeTrue())
+		Expect(semver.MustParse("1.2.3+build.1").IsPrerelease()).To(BeFalse())
+	})
+
+	It("can be converted to and from JSON", func() {
+		bytes, err := json.Marshal(semver.MustParse("1.2.3-rc.1"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(bytes)).To(Equal(`"1.2.3-rc.1"`))
+
+		var v semver.Version
+		Expect(json.Unmarshal(bytes, &v)).To(Succeed())
+		Expect(v).To(Equal(semver.MustParse("1.2.3-rc.1")))
+	})
+
+	It("returns an error when converting an invalid version from JSON", func() {
+		var v semver.Version
+		Expect(json.Unmarshal([]byte(`"1.2"`), &v)).To(MatchError(semver.ErrInvalidVersion))
+	})
+})
@@ -0,0 +1,127 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type Key struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+}
+
+func NewKeyFromSeed(id string, seed []byte) (Key, error) {
+	if id == "" {
+		return Key{}, errors.New("key ID must not be empty")
+	}
+
+	if len(seed) != ed25519.SeedSize {
+		return Key{}, fmt.Errorf("seed for key '%v' must be %v bytes, but is %v bytes", id, ed25519.SeedSize, len(seed))
+	}
+
+	return Key{ID: id, PrivateKey: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+type Signer interface {
+	// SignDetached returns a JWS with a detached payload (RFC 7515, appendix F).
+	SignDetached(content []byte) string
+
+	PublicKeys() JSONWebKeySet
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	X         string `json:"x"`
+}
+
+type signer struct {
+	current         Key
+	protectedHeader string
+	publicKeys      JSONWebKeySet
+}
+
+// NewSigner signs with the first of keys, but publishes all of them, so that keys can be rotated.
+func NewSigner(keys []Key) (Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+
+	seen := map[string]struct{}{}
+	publicKeys := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+
+	for _, key := range keys {
+		if _, duplicate := seen[key.ID]; duplicate {
+			return nil, fmt.Errorf("more than one key has ID '%v'", key.ID)
+		}
+
+		seen[key.ID] = struct{}{}
+
+		if len(key.PrivateKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("key '%v' is not a valid Ed25519 private key", key.ID)
+		}
+
+		publicKey, _ := key.PrivateKey.Public().(ed25519.PublicKey)
+
+		publicKeys.Keys = append(publicKeys.Keys, JSONWebKey{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			X:         base64.RawURLEncoding.EncodeToString(publicKey),
+		})
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "EdDSA", "kid": keys[0].ID})
+
+	if err != nil {
+		return nil, fmt.Errorf("could not create JWS header: %w", err)
+	}
+
+	return &signer{
+		current:         keys[0],
+		protectedHeader: base64.RawURLEncoding.EncodeToString(header),
+		publicKeys:      publicKeys,
+	}, nil
+}
+
+func (s *signer) SignDetached(content []byte) string {
+	signingInput := s.protectedHeader + "." + base64.RawURLEncoding.EncodeToString(content)
+	signature := ed25519.Sign(s.current.PrivateKey, []byte(signingInput))
+
+	return s.protectedHeader + ".." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (s *signer) PublicKeys() JSONWebKeySet {
+	return s.publicKeys
+}
@@ -0,0 +1,141 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package signing_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/batect/updates.batect.dev/server/signing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signing content", func() {
+	currentSeed := bytes.Repeat([]byte{1}, ed25519.SeedSize)
+	nextSeed := bytes.Repeat([]byte{2}, ed25519.SeedSize)
+
+	mustCreateKey := func(id string, seed []byte) signing.Key {
+		key, err := signing.NewKeyFromSeed(id, seed)
+		Expect(err).ToNot(HaveOccurred())
+
+		return key
+	}
+
+	Context("given valid keys", func() {
+		var signer signing.Signer
+
+		BeforeEach(func() {
+			var err error
+			signer, err = signing.NewSigner([]signing.Key{mustCreateKey("2023-01", currentSeed), mustCreateKey("2023-07", nextSeed)})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Describe("signing content", func() {
+			content := []byte(`{"version":"0.83.2"}`)
+			var jws string
+
+			BeforeEach(func() {
+				jws = signer.SignDetached(content)
+			})
+
+			It("returns a JWS with a detached payload", func() {
+				Expect(strings.Split(jws, ".")).To(HaveLen(3))
+				Expect(strings.Split(jws, ".")[1]).To(BeEmpty())
+			})
+
+			It("identifies the algorithm and the first key in the header", func() {
+				header, err := base64.RawURLEncoding.DecodeString(strings.Split(jws, ".")[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(header).To(MatchJSON(`{"alg":"EdDSA","kid":"2023-01"}`))
+			})
+
+			It("returns a signature that can be verified with the first key's public key", func() {
+				parts := strings.Split(jws, ".")
+				signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+				Expect(err).ToNot(HaveOccurred())
+
+				signingInput := parts[0] + "." + base64.RawURLEncoding.EncodeToString(content)
+				publicKey := ed25519.NewKeyFromSeed(currentSeed).Public().(ed25519.PublicKey)
+				Expect(ed25519.Verify(publicKey, []byte(signingInput), signature)).To(BeTrue())
+			})
+
+			It("returns a signature that cannot be verified for different content", func() {
+				parts := strings.Split(jws, ".")
+				signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+				Expect(err).ToNot(HaveOccurred())
+
+				signingInput := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"version":"6.6.6"}`))
+				publicKey := ed25519.NewKeyFromSeed(currentSeed).Public().(ed25519.PublicKey)
+				Expect(ed25519.Verify(publicKey, []byte(signingInput), signature)).To(BeFalse())
+			})
+		})
+
+		Describe("getting the public keys", func() {
+			It("returns the public keys of all keys as a JSON Web Key Set", func() {
+				encoded, err := json.Marshal(signer.PublicKeys())
+				Expect(err).ToNot(HaveOccurred())
+
+				currentPublicKey := base64.RawURLEncoding.EncodeToString(ed25519.NewKeyFromSeed(currentSeed).Public().(ed25519.PublicKey))
+				nextPublicKey := base64.RawURLEncoding.EncodeToString(ed25519.NewKeyFromSeed(nextSeed).Public().(ed25519.PublicKey))
+
+				Expect(encoded).To(MatchJSON(`{
+					"keys": [
+						{"kty": "OKP", "crv": "Ed25519", "kid": "2023-01", "use": "sig", "alg": "EdDSA", "x": "` + currentPublicKey + `"},
+						{"kty": "OKP", "crv": "Ed25519", "kid": "2023-07", "use": "sig", "alg": "EdDSA", "x": "` + nextPublicKey + `"}
+					]
+				}`))
+			})
+		})
+	})
+
+	Context("given no keys", func() {
+		It("returns an error", func() {
+			_, err := signing.NewSigner(nil)
+			Expect(err).To(MatchError("at least one key is required"))
+		})
+	})
+
+	Context("given keys with duplicate IDs", func() {
+		It("returns an error", func() {
+			_, err := signing.NewSigner([]signing.Key{mustCreateKey("2023-01", currentSeed), mustCreateKey("2023-01", nextSeed)})
+			Expect(err).To(MatchError("more than one key has ID '2023-01'"))
+		})
+	})
+
+	Context("given an invalid private key", func() {
+		It("returns an error", func() {
+			_, err := signing.NewSigner([]signing.Key{{ID: "2023-01", PrivateKey: []byte{1, 2, 3}}})
+			Expect(err).To(MatchError("key '2023-01' is not a valid Ed25519 private key"))
+		})
+	})
+
+	DescribeTable("creating a key from an invalid seed",
+		func(id string, seed []byte, expectedError string) {
+			_, err := signing.NewKeyFromSeed(id, seed)
+			Expect(err).To(MatchError(expectedError))
+		},
+		Entry("no ID", "", currentSeed, "key ID must not be empty"),
+		Entry("a seed that is too short", "2023-01", []byte{1, 2, 3}, "seed for key '2023-01' must be 32 bytes, but is 3 bytes"),
+	)
+})
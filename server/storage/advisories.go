@@ -0,0 +1,120 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+)
+
+const advisoriesObjectName = "v1/advisories.json"
+
+type advisoryStore struct {
+	objects ObjectStore
+}
+
+func NewAdvisoryStore(objects ObjectStore) AdvisoryStore {
+	return &advisoryStore{
+		objects: objects,
+	}
+}
+
+type advisoriesDocument struct {
+	Advisories []Advisory `json:"advisories"`
+}
+
+func (s *advisoryStore) GetAdvisories(ctx context.Context) ([]Advisory, error) {
+	object, err := s.objects.GetObject(ctx, advisoriesObjectName)
+
+	if errors.Is(err, ErrObjectNotFound) {
+		return []Advisory{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get advisories: %w", err)
+	}
+
+	var document advisoriesDocument
+
+	if err := json.Unmarshal(object.Content, &document); err != nil {
+		return nil, fmt.Errorf("could not parse advisories: %w", err)
+	}
+
+	seenIDs := map[string]struct{}{}
+
+	for _, advisory := range document.Advisories {
+		if err := validateAdvisory(advisory); err != nil {
+			return nil, fmt.Errorf("advisories contain an invalid advisory: %w", err)
+		}
+
+		if _, seen := seenIDs[advisory.ID]; seen {
+			return nil, fmt.Errorf("advisories contain more than one advisory with ID '%v'", advisory.ID)
+		}
+
+		seenIDs[advisory.ID] = struct{}{}
+	}
+
+	if document.Advisories == nil {
+		document.Advisories = []Advisory{}
+	}
+
+	return document.Advisories, nil
+}
+
+func (a Advisory) Affects(version semver.Version) bool {
+	return a.AffectedVersions.Contains(version)
+}
+
+func validateAdvisory(advisory Advisory) error {
+	if advisory.ID == "" {
+		return errors.New("advisory has no ID")
+	}
+
+	switch advisory.Type {
+	case AdvisoryTypeSecurity, AdvisoryTypeBug:
+	default:
+		return fmt.Errorf("advisory %v has unknown type '%v'", advisory.ID, advisory.Type)
+	}
+
+	switch advisory.Severity {
+	case AdvisorySeverityLow, AdvisorySeverityMedium, AdvisorySeverityHigh, AdvisorySeverityCritical:
+	default:
+		return fmt.Errorf("advisory %v has unknown severity '%v'", advisory.ID, advisory.Severity)
+	}
+
+	if strings.TrimSpace(advisory.AffectedVersions.String()) == "" {
+		return fmt.Errorf("advisory %v has no affected versions", advisory.ID)
+	}
+
+	if advisory.FixedIn != nil && advisory.Affects(*advisory.FixedIn) {
+		return fmt.Errorf("advisory %v is fixed in %v, but that version is in its affected versions", advisory.ID, advisory.FixedIn)
+	}
+
+	if err := validateURL(advisory.URL); err != nil {
+		return fmt.Errorf("advisory %v has an invalid URL: %w", advisory.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Getting advisories", func() {
+	Context("given there are no advisories", func() {
+		It("returns an empty list", func() {
+			store := storage.NewAdvisoryStore(storage.NewInMemoryObjectStore(nil))
+			Expect(store.GetAdvisories(context.Background())).To(BeEmpty())
+		})
+	})
+
+	Context("given the advisories are valid", func() {
+		It("returns all advisories", func() {
+			store := storage.NewAdvisoryStore(jsonObject("v1/advisories.json", `{
+				"advisories": [
+					{
+						"id": "BATECT-2022-001",
+						"type": "security",
+						"severity": "high",
+						"summary": "Credentials may be written to logs",
+						"affectedVersions": ">=0.78.0 <0.79.2",
+						"fixedIn": "0.79.2",
+						"url": "https://github.com/batect/batect/security/advisories/1"
+					},
+					{
+						"id": "BATECT-2023-001",
+						"type": "bug",
+						"severity": "medium",
+						"summary": "Containers are not cleaned up",
+						"affectedVersions": "0.83.x",
+						"url": "https://github.com/batect/batect/issues/2"
+					}
+				]
+			}`))
+
+			fixedIn := semver.MustParse("0.79.2")
+
+			Expect(store.GetAdvisories(context.Background())).To(Equal([]storage.Advisory{
+				{
+					ID:               "BATECT-2022-001",
+					Type:             storage.AdvisoryTypeSecurity,
+					Severity:         storage.AdvisorySeverityHigh,
+					Summary:          "Credentials may be written to logs",
+					AffectedVersions: semver.MustParseRange(">=0.78.0 <0.79.2"),
+					FixedIn:          &fixedIn,
+					URL:              "https://github.com/batect/batect/security/advisories/1",
+				},
+				{
+					ID:               "BATECT-2023-001",
+					Type:             storage.AdvisoryTypeBug,
+					Severity:         storage.AdvisorySeverityMedium,
+					Summary:          "Containers are not cleaned up",
+					AffectedVersions: semver.MustParseRange("0.83.x"),
+					URL:              "https://github.com/batect/batect/issues/2",
+				},
+			}))
+		})
+	})
+
+	itRejectsInvalidContent("given the advisories are invalid",
+		func(advisory string) error {
+			_, err := storage.NewAdvisoryStore(jsonObject("v1/advisories.json", `{"advisories": [`+advisory+`]}`)).GetAdvisories(context.Background())
+			return err
+		},
+		Entry("missing ID", `{"type": "bug", "severity": "low", "affectedVersions": "1.2.x", "url": "https://example.com"}`, "advisory has no ID"),
+		Entry("unknown type", `{"id": "A-1", "type": "blah", "severity": "low", "affectedVersions": "1.2.x", "url": "https://example.com"}`, "advisory A-1 has unknown type 'blah'"),
+		Entry("unknown severity", `{"id": "A-1", "type": "bug", "severity": "blah", "affectedVersions": "1.2.x", "url": "https://example.com"}`, "advisory A-1 has unknown severity 'blah'"),
+		Entry("missing affected versions", `{"id": "A-1", "type": "bug", "severity": "low", "url": "https://example.com"}`, "advisory A-1 has no affected versions"),
+		Entry("invalid affected versions", `{"id": "A-1", "type": "bug", "severity": "low", "affectedVersions": "=>1.2.0", "url": "https://example.com"}`, "could not parse advisories"),
+		Entry("fixed in an affected version", `{"id": "A-1", "type": "bug", "severity": "low", "affectedVersions": "1.2.x", "fixedIn": "1.2.3", "url": "https://example.com"}`,
+			"advisory A-1 is fixed in 1.2.3, but that version is in its affected versions"),
+		Entry("invalid URL", `{"id": "A-1", "type": "bug", "severity": "low", "affectedVersions": "1.2.x", "url": "http://example.com"}`, "advisory A-1 has an invalid URL"),
+		Entry("duplicate ID", `{"id": "A-1", "type": "bug", "severity": "low", "affectedVersions": "1.2.x", "url": "https://example.com"}, {"id": "A-1", "type": "bug", "severity": "low", "affectedVersions": "1.3.x", "url": "https://example.com"}`,
+			"advisories contain more than one advisory with ID 'A-1'"),
+	)
+})
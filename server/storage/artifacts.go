@@ -0,0 +1,65 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+type artifactCache struct {
+	objects ObjectStore
+}
+
+func NewArtifactCache(objects ObjectStore) ArtifactCache {
+	return &artifactCache{
+		objects: objects,
+	}
+}
+
+func (c *artifactCache) GetArtifact(ctx context.Context, version string, fileName string) (Artifact, error) {
+	object, content, err := c.objects.OpenObject(ctx, artifactObjectName(version, fileName))
+
+	if err != nil {
+		return Artifact{}, fmt.Errorf("could not get cached artifact '%v' for version %v: %w", fileName, version, err)
+	}
+
+	artifact := Artifact{
+		Content:      content,
+		ContentType:  object.ContentType,
+		ETag:         fmt.Sprintf(`"%v"`, object.Generation),
+		LastModified: object.LastModified,
+	}
+
+	return artifact, nil
+}
+
+func (c *artifactCache) PutArtifact(ctx context.Context, version string, fileName string, content io.Reader, contentType string) error {
+	if _, err := c.objects.PutObjectFromReader(ctx, artifactObjectName(version, fileName), content, contentType, Precondition{}); err != nil {
+		return fmt.Errorf("could not cache artifact '%v' for version %v: %w", fileName, version, err)
+	}
+
+	return nil
+}
+
+func artifactObjectName(version string, fileName string) string {
+	return "v1/artifacts/" + version + "/" + fileName
+}
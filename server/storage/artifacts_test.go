@@ -0,0 +1,93 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing/iotest"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Caching artifacts", func() {
+	var objects storage.ObjectStore
+	var cache storage.ArtifactCache
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		objects = storage.NewInMemoryObjectStore(nil)
+		cache = storage.NewArtifactCache(objects)
+	})
+
+	Context("given the artifact has not been cached", func() {
+		It("returns an error matching ErrObjectNotFound", func() {
+			_, err := cache.GetArtifact(ctx, "0.83.2", "batect-0.83.2.jar")
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+		})
+	})
+
+	Context("given the artifact has been cached", func() {
+		BeforeEach(func() {
+			Expect(cache.PutArtifact(ctx, "0.83.2", "batect-0.83.2.jar", strings.NewReader("the jar"), "application/java-archive")).To(Succeed())
+		})
+
+		It("returns the cached artifact", func() {
+			artifact, err := cache.GetArtifact(ctx, "0.83.2", "batect-0.83.2.jar")
+			Expect(err).ToNot(HaveOccurred())
+			defer artifact.Content.Close()
+
+			Expect(io.ReadAll(artifact.Content)).To(Equal([]byte("the jar")))
+			Expect(artifact.ContentType).To(Equal("application/java-archive"))
+		})
+
+		It("stores the artifact in an object named after its version and file name", func() {
+			object, err := objects.GetObject(ctx, "v1/artifacts/0.83.2/batect-0.83.2.jar")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(object.Content).To(Equal([]byte("the jar")))
+		})
+
+		It("returns an ETag based on the generation of the object", func() {
+			object, err := objects.GetObject(ctx, "v1/artifacts/0.83.2/batect-0.83.2.jar")
+			Expect(err).ToNot(HaveOccurred())
+
+			artifact, err := cache.GetArtifact(ctx, "0.83.2", "batect-0.83.2.jar")
+			Expect(err).ToNot(HaveOccurred())
+			defer artifact.Content.Close()
+
+			Expect(artifact.ETag).To(Equal(`"` + fmt.Sprint(object.Generation) + `"`))
+		})
+	})
+
+	Context("given reading the artifact fails part way through", func() {
+		It("does not cache anything", func() {
+			content := io.MultiReader(strings.NewReader("the"), iotest.ErrReader(errors.New("connection reset")))
+			Expect(cache.PutArtifact(ctx, "0.83.2", "batect-0.83.2.jar", content, "application/java-archive")).ToNot(Succeed())
+
+			_, err := cache.GetArtifact(ctx, "0.83.2", "batect-0.83.2.jar")
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+		})
+	})
+})
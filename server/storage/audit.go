@@ -0,0 +1,79 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type auditTrail struct {
+	objects    ObjectStore
+	timeSource func() time.Time
+	uuidSource func() uuid.UUID
+}
+
+// Objects are never overwritten, so the trail is append-only.
+func NewAuditTrail(objects ObjectStore) AuditTrail {
+	timeSource := func() time.Time { return time.Now().UTC() }
+
+	return NewAuditTrailWithSpecificDependencies(objects, timeSource, uuid.New)
+}
+
+func NewAuditTrailWithSpecificDependencies(objects ObjectStore, timeSource func() time.Time, uuidSource func() uuid.UUID) AuditTrail {
+	return &auditTrail{
+		objects:    objects,
+		timeSource: timeSource,
+		uuidSource: uuidSource,
+	}
+}
+
+type auditRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	AdminAction
+}
+
+func (t *auditTrail) RecordAdminAction(ctx context.Context, action AdminAction) error {
+	record := auditRecord{
+		ID:          t.uuidSource(),
+		Timestamp:   t.timeSource(),
+		AdminAction: action,
+	}
+
+	content, err := json.Marshal(record)
+
+	if err != nil {
+		return fmt.Errorf("could not record admin action: %w", err)
+	}
+
+	// The timestamp comes first so that listing the objects returns them in chronological order.
+	name := fmt.Sprintf("admin/audit/%v/%v-%v.json", record.Timestamp.Format("2006/01/02"), record.Timestamp.Format("150405.000000000"), record.ID)
+
+	if _, err := t.objects.PutObject(ctx, name, content, "application/json", Precondition{DoesNotExist: true}); err != nil {
+		return fmt.Errorf("could not record admin action: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recording admin actions in the audit trail", func() {
+	var objects storage.ObjectStore
+	var trail storage.AuditTrail
+
+	BeforeEach(func() {
+		objects = storage.NewInMemoryObjectStore(nil)
+		timeSource := func() time.Time { return time.Date(2021, 3, 1, 9, 54, 40, 123000000, time.UTC) }
+		uuidSource := func() uuid.UUID { return uuid.MustParse("11112222-3333-4444-5555-666677778888") }
+		trail = storage.NewAuditTrailWithSpecificDependencies(objects, timeSource, uuidSource)
+
+		Expect(trail.RecordAdminAction(context.Background(), storage.AdminAction{
+			Actor:              "alice",
+			Action:             storage.AdminActionPublish,
+			Channel:            "beta",
+			Version:            "0.84.0",
+			Generation:         1235,
+			PreviousVersion:    "0.83.2",
+			PreviousGeneration: 1234,
+		})).To(Succeed())
+	})
+
+	It("stores the action with its time and a unique ID", func() {
+		object, err := objects.GetObject(context.Background(), "admin/audit/2021/03/01/095440.123000000-11112222-3333-4444-5555-666677778888.json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(object.ContentType).To(Equal("application/json"))
+
+		var record map[string]interface{}
+		Expect(json.Unmarshal(object.Content, &record)).To(Succeed())
+		Expect(record).To(Equal(map[string]interface{}{
+			"id":                 "11112222-3333-4444-5555-666677778888",
+			"timestamp":          "2021-03-01T09:54:40.123Z",
+			"actor":              "alice",
+			"action":             "publish",
+			"channel":            "beta",
+			"version":            "0.84.0",
+			"generation":         1235.0,
+			"previousVersion":    "0.83.2",
+			"previousGeneration": 1234.0,
+		}))
+	})
+
+	It("never overwrites a previously recorded action", func() {
+		err := trail.RecordAdminAction(context.Background(), storage.AdminAction{Actor: "bob", Action: storage.AdminActionPreview, Channel: "beta", Version: "0.84.0"})
+		Expect(err).To(MatchError(storage.ErrPreconditionFailed))
+	})
+})
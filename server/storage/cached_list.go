@@ -0,0 +1,137 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/batect/services-common/middleware"
+)
+
+// If nothing has been retrieved yet, a failed refresh's error is returned until the next refresh rather than retrying on every request.
+type cachedList[T any] struct {
+	description     string
+	get             func(ctx context.Context) ([]T, error)
+	refreshInterval time.Duration
+
+	lock       sync.RWMutex
+	cached     []T
+	lastError  error
+	retryAfter time.Time
+}
+
+func newCachedList[T any](ctx context.Context, description string, get func(ctx context.Context) ([]T, error), refreshInterval time.Duration) *cachedList[T] {
+	list := &cachedList[T]{
+		description:     description,
+		get:             get,
+		refreshInterval: refreshInterval,
+	}
+
+	go list.refreshPeriodically(ctx)
+
+	return list
+}
+
+func (c *cachedList[T]) Get(ctx context.Context) ([]T, error) {
+	if items, ok := c.getCached(); ok {
+		return items, nil
+	}
+
+	if err := c.recentFailure(); err != nil {
+		return nil, err
+	}
+
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	items, _ := c.getCached()
+
+	return items, nil
+}
+
+func (c *cachedList[T]) getCached() ([]T, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.cached == nil {
+		return nil, false
+	}
+
+	items := make([]T, len(c.cached))
+	copy(items, c.cached)
+
+	return items, true
+}
+
+func (c *cachedList[T]) recentFailure() error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if time.Now().Before(c.retryAfter) {
+		return c.lastError
+	}
+
+	return nil
+}
+
+func (c *cachedList[T]) refreshPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.refresh(ctx); err != nil {
+				log := middleware.LoggerFromContext(ctx).WithError(err)
+				log.Errorf("Refreshing cached %v failed, will continue to serve previously cached %v.", c.description, c.description)
+			}
+		}
+	}
+}
+
+func (c *cachedList[T]) refresh(ctx context.Context) error {
+	items, err := c.get(ctx)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err != nil {
+		c.lastError = fmt.Errorf("could not refresh %v: %w", c.description, err)
+		c.retryAfter = time.Now().Add(c.refreshInterval)
+
+		return c.lastError
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+
+	c.cached = items
+	c.lastError = nil
+	c.retryAfter = time.Time{}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("Caching the release history", func() {
+	var underlying *fakeReleaseHistoryStore
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var hook *test.Hook
+	var store storage.ReleaseHistoryStore
+
+	firstReleases := []storage.Release{{Version: semver.MustParse("1.0.0"), Status: storage.ReleaseStatusCurrent}}
+	secondReleases := []storage.Release{
+		{Version: semver.MustParse("2.0.0"), Status: storage.ReleaseStatusCurrent},
+		{Version: semver.MustParse("1.0.0"), Status: storage.ReleaseStatusDeprecated},
+	}
+
+	BeforeEach(func() {
+		underlying = &fakeReleaseHistoryStore{}
+
+		ctx, hook = testutils.ContextWithTestLogger(context.Background())
+		ctx, cancel = context.WithCancel(ctx)
+
+		store = storage.NewCachingReleaseHistoryStore(ctx, underlying, 10*time.Millisecond)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Context("before the release history has been retrieved", func() {
+		It("returns the release history from the underlying store", func() {
+			underlying.Set(firstReleases, nil)
+
+			Expect(store.GetReleases(context.Background())).To(Equal(firstReleases))
+		})
+
+		It("returns an error if the underlying store returns an error", func() {
+			underlying.Set(nil, errors.New("something went wrong"))
+
+			_, err := store.GetReleases(context.Background())
+			Expect(err).To(MatchError("could not refresh release history: something went wrong"))
+		})
+
+		It("returns an empty release history if none has been published", func() {
+			underlying.Set(nil, fmt.Errorf("could not get release history: %w", storage.ErrObjectNotFound))
+
+			Expect(store.GetReleases(context.Background())).To(BeEmpty())
+		})
+
+		It("returns the same error until the next refresh, rather than trying the underlying store again", func() {
+			underlying := &fakeReleaseHistoryStore{}
+			underlying.Set(nil, errors.New("something went wrong"))
+			store := storage.NewCachingReleaseHistoryStore(ctx, underlying, time.Hour)
+
+			_, err := store.GetReleases(context.Background())
+			Expect(err).To(MatchError("could not refresh release history: something went wrong"))
+
+			underlying.Set(firstReleases, nil)
+
+			_, err = store.GetReleases(context.Background())
+			Expect(err).To(MatchError("could not refresh release history: something went wrong"))
+			Expect(underlying.Calls()).To(Equal(1))
+		})
+	})
+
+	Context("after the release history has been retrieved", func() {
+		BeforeEach(func() {
+			underlying.Set(firstReleases, nil)
+			Expect(store.GetReleases(context.Background())).To(Equal(firstReleases))
+		})
+
+		It("returns the new release history after the next background refresh", func() {
+			underlying.Set(secondReleases, nil)
+
+			Eventually(func() ([]storage.Release, error) {
+				return store.GetReleases(context.Background())
+			}).Should(Equal(secondReleases))
+		})
+
+		Context("when the underlying store starts returning errors", func() {
+			BeforeEach(func() {
+				underlying.Set(nil, errors.New("something went wrong"))
+			})
+
+			It("continues to return the previously cached release history", func() {
+				Consistently(func() ([]storage.Release, error) {
+					return store.GetReleases(context.Background())
+				}, "50ms").Should(Equal(firstReleases))
+			})
+
+			It("logs an error for the failed refresh", func() {
+				Eventually(func() []string {
+					messages := []string{}
+
+					for _, e := range hook.AllEntries() {
+						messages = append(messages, e.Message)
+					}
+
+					return messages
+				}).Should(ContainElement("Refreshing cached release history failed, will continue to serve previously cached release history."))
+			})
+		})
+	})
+})
+
+var _ = Describe("Caching yanked versions", func() {
+	var underlying *fakeYankedVersionStore
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var hook *test.Hook
+	var store storage.YankedVersionStore
+
+	firstYanked := []storage.YankedVersion{{Version: semver.MustParse("1.0.0"), Reason: "Broken"}}
+	secondYanked := []storage.YankedVersion{
+		{Version: semver.MustParse("1.0.0"), Reason: "Broken"},
+		{Version: semver.MustParse("1.0.1"), Reason: "Also broken"},
+	}
+
+	BeforeEach(func() {
+		underlying = &fakeYankedVersionStore{}
+
+		ctx, hook = testutils.ContextWithTestLogger(context.Background())
+		ctx, cancel = context.WithCancel(ctx)
+
+		store = storage.NewCachingYankedVersionStore(ctx, underlying, 10*time.Millisecond)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Context("before the yanked versions have been retrieved", func() {
+		It("returns the yanked versions from the underlying store", func() {
+			underlying.Set(firstYanked, nil)
+
+			Expect(store.GetYankedVersions(context.Background())).To(Equal(firstYanked))
+		})
+
+		It("returns an error if the underlying store returns an error", func() {
+			underlying.Set(nil, errors.New("something went wrong"))
+
+			_, err := store.GetYankedVersions(context.Background())
+			Expect(err).To(MatchError("could not refresh yanked versions: something went wrong"))
+		})
+	})
+
+	Context("after the yanked versions have been retrieved", func() {
+		BeforeEach(func() {
+			underlying.Set(firstYanked, nil)
+			Expect(store.GetYankedVersions(context.Background())).To(Equal(firstYanked))
+		})
+
+		It("returns the new yanked versions after the next background refresh", func() {
+			underlying.Set(secondYanked, nil)
+
+			Eventually(func() ([]storage.YankedVersion, error) {
+				return store.GetYankedVersions(context.Background())
+			}).Should(Equal(secondYanked))
+		})
+
+		Context("when the underlying store starts returning errors", func() {
+			BeforeEach(func() {
+				underlying.Set(nil, errors.New("something went wrong"))
+			})
+
+			It("continues to return the previously cached yanked versions", func() {
+				Consistently(func() ([]storage.YankedVersion, error) {
+					return store.GetYankedVersions(context.Background())
+				}, "50ms").Should(Equal(firstYanked))
+			})
+
+			It("logs an error for the failed refresh", func() {
+				Eventually(func() []string {
+					messages := []string{}
+
+					for _, e := range hook.AllEntries() {
+						messages = append(messages, e.Message)
+					}
+
+					return messages
+				}).Should(ContainElement("Refreshing cached yanked versions failed, will continue to serve previously cached yanked versions."))
+			})
+		})
+	})
+})
+
+type fakeReleaseHistoryStore struct {
+	lock     sync.Mutex
+	releases []storage.Release
+	err      error
+	calls    int
+}
+
+func (f *fakeReleaseHistoryStore) Set(releases []storage.Release, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.releases = releases
+	f.err = err
+}
+
+func (f *fakeReleaseHistoryStore) GetReleases(_ context.Context) ([]storage.Release, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.calls++
+
+	return f.releases, f.err
+}
+
+func (f *fakeReleaseHistoryStore) Calls() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.calls
+}
+
+type fakeYankedVersionStore struct {
+	lock   sync.Mutex
+	yanked []storage.YankedVersion
+	err    error
+}
+
+func (f *fakeYankedVersionStore) Set(yanked []storage.YankedVersion, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.yanked = yanked
+	f.err = err
+}
+
+func (f *fakeYankedVersionStore) GetYankedVersions(_ context.Context) ([]storage.YankedVersion, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.yanked, f.err
+}
@@ -0,0 +1,341 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/batect/services-common/middleware"
+	"golang.org/x/sync/singleflight"
+)
+
+// initialRetryDelay is how long to wait before retrying a failed refresh of a channel that has nothing cached. The delay doubles after
+// each consecutive failure, up to the refresh interval.
+const initialRetryDelay = time.Second
+
+const generationCheckInterval = time.Second
+
+// generationCheckPollInterval is how often the background refresher looks for channels that are due a generation check.
+const generationCheckPollInterval = 100 * time.Millisecond
+
+type cachingLatestVersionStore struct {
+	underlying      LatestVersionStore
+	generations     LatestVersionGenerationStore
+	channels        []string
+	refreshInterval time.Duration
+	timeSource      func() time.Time
+	refreshes       singleflight.Group
+
+	lock    sync.RWMutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	cached                *VersionDescriptor
+	lastSuccessfulRefresh time.Time
+	lastRefreshFailed     bool
+
+	nextGenerationCheck    time.Time
+	failedGenerationChecks int
+
+	lastError           error
+	consecutiveFailures int
+	retryAfter          time.Time
+}
+
+// NewCachingLatestVersionStore returns a store that holds descriptors from underlying in memory and refreshes them every refreshInterval
+// until ctx is cancelled. The descriptors for channels are retrieved straight away, and other channels are cached once they have been
+// requested for the first time.
+//
+// The stored descriptor's generation is also checked in the background once a second, so every instance serves a new descriptor within a second.
+//
+// If a refresh fails, the last successfully retrieved descriptor continues to be served, with its Staleness set to the time since it was retrieved.
+func NewCachingLatestVersionStore(
+	ctx context.Context,
+	underlying LatestVersionStore,
+	generations LatestVersionGenerationStore,
+	channels []string,
+	refreshInterval time.Duration,
+) CachingLatestVersionStore {
+	timeSource := func() time.Time { return time.Now().UTC() }
+
+	return NewCachingLatestVersionStoreWithSpecificDependencies(ctx, underlying, generations, channels, refreshInterval, timeSource)
+}
+
+func NewCachingLatestVersionStoreWithSpecificDependencies(
+	ctx context.Context,
+	underlying LatestVersionStore,
+	generations LatestVersionGenerationStore,
+	channels []string,
+	refreshInterval time.Duration,
+	timeSource func() time.Time,
+) CachingLatestVersionStore {
+	store := &cachingLatestVersionStore{
+		underlying:      underlying,
+		generations:     generations,
+		channels:        channels,
+		refreshInterval: refreshInterval,
+		timeSource:      timeSource,
+		entries:         map[string]*cacheEntry{},
+	}
+
+	go store.refreshPeriodically(ctx)
+
+	return store
+}
+
+func (c *cachingLatestVersionStore) GetLatestVersionDescriptor(ctx context.Context, channel string) (VersionDescriptor, error) {
+	if descriptor, ok := c.getCached(channel); ok {
+		return descriptor, nil
+	}
+
+	// Don't make every request wait for storage while it is failing.
+	if err := c.recentFailure(channel); err != nil {
+		return VersionDescriptor{}, err
+	}
+
+	if err := c.refresh(ctx, channel); err != nil {
+		return VersionDescriptor{}, err
+	}
+
+	descriptor, _ := c.getCached(channel)
+
+	return descriptor, nil
+}
+
+// Channels that have not been cached yet are ignored, as their descriptor will be retrieved when it is first requested.
+func (c *cachingLatestVersionStore) RefreshLatestVersionDescriptor(ctx context.Context, channel string) error {
+	if _, ok := c.getCached(channel); !ok {
+		return nil
+	}
+
+	return c.refresh(ctx, channel)
+}
+
+func (c *cachingLatestVersionStore) getCached(channel string) (VersionDescriptor, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	entry, ok := c.entries[channel]
+
+	if !ok || entry.cached == nil {
+		return VersionDescriptor{}, false
+	}
+
+	descriptor := *entry.cached
+
+	if entry.lastRefreshFailed {
+		descriptor.Staleness = c.timeSource().Sub(entry.lastSuccessfulRefresh)
+	}
+
+	return descriptor, true
+}
+
+// recentFailure returns the error from the last refresh of channel if it failed and it is too soon to try again, or nil otherwise.
+func (c *cachingLatestVersionStore) recentFailure(channel string) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	entry, ok := c.entries[channel]
+
+	if !ok || !c.timeSource().Before(entry.retryAfter) {
+		return nil
+	}
+
+	return entry.lastError
+}
+
+// channelsDueGenerationCheck returns the cached channels that are due a generation check, along with the generation of the descriptor cached for each.
+func (c *cachingLatestVersionStore) channelsDueGenerationCheck() map[string]int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	now := c.timeSource()
+	due := map[string]int64{}
+
+	for channel, entry := range c.entries {
+		if entry.cached != nil && !now.Before(entry.nextGenerationCheck) && !now.Before(entry.retryAfter) {
+			due[channel] = entry.cached.Generation
+		}
+	}
+
+	return due
+}
+
+func (c *cachingLatestVersionStore) checkGenerations(ctx context.Context) {
+	for channel, cachedGeneration := range c.channelsDueGenerationCheck() {
+		generation, err := c.generations.GetLatestVersionDescriptorGeneration(ctx, channel)
+
+		c.recordGenerationCheck(channel, err)
+
+		if err != nil {
+			middleware.LoggerFromContext(ctx).WithError(err).WithField("channel", channel).
+				Warn("Checking generation of latest version descriptor failed, will continue to serve previously cached descriptor.")
+
+			continue
+		}
+
+		if generation != cachedGeneration {
+			c.refreshChannels(ctx, []string{channel})
+		}
+	}
+}
+
+// recordGenerationCheck schedules the next generation check for channel, backing off in the same way as refreshes if the check failed.
+func (c *cachingLatestVersionStore) recordGenerationCheck(channel string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry := c.entries[channel]
+
+	if err != nil {
+		entry.nextGenerationCheck = c.timeSource().Add(c.retryDelay(entry.failedGenerationChecks))
+		entry.failedGenerationChecks++
+
+		return
+	}
+
+	entry.nextGenerationCheck = c.timeSource().Add(generationCheckInterval)
+	entry.failedGenerationChecks = 0
+}
+
+func (c *cachingLatestVersionStore) refreshPeriodically(ctx context.Context) {
+	c.refreshChannels(ctx, c.channels)
+
+	refreshTicker := time.NewTicker(c.refreshInterval)
+	defer refreshTicker.Stop()
+
+	generationTicker := time.NewTicker(generationCheckPollInterval)
+	defer generationTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-refreshTicker.C:
+			c.refreshChannels(ctx, c.channelsToRefresh())
+		case <-generationTicker.C:
+			c.checkGenerations(ctx)
+		}
+	}
+}
+
+func (c *cachingLatestVersionStore) refreshChannels(ctx context.Context, channels []string) {
+	for _, channel := range channels {
+		if err := c.refresh(ctx, channel); err != nil {
+			log := middleware.LoggerFromContext(ctx).WithError(err).WithField("channel", channel)
+
+			switch {
+			case errors.Is(err, ErrInvalidVersionDescriptor):
+				log.Error("Latest version descriptor is invalid, will continue to serve previously cached descriptor.")
+			case errors.Is(err, ErrObjectNotFound):
+				log.Warn("Latest version descriptor does not exist.")
+			default:
+				log.Error("Refreshing cached latest version descriptor failed, will continue to serve previously cached descriptor.")
+			}
+		}
+	}
+}
+
+// channelsToRefresh returns the channels given when the store was created, and any other channels that have been requested since.
+func (c *cachingLatestVersionStore) channelsToRefresh() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	channels := append([]string{}, c.channels...)
+
+	for channel := range c.entries {
+		if !contains(c.channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+
+	return channels
+}
+
+// refresh shares a single request to the underlying store between everyone refreshing the same channel at the same time.
+func (c *cachingLatestVersionStore) refresh(ctx context.Context, channel string) error {
+	_, err, _ := c.refreshes.Do(channel, func() (interface{}, error) {
+		return nil, c.fetch(ctx, channel)
+	})
+
+	return err
+}
+
+func (c *cachingLatestVersionStore) fetch(ctx context.Context, channel string) error {
+	descriptor, err := c.underlying.GetLatestVersionDescriptor(ctx, channel)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[channel]
+
+	if !ok {
+		entry = &cacheEntry{}
+		c.entries[channel] = entry
+	}
+
+	if err != nil {
+		err = fmt.Errorf("could not refresh latest version descriptor: %w", err)
+		entry.lastRefreshFailed = true
+		entry.lastError = err
+		entry.retryAfter = c.timeSource().Add(c.retryDelay(entry.consecutiveFailures))
+		entry.consecutiveFailures++
+
+		return err
+	}
+
+	entry.cached = &descriptor
+	entry.lastSuccessfulRefresh = c.timeSource()
+	entry.nextGenerationCheck = entry.lastSuccessfulRefresh.Add(generationCheckInterval)
+	entry.lastRefreshFailed = false
+	entry.lastError = nil
+	entry.consecutiveFailures = 0
+
+	return nil
+}
+
+func (c *cachingLatestVersionStore) retryDelay(previousFailures int) time.Duration {
+	delay := initialRetryDelay
+
+	for i := 0; i < previousFailures && delay < c.refreshInterval; i++ {
+		delay *= 2
+	}
+
+	if delay > c.refreshInterval {
+		return c.refreshInterval
+	}
+
+	return delay
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,604 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("Caching latest version store", func() {
+	var underlying *fakeLatestVersionStore
+	var currentTime time.Time
+	var timeLock sync.Mutex
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var hook *test.Hook
+	var store storage.LatestVersionStore
+
+	firstDescriptor := storage.VersionDescriptor{Info: storage.VersionInfo{Version: semver.MustParse("1.0.0")}, ETag: `"1"`}
+	secondDescriptor := storage.VersionDescriptor{Info: storage.VersionInfo{Version: semver.MustParse("2.0.0")}, ETag: `"2"`}
+
+	setTime := func(t time.Time) {
+		timeLock.Lock()
+		defer timeLock.Unlock()
+
+		currentTime = t
+	}
+
+	BeforeEach(func() {
+		underlying = &fakeLatestVersionStore{}
+		setTime(time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC))
+
+		ctx, hook = testutils.ContextWithTestLogger(context.Background())
+		ctx, cancel = context.WithCancel(ctx)
+
+		timeSource := func() time.Time {
+			timeLock.Lock()
+			defer timeLock.Unlock()
+
+			return currentTime
+		}
+
+		store = storage.NewCachingLatestVersionStoreWithSpecificDependencies(ctx, underlying, underlying, nil, 10*time.Millisecond, timeSource)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Context("before the first background refresh has completed", func() {
+		Context("given the underlying store returns a descriptor", func() {
+			BeforeEach(func() {
+				underlying.Set("stable", firstDescriptor, nil)
+			})
+
+			It("returns the descriptor from the underlying store without any staleness", func() {
+				Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+			})
+		})
+
+		Context("given the underlying store returns an error", func() {
+			BeforeEach(func() {
+				underlying.Set("stable", storage.VersionDescriptor{}, errors.New("something went wrong"))
+			})
+
+			It("returns the error", func() {
+				_, err := store.GetLatestVersionDescriptor(ctx, "stable")
+				Expect(err).To(MatchError("could not refresh latest version descriptor: something went wrong"))
+			})
+		})
+	})
+
+	Context("given descriptors for multiple channels", func() {
+		BeforeEach(func() {
+			underlying.Set("stable", firstDescriptor, nil)
+			underlying.Set("beta", secondDescriptor, nil)
+		})
+
+		It("caches the descriptor for each channel separately", func() {
+			Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+			Expect(store.GetLatestVersionDescriptor(ctx, "beta")).To(Equal(secondDescriptor))
+			Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+		})
+	})
+
+	Context("after a descriptor has been cached", func() {
+		BeforeEach(func() {
+			underlying.Set("stable", firstDescriptor, nil)
+			Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+		})
+
+		Context("when the underlying store returns a new descriptor", func() {
+			BeforeEach(func() {
+				underlying.Set("stable", secondDescriptor, nil)
+			})
+
+			It("returns the new descriptor after the next background refresh", func() {
+				Eventually(func() (storage.VersionDescriptor, error) {
+					return store.GetLatestVersionDescriptor(ctx, "stable")
+				}).Should(Equal(secondDescriptor))
+			})
+		})
+
+		Context("when the underlying store starts returning invalid descriptors", func() {
+			BeforeEach(func() {
+				underlying.Set("stable", storage.VersionDescriptor{}, fmt.Errorf("could not get descriptor: %w", storage.ErrInvalidVersionDescriptor))
+			})
+
+			It("continues to return the previously cached descriptor", func() {
+				Consistently(func() (storage.VersionInfo, error) {
+					descriptor, err := store.GetLatestVersionDescriptor(ctx, "stable")
+
+					return descriptor.Info, err
+				}, "50ms").Should(Equal(firstDescriptor.Info))
+			})
+
+			It("logs an error indicating that the descriptor is invalid", func() {
+				Eventually(func() []string {
+					messages := []string{}
+
+					for _, e := range hook.AllEntries() {
+						messages = append(messages, e.Message)
+					}
+
+					return messages
+				}).Should(ContainElement("Latest version descriptor is invalid, will continue to serve previously cached descriptor."))
+			})
+		})
+
+		Context("when the underlying store starts returning errors", func() {
+			BeforeEach(func() {
+				setTime(time.Date(2021, 3, 1, 9, 2, 30, 0, time.UTC))
+				underlying.Set("stable", storage.VersionDescriptor{}, errors.New("something went wrong"))
+			})
+
+			It("continues to return the previously cached descriptor, reporting how long it has been since it was refreshed", func() {
+				expected := firstDescriptor
+				expected.Staleness = 150 * time.Second
+
+				Eventually(func() (storage.VersionDescriptor, error) {
+					return store.GetLatestVersionDescriptor(ctx, "stable")
+				}).Should(Equal(expected))
+			})
+
+			It("logs an error for the failed refresh", func() {
+				Eventually(func() []string {
+					messages := []string{}
+
+					for _, e := range hook.AllEntries() {
+						messages = append(messages, e.Message)
+					}
+
+					return messages
+				}).Should(ContainElement("Refreshing cached latest version descriptor failed, will continue to serve previously cached descriptor."))
+			})
+
+			Context("when the underlying store recovers", func() {
+				BeforeEach(func() {
+					Eventually(func() time.Duration {
+						descriptor, _ := store.GetLatestVersionDescriptor(ctx, "stable")
+
+						return descriptor.Staleness
+					}).ShouldNot(BeZero())
+
+					underlying.Set("stable", secondDescriptor, nil)
+				})
+
+				It("returns the new descriptor without any staleness", func() {
+					Eventually(func() (storage.VersionDescriptor, error) {
+						return store.GetLatestVersionDescriptor(ctx, "stable")
+					}).Should(Equal(secondDescriptor))
+				})
+			})
+		})
+	})
+})
+
+var _ = Describe("Refreshing a cached latest version descriptor on demand", func() {
+	var underlying *fakeLatestVersionStore
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var store storage.CachingLatestVersionStore
+
+	firstDescriptor := storage.VersionDescriptor{Info: storage.VersionInfo{Version: semver.MustParse("1.0.0")}, ETag: `"1"`}
+	secondDescriptor := storage.VersionDescriptor{Info: storage.VersionInfo{Version: semver.MustParse("2.0.0")}, ETag: `"2"`}
+
+	BeforeEach(func() {
+		underlying = &fakeLatestVersionStore{}
+		ctx, _ = testutils.ContextWithTestLogger(context.Background())
+		ctx, cancel = context.WithCancel(ctx)
+
+		// Use a long refresh interval so that any change must be the result of the on-demand refresh.
+		store = storage.NewCachingLatestVersionStore(ctx, underlying, underlying, nil, time.Hour)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Context("given the descriptor for the channel has been cached", func() {
+		BeforeEach(func() {
+			underlying.Set("stable", firstDescriptor, nil)
+			Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+		})
+
+		Context("when the underlying store returns a new descriptor", func() {
+			BeforeEach(func() {
+				underlying.Set("stable", secondDescriptor, nil)
+			})
+
+			It("returns the new descriptor immediately after the refresh", func() {
+				Expect(store.RefreshLatestVersionDescriptor(ctx, "stable")).To(Succeed())
+				Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(secondDescriptor))
+			})
+		})
+
+		Context("when the underlying store returns an error", func() {
+			BeforeEach(func() {
+				underlying.Set("stable", storage.VersionDescriptor{}, errors.New("something went wrong"))
+			})
+
+			It("returns the error", func() {
+				Expect(store.RefreshLatestVersionDescriptor(ctx, "stable")).To(MatchError("could not refresh latest version descriptor: something went wrong"))
+			})
+
+			It("continues to return the previously cached descriptor", func() {
+				_ = store.RefreshLatestVersionDescriptor(ctx, "stable")
+
+				descriptor, err := store.GetLatestVersionDescriptor(ctx, "stable")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(descriptor.Info).To(Equal(firstDescriptor.Info))
+			})
+		})
+	})
+
+	Context("given the descriptor for the channel has not been cached", func() {
+		BeforeEach(func() {
+			underlying.Set("beta", storage.VersionDescriptor{}, errors.New("something went wrong"))
+		})
+
+		It("does not retrieve the descriptor", func() {
+			Expect(store.RefreshLatestVersionDescriptor(ctx, "beta")).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("Retrieving latest version descriptors that have not been cached", func() {
+	var underlying *fakeLatestVersionStore
+	var currentTime time.Time
+	var timeLock sync.Mutex
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var timeSource func() time.Time
+
+	firstDescriptor := storage.VersionDescriptor{Info: storage.VersionInfo{Version: semver.MustParse("1.0.0")}, ETag: `"1"`}
+
+	setTime := func(t time.Time) {
+		timeLock.Lock()
+		defer timeLock.Unlock()
+
+		currentTime = t
+	}
+
+	BeforeEach(func() {
+		underlying = &fakeLatestVersionStore{}
+		setTime(time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC))
+
+		ctx, _ = testutils.ContextWithTestLogger(context.Background())
+		ctx, cancel = context.WithCancel(ctx)
+
+		timeSource = func() time.Time {
+			timeLock.Lock()
+			defer timeLock.Unlock()
+
+			return currentTime
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Context("given the store is created with a list of channels", func() {
+		BeforeEach(func() {
+			underlying.Set("stable", firstDescriptor, nil)
+			underlying.Set("beta", firstDescriptor, nil)
+
+			storage.NewCachingLatestVersionStoreWithSpecificDependencies(ctx, underlying, underlying, []string{"stable", "beta"}, time.Hour, timeSource)
+		})
+
+		It("retrieves the descriptor for each channel without waiting for it to be requested", func() {
+			Eventually(func() int { return underlying.Calls("stable") }).Should(Equal(1))
+			Eventually(func() int { return underlying.Calls("beta") }).Should(Equal(1))
+		})
+	})
+
+	Context("given many requests for the same channel arrive at once", func() {
+		var store storage.LatestVersionStore
+
+		BeforeEach(func() {
+			underlying.Set("stable", firstDescriptor, nil)
+			underlying.Block()
+
+			store = storage.NewCachingLatestVersionStoreWithSpecificDependencies(ctx, underlying, underlying, nil, time.Hour, timeSource)
+		})
+
+		It("retrieves the descriptor from the underlying store once and returns it to every request", func() {
+			results := make(chan storage.VersionDescriptor, 10)
+
+			for i := 0; i < 10; i++ {
+				go func() {
+					defer GinkgoRecover()
+
+					descriptor, err := store.GetLatestVersionDescriptor(ctx, "stable")
+					Expect(err).ToNot(HaveOccurred())
+					results <- descriptor
+				}()
+			}
+
+			Eventually(func() int { return underlying.Calls("stable") }).Should(Equal(1))
+			underlying.Unblock()
+
+			for i := 0; i < 10; i++ {
+				Eventually(results).Should(Receive(Equal(firstDescriptor)))
+			}
+
+			Expect(underlying.Calls("stable")).To(Equal(1))
+		})
+	})
+
+	Context("given the underlying store returns an error", func() {
+		var store storage.LatestVersionStore
+
+		BeforeEach(func() {
+			underlying.Set("stable", storage.VersionDescriptor{}, errors.New("something went wrong"))
+
+			store = storage.NewCachingLatestVersionStoreWithSpecificDependencies(ctx, underlying, underlying, nil, time.Hour, timeSource)
+
+			_, err := store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).To(MatchError("could not refresh latest version descriptor: something went wrong"))
+		})
+
+		It("returns the same error to requests made soon after without retrying", func() {
+			setTime(time.Date(2021, 3, 1, 9, 0, 0, 500_000_000, time.UTC))
+
+			_, err := store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).To(MatchError("could not refresh latest version descriptor: something went wrong"))
+			Expect(underlying.Calls("stable")).To(Equal(1))
+		})
+
+		It("retries once a second has passed", func() {
+			underlying.Set("stable", firstDescriptor, nil)
+			setTime(time.Date(2021, 3, 1, 9, 0, 1, 0, time.UTC))
+
+			Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+			Expect(underlying.Calls("stable")).To(Equal(2))
+		})
+
+		It("waits twice as long before retrying after the retry also fails", func() {
+			setTime(time.Date(2021, 3, 1, 9, 0, 1, 0, time.UTC))
+			_, _ = store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(underlying.Calls("stable")).To(Equal(2))
+
+			setTime(time.Date(2021, 3, 1, 9, 0, 2, 0, time.UTC))
+			_, _ = store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(underlying.Calls("stable")).To(Equal(2))
+
+			setTime(time.Date(2021, 3, 1, 9, 0, 3, 0, time.UTC))
+			_, _ = store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(underlying.Calls("stable")).To(Equal(3))
+		})
+	})
+})
+
+var _ = Describe("Checking the generation of cached latest version descriptors", func() {
+	var underlying *fakeLatestVersionStore
+	var currentTime time.Time
+	var timeLock sync.Mutex
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var hook *test.Hook
+	var store storage.LatestVersionStore
+
+	firstDescriptor := storage.VersionDescriptor{Info: storage.VersionInfo{Version: semver.MustParse("1.0.0")}, ETag: `"1"`, Generation: 1}
+	secondDescriptor := storage.VersionDescriptor{Info: storage.VersionInfo{Version: semver.MustParse("2.0.0")}, ETag: `"2"`, Generation: 2}
+
+	setTime := func(t time.Time) {
+		timeLock.Lock()
+		defer timeLock.Unlock()
+
+		currentTime = t
+	}
+
+	BeforeEach(func() {
+		underlying = &fakeLatestVersionStore{}
+		setTime(time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC))
+
+		ctx, hook = testutils.ContextWithTestLogger(context.Background())
+		ctx, cancel = context.WithCancel(ctx)
+
+		timeSource := func() time.Time {
+			timeLock.Lock()
+			defer timeLock.Unlock()
+
+			return currentTime
+		}
+
+		store = storage.NewCachingLatestVersionStoreWithSpecificDependencies(ctx, underlying, underlying, nil, time.Hour, timeSource)
+
+		underlying.Set("stable", firstDescriptor, nil)
+		Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Context("given less than a second has passed since the descriptor was retrieved", func() {
+		BeforeEach(func() {
+			underlying.Set("stable", secondDescriptor, nil)
+			setTime(time.Date(2021, 3, 1, 9, 0, 0, 500_000_000, time.UTC))
+		})
+
+		It("does not check its generation", func() {
+			Consistently(func() int { return underlying.GenerationChecks("stable") }, "300ms").Should(BeZero())
+			Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+		})
+	})
+
+	Context("given a second has passed and the stored descriptor has not changed", func() {
+		BeforeEach(func() {
+			setTime(time.Date(2021, 3, 1, 9, 0, 1, 0, time.UTC))
+		})
+
+		It("checks its generation in the background without retrieving it again", func() {
+			Eventually(func() int { return underlying.GenerationChecks("stable") }).Should(Equal(1))
+			Expect(underlying.Calls("stable")).To(Equal(1))
+			Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+		})
+
+		It("does not check the generation again for another second", func() {
+			Eventually(func() int { return underlying.GenerationChecks("stable") }).Should(Equal(1))
+			Consistently(func() int { return underlying.GenerationChecks("stable") }, "300ms").Should(Equal(1))
+		})
+	})
+
+	Context("given a second has passed and the stored descriptor has changed", func() {
+		BeforeEach(func() {
+			underlying.Set("stable", secondDescriptor, nil)
+			setTime(time.Date(2021, 3, 1, 9, 0, 1, 0, time.UTC))
+		})
+
+		It("returns the new descriptor after the next background check", func() {
+			Eventually(func() (storage.VersionDescriptor, error) {
+				return store.GetLatestVersionDescriptor(ctx, "stable")
+			}).Should(Equal(secondDescriptor))
+		})
+	})
+
+	Context("given a second has passed and checking the generation fails", func() {
+		BeforeEach(func() {
+			underlying.Set("stable", storage.VersionDescriptor{}, errors.New("something went wrong"))
+			setTime(time.Date(2021, 3, 1, 9, 0, 1, 0, time.UTC))
+		})
+
+		It("continues to return the cached descriptor", func() {
+			Eventually(func() int { return underlying.GenerationChecks("stable") }).Should(Equal(1))
+			Expect(store.GetLatestVersionDescriptor(ctx, "stable")).To(Equal(firstDescriptor))
+		})
+
+		It("logs a warning", func() {
+			Eventually(func() *logrus.Entry { return hook.LastEntry() }).ShouldNot(BeNil())
+			Expect(hook.LastEntry().Level).To(Equal(logrus.WarnLevel))
+			Expect(hook.LastEntry().Message).To(Equal("Checking generation of latest version descriptor failed, will continue to serve previously cached descriptor."))
+		})
+
+		It("waits twice as long before checking again after the retry also fails", func() {
+			Eventually(func() int { return underlying.GenerationChecks("stable") }).Should(Equal(1))
+
+			setTime(time.Date(2021, 3, 1, 9, 0, 2, 0, time.UTC))
+			Eventually(func() int { return underlying.GenerationChecks("stable") }).Should(Equal(2))
+
+			setTime(time.Date(2021, 3, 1, 9, 0, 3, 0, time.UTC))
+			Consistently(func() int { return underlying.GenerationChecks("stable") }, "300ms").Should(Equal(2))
+
+			setTime(time.Date(2021, 3, 1, 9, 0, 4, 0, time.UTC))
+			Eventually(func() int { return underlying.GenerationChecks("stable") }).Should(Equal(3))
+		})
+	})
+})
+
+type fakeLatestVersionStore struct {
+	lock             sync.Mutex
+	descriptors      map[string]storage.VersionDescriptor
+	errors           map[string]error
+	calls            map[string]int
+	generationChecks map[string]int
+	blocked          chan struct{}
+}
+
+func (f *fakeLatestVersionStore) Set(channel string, descriptor storage.VersionDescriptor, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.descriptors == nil {
+		f.descriptors = map[string]storage.VersionDescriptor{}
+		f.errors = map[string]error{}
+	}
+
+	f.descriptors[channel] = descriptor
+	f.errors[channel] = err
+}
+
+// Block makes calls to GetLatestVersionDescriptor wait until Unblock is called.
+func (f *fakeLatestVersionStore) Block() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.blocked = make(chan struct{})
+}
+
+func (f *fakeLatestVersionStore) Unblock() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	close(f.blocked)
+}
+
+func (f *fakeLatestVersionStore) Calls(channel string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.calls[channel]
+}
+
+func (f *fakeLatestVersionStore) GenerationChecks(channel string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.generationChecks[channel]
+}
+
+func (f *fakeLatestVersionStore) GetLatestVersionDescriptorGeneration(_ context.Context, channel string) (int64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.generationChecks == nil {
+		f.generationChecks = map[string]int{}
+	}
+
+	f.generationChecks[channel]++
+
+	return f.descriptors[channel].Generation, f.errors[channel]
+}
+
+func (f *fakeLatestVersionStore) GetLatestVersionDescriptor(_ context.Context, channel string) (storage.VersionDescriptor, error) {
+	f.lock.Lock()
+
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+
+	f.calls[channel]++
+	blocked := f.blocked
+
+	f.lock.Unlock()
+
+	if blocked != nil {
+		<-blocked
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.descriptors[channel], f.errors[channel]
+}
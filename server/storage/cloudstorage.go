@@ -20,30 +20,38 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 
 	cloudstorage "cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
 )
 
-type cloudStorageLatestVersionStore struct {
+type cloudStorageObjectStore struct {
 	client *cloudstorage.Client
 	bucket *cloudstorage.BucketHandle
 }
 
-func NewCloudStorageLatestVersionStore(bucketName string, client *cloudstorage.Client) LatestVersionStore {
-	return &cloudStorageLatestVersionStore{
+func NewCloudStorageObjectStore(bucketName string, client *cloudstorage.Client) ObjectStore {
+	return &cloudStorageObjectStore{
 		client: client,
 		bucket: client.Bucket(bucketName),
 	}
 }
 
-func (c *cloudStorageLatestVersionStore) GetLatestVersionDescriptor(ctx context.Context) (VersionDescriptor, error) {
-	reader, err := c.bucket.Object("v1/latest.json").NewReader(ctx)
+func (c *cloudStorageObjectStore) GetObject(ctx context.Context, name string) (Object, error) {
+	reader, err := c.bucket.Object(name).NewReader(ctx)
+
+	if errors.Is(err, cloudstorage.ErrObjectNotExist) {
+		return Object{}, newObjectNotFoundError(name, err)
+	}
 
 	if err != nil {
-		return VersionDescriptor{}, fmt.Errorf("could not get latest version descriptor: %w", err)
+		return Object{}, err
 	}
 
 	defer reader.Close()
@@ -51,13 +59,182 @@ func (c *cloudStorageLatestVersionStore) GetLatestVersionDescriptor(ctx context.
 	content, err := io.ReadAll(reader)
 
 	if err != nil {
-		return VersionDescriptor{}, fmt.Errorf("could not read file content: %w", err)
+		return Object{}, fmt.Errorf("could not read file content: %w", err)
+	}
+
+	object := Object{
+		Content:      content,
+		ContentType:  reader.Attrs.ContentType,
+		Generation:   reader.Attrs.Generation,
+		LastModified: reader.Attrs.LastModified,
+	}
+
+	return object, nil
+}
+
+func (c *cloudStorageObjectStore) GetObjectGeneration(ctx context.Context, name string) (int64, error) {
+	attrs, err := c.bucket.Object(name).Attrs(ctx)
+
+	if errors.Is(err, cloudstorage.ErrObjectNotExist) {
+		return 0, newObjectNotFoundError(name, err)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return attrs.Generation, nil
+}
+
+func (c *cloudStorageObjectStore) PutObject(ctx context.Context, name string, content []byte, contentType string, precondition Precondition) (Object, error) {
+	object, err := c.PutObjectFromReader(ctx, name, bytes.NewReader(content), contentType, precondition)
+
+	if err != nil {
+		return Object{}, err
 	}
 
-	descriptor := VersionDescriptor{
-		Content:     content,
-		ContentType: reader.Attrs.ContentType,
+	object.Content = content
+
+	return object, nil
+}
+
+func (c *cloudStorageObjectStore) OpenObject(ctx context.Context, name string) (Object, io.ReadSeekCloser, error) {
+	attrs, err := c.bucket.Object(name).Attrs(ctx)
+
+	if errors.Is(err, cloudstorage.ErrObjectNotExist) {
+		return Object{}, nil, newObjectNotFoundError(name, err)
+	}
+
+	if err != nil {
+		return Object{}, nil, err
+	}
+
+	object := Object{
+		ContentType:  attrs.ContentType,
+		Generation:   attrs.Generation,
+		LastModified: attrs.Updated,
 	}
 
-	return descriptor, nil
+	reader := &cloudStorageObjectReader{
+		ctx:    ctx,
+		handle: c.bucket.Object(name).Generation(attrs.Generation),
+		size:   attrs.Size,
+	}
+
+	return object, reader, nil
+}
+
+func (c *cloudStorageObjectStore) PutObjectFromReader(
+	ctx context.Context,
+	name string,
+	content io.Reader,
+	contentType string,
+	precondition Precondition,
+) (Object, error) {
+	handle := c.bucket.Object(name)
+
+	switch {
+	case precondition.DoesNotExist:
+		handle = handle.If(cloudstorage.Conditions{DoesNotExist: true})
+	case precondition.GenerationMatch != 0:
+		handle = handle.If(cloudstorage.Conditions{GenerationMatch: precondition.GenerationMatch})
+	}
+
+	// Cancelling the writer's context is the only way to abandon an upload without storing what has been written so far.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := handle.NewWriter(ctx)
+	w.ContentType = contentType
+
+	if _, err := io.Copy(w, content); err != nil {
+		cancel()
+		_ = w.Close()
+
+		return Object{}, fmt.Errorf("writing to Cloud Storage failed: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		var apiError *googleapi.Error
+
+		if errors.As(err, &apiError) && apiError.Code == http.StatusPreconditionFailed {
+			return Object{}, newPreconditionFailedError(name, precondition)
+		}
+
+		return Object{}, fmt.Errorf("storing object in Cloud Storage failed: %w", err)
+	}
+
+	attrs := w.Attrs()
+
+	object := Object{
+		ContentType:  attrs.ContentType,
+		Generation:   attrs.Generation,
+		LastModified: attrs.Updated,
+	}
+
+	return object, nil
+}
+
+// cloudStorageObjectReader starts a new ranged read of the same generation after each seek.
+type cloudStorageObjectReader struct {
+	ctx    context.Context
+	handle *cloudstorage.ObjectHandle
+	size   int64
+	offset int64
+	reader *cloudstorage.Reader
+}
+
+func (r *cloudStorageObjectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.reader == nil {
+		reader, err := r.handle.NewRangeReader(r.ctx, r.offset, -1)
+
+		if err != nil {
+			return 0, fmt.Errorf("could not read object from Cloud Storage: %w", err)
+		}
+
+		r.reader = reader
+	}
+
+	n, err := r.reader.Read(p)
+	r.offset += int64(n)
+
+	return n, err
+}
+
+func (r *cloudStorageObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+
+	if offset < 0 {
+		return 0, errors.New("cannot seek to before the start of the object")
+	}
+
+	if offset != r.offset {
+		if err := r.Close(); err != nil {
+			return 0, err
+		}
+
+		r.offset = offset
+	}
+
+	return offset, nil
+}
+
+func (r *cloudStorageObjectReader) Close() error {
+	if r.reader == nil {
+		return nil
+	}
+
+	err := r.reader.Close()
+	r.reader = nil
+
+	return err
 }
@@ -21,8 +21,11 @@ package storage_test
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	cloudstorage "cloud.google.com/go/storage"
+	"github.com/batect/updates.batect.dev/server/semver"
 	"github.com/batect/updates.batect.dev/server/storage"
 	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
@@ -32,11 +35,13 @@ import (
 
 var _ = Describe("Getting version information from Cloud Storage", func() {
 	var bucket *cloudstorage.BucketHandle
+	var bucketName string
+	var client *cloudstorage.Client
 	var store storage.LatestVersionStore
 
 	BeforeEach(func() {
 		project := "my-project"
-		bucketName := "test-version-store-" + uuid.New().String()
+		bucketName = "test-version-store-" + uuid.New().String()
 
 		// Note that we also have to set the STORAGE_EMULATOR_HOST environment variable so that object downloads
 		// are done from the correct host and over HTTP (rather than HTTPS).
@@ -44,14 +49,15 @@ var _ = Describe("Getting version information from Cloud Storage", func() {
 			option.WithEndpoint("http://cloud-storage/storage/v1/"),
 		}
 
-		client, err := cloudstorage.NewClient(context.Background(), opts...)
+		var err error
+		client, err = cloudstorage.NewClient(context.Background(), opts...)
 		Expect(err).ToNot(HaveOccurred())
 
 		bucket = client.Bucket(bucketName)
 		err = bucket.Create(context.Background(), project, nil)
 		Expect(err).ToNot(HaveOccurred())
 
-		store = storage.NewCloudStorageLatestVersionStore(bucketName, client)
+		store = storage.NewLatestVersionStore(storage.NewCloudStorageObjectStore(bucketName, client))
 	})
 
 	Describe("given the version information file does not exist in the bucket", func() {
@@ -59,7 +65,7 @@ var _ = Describe("Getting version information from Cloud Storage", func() {
 		var err error
 
 		BeforeEach(func() {
-			descriptor, err = store.GetLatestVersionDescriptor(context.Background())
+			descriptor, err = store.GetLatestVersionDescriptor(context.Background(), storage.StableChannel)
 		})
 
 		It("returns an empty version descriptor", func() {
@@ -67,34 +73,49 @@ var _ = Describe("Getting version information from Cloud Storage", func() {
 		})
 
 		It("returns an appropriate error", func() {
-			Expect(err).To(MatchError("could not get latest version descriptor: storage: object doesn't exist"))
+			Expect(err).To(MatchError("could not get latest version descriptor for channel 'stable': object 'v1/latest.json' does not exist"))
 		})
 	})
 
 	Describe("given the version information file exists in the bucket", func() {
 		var descriptor storage.VersionDescriptor
+		var attrs *cloudstorage.ObjectAttrs
 		var err error
 
 		BeforeEach(func() {
 			w := bucket.Object("v1/latest.json").NewWriter(context.Background())
-			w.ContentType = "application/json+descriptor"
-			_, writeError := w.Write([]byte(`{"some":"descriptor"}`))
+			w.ContentType = "application/json"
+			_, writeError := w.Write([]byte(`{"version": "0.83.2", "url": "https://github.com/batect/batect/releases/tag/0.83.2", "files": [{"type": "script", "name": "batect", "url": "https://github.com/batect/batect/releases/download/0.83.2/batect"}]}`))
 			Expect(writeError).ToNot(HaveOccurred())
 			writeError = w.Close()
 			Expect(writeError).ToNot(HaveOccurred())
+			attrs = w.Attrs()
 
-			descriptor, err = store.GetLatestVersionDescriptor(context.Background())
+			descriptor, err = store.GetLatestVersionDescriptor(context.Background(), storage.StableChannel)
 		})
 
 		It("returns a version descriptor with the details from the bucket", func() {
-			Expect(descriptor).To(Equal(storage.VersionDescriptor{
-				Content:     []byte(`{"some":"descriptor"}`),
-				ContentType: "application/json+descriptor",
+			Expect(descriptor.Info).To(Equal(storage.VersionInfo{
+				Version: semver.MustParse("0.83.2"),
+				URL:     "https://github.com/batect/batect/releases/tag/0.83.2",
+				Files:   []storage.VersionFile{{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.83.2/batect"}},
 			}))
 		})
 
+		It("returns the object's generation as the entity tag", func() {
+			Expect(descriptor.ETag).To(Equal(fmt.Sprintf(`"%v"`, attrs.Generation)))
+		})
+
+		It("returns the object's update time as the last modified time", func() {
+			Expect(descriptor.LastModified).To(BeTemporally("~", attrs.Updated, time.Second))
+		})
+
 		It("does not return an error", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
+
+	itBehavesLikeAWritableObjectStore(func() storage.ObjectStore {
+		return storage.NewCloudStorageObjectStore(bucketName, client)
+	})
 })
@@ -0,0 +1,218 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+)
+
+type VersionInfo struct {
+	Version semver.Version `json:"version"`
+	URL     string         `json:"url"`
+	Files   []VersionFile  `json:"files"`
+	Notes   string         `json:"notes,omitempty"`
+
+	// Rollout and Support are never returned to clients.
+	Rollout *Rollout       `json:"rollout,omitempty"`
+	Support *SupportPolicy `json:"support,omitempty"`
+}
+
+type Rollout struct {
+	Percentage int         `json:"percentage"`
+	Previous   VersionInfo `json:"previous"`
+}
+
+type SupportPolicy struct {
+	// Clients on versions older than MinimumVersion are required to upgrade.
+	MinimumVersion       *semver.Version `json:"minimumVersion,omitempty"`
+	MinimumVersionReason string          `json:"minimumVersionReason,omitempty"`
+
+	// Clients on versions older than RecommendedMinimumVersion are encouraged to upgrade.
+	RecommendedMinimumVersion       *semver.Version `json:"recommendedMinimumVersion,omitempty"`
+	RecommendedMinimumVersionReason string          `json:"recommendedMinimumVersionReason,omitempty"`
+
+	// Clients on BadVersions are required to upgrade.
+	BadVersions []BadVersion `json:"badVersions,omitempty"`
+}
+
+type BadVersion struct {
+	Range  semver.Range `json:"range"`
+	Reason string       `json:"reason"`
+}
+
+type VersionFile struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+var ErrInvalidVersionDescriptor = errors.New("version descriptor is invalid")
+
+func ParseVersionInfo(content []byte) (VersionInfo, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	var info VersionInfo
+
+	if err := decoder.Decode(&info); err != nil {
+		return VersionInfo{}, fmt.Errorf("%w: could not parse JSON: %v", ErrInvalidVersionDescriptor, err)
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return VersionInfo{}, fmt.Errorf("%w: unexpected content after JSON object", ErrInvalidVersionDescriptor)
+	}
+
+	if err := info.Validate(); err != nil {
+		return VersionInfo{}, fmt.Errorf("%w: %v", ErrInvalidVersionDescriptor, err)
+	}
+
+	return info, nil
+}
+
+func (i VersionInfo) Validate() error {
+	if i.Version.Equal(semver.Version{}) {
+		return errors.New("version is missing")
+	}
+
+	if err := validateURL(i.URL); err != nil {
+		return fmt.Errorf("url is invalid: %w", err)
+	}
+
+	if len(i.Files) == 0 {
+		return errors.New("files is empty")
+	}
+
+	for index, f := range i.Files {
+		if f.Type == "" {
+			return fmt.Errorf("files[%v].type is missing", index)
+		}
+
+		if f.Name == "" {
+			return fmt.Errorf("files[%v].name is missing", index)
+		}
+
+		if err := validateURL(f.URL); err != nil {
+			return fmt.Errorf("files[%v].url is invalid: %w", index, err)
+		}
+	}
+
+	if i.Rollout != nil {
+		if err := i.Rollout.validate(i.Version); err != nil {
+			return fmt.Errorf("rollout is invalid: %w", err)
+		}
+	}
+
+	if i.Support != nil {
+		if err := i.Support.validate(i.Version); err != nil {
+			return fmt.Errorf("support is invalid: %w", err)
+		}
+	}
+
+	// Clients that aren't selected for the rollout receive the previous version, so they must be able to satisfy the minimum version with it.
+	if i.Rollout != nil && i.Support != nil && i.Support.MinimumVersion != nil && i.Rollout.Previous.Version.LessThan(*i.Support.MinimumVersion) {
+		return fmt.Errorf("support is invalid: minimum version %v is newer than the rollout's previous version %v", i.Support.MinimumVersion, i.Rollout.Previous.Version)
+	}
+
+	return nil
+}
+
+func (p SupportPolicy) validate(version semver.Version) error {
+	if p.MinimumVersion != nil && version.LessThan(*p.MinimumVersion) {
+		return fmt.Errorf("minimum version %v is newer than %v", p.MinimumVersion, version)
+	}
+
+	if p.RecommendedMinimumVersion != nil && version.LessThan(*p.RecommendedMinimumVersion) {
+		return fmt.Errorf("recommended minimum version %v is newer than %v", p.RecommendedMinimumVersion, version)
+	}
+
+	for index, bad := range p.BadVersions {
+		if strings.TrimSpace(bad.Range.String()) == "" {
+			return fmt.Errorf("badVersions[%v].range is missing", index)
+		}
+
+		if bad.Reason == "" {
+			return fmt.Errorf("badVersions[%v].reason is missing", index)
+		}
+	}
+
+	return nil
+}
+
+func (r Rollout) validate(version semver.Version) error {
+	if r.Percentage < 0 || r.Percentage > 100 {
+		return fmt.Errorf("percentage must be between 0 and 100, but is %v", r.Percentage)
+	}
+
+	if r.Previous.Rollout != nil {
+		return errors.New("previous version cannot itself have a rollout")
+	}
+
+	if r.Previous.Support != nil {
+		return errors.New("previous version cannot have its own support policy")
+	}
+
+	if err := r.Previous.Validate(); err != nil {
+		return fmt.Errorf("previous version is invalid: %w", err)
+	}
+
+	if !r.Previous.Version.LessThan(version) {
+		return fmt.Errorf("previous version %v must be older than %v", r.Previous.Version, version)
+	}
+
+	return nil
+}
+
+func (i VersionInfo) ForClient(receivesNewVersion bool) VersionInfo {
+	if i.Rollout == nil || receivesNewVersion {
+		forClient := i
+		forClient.Rollout = nil
+		forClient.Support = nil
+
+		return forClient
+	}
+
+	return i.Rollout.Previous
+}
+
+func validateURL(value string) error {
+	if value == "" {
+		return errors.New("value is missing")
+	}
+
+	parsed, err := url.Parse(value)
+
+	if err != nil {
+		return err
+	}
+
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("'%v' is not an absolute HTTPS URL", value)
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"strings"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parsing version descriptors", func() {
+	It("parses a valid descriptor", func() {
+		info, err := storage.ParseVersionInfo([]byte(`{
+			"version": "0.83.2",
+			"url": "https://github.com/batect/batect/releases/tag/0.83.2",
+			"files": [
+				{"type": "script", "name": "batect", "url": "https://github.com/batect/batect/releases/download/0.83.2/batect"},
+				{"type": "script", "name": "batect.cmd", "url": "https://github.com/batect/batect/releases/download/0.83.2/batect.cmd"}
+			],
+			"notes": "Some release notes"
+		}`))
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info).To(Equal(storage.VersionInfo{
+			Version: semver.MustParse("0.83.2"),
+			URL:     "https://github.com/batect/batect/releases/tag/0.83.2",
+			Files: []storage.VersionFile{
+				{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.83.2/batect"},
+				{Type: "script", Name: "batect.cmd", URL: "https://github.com/batect/batect/releases/download/0.83.2/batect.cmd"},
+			},
+			Notes: "Some release notes",
+		}))
+	})
+
+	It("parses a valid descriptor with a support policy", func() {
+		info, err := storage.ParseVersionInfo([]byte(supportDescriptor(`{
+			"minimumVersion": "0.80.0",
+			"minimumVersionReason": "Versions before 0.80.0 can no longer download Java.",
+			"recommendedMinimumVersion": "0.82.0",
+			"badVersions": [{"range": "0.81.x", "reason": "0.81.x can delete files outside the project directory."}]
+		}`)))
+
+		minimumVersion := semver.MustParse("0.80.0")
+		recommendedMinimumVersion := semver.MustParse("0.82.0")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Support).To(Equal(&storage.SupportPolicy{
+			MinimumVersion:            &minimumVersion,
+			MinimumVersionReason:      "Versions before 0.80.0 can no longer download Java.",
+			RecommendedMinimumVersion: &recommendedMinimumVersion,
+			BadVersions:               []storage.BadVersion{{Range: semver.MustParseRange("0.81.x"), Reason: "0.81.x can delete files outside the project directory."}},
+		}))
+	})
+
+	It("parses a valid descriptor with a rollout and a minimum version no newer than the rollout's previous version", func() {
+		content := strings.TrimSuffix(rolloutDescriptor(`{"percentage": 25, "previous": `+previousVersionJSON+`}`), "}") + `, "support": {"minimumVersion": "0.83.1"}}`
+		_, err := storage.ParseVersionInfo([]byte(content))
+
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("parses a valid descriptor with a rollout", func() {
+		info, err := storage.ParseVersionInfo([]byte(rolloutDescriptor(`{"percentage": 25, "previous": ` + previousVersionJSON + `}`)))
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Rollout).To(Equal(&storage.Rollout{
+			Percentage: 25,
+			Previous: storage.VersionInfo{
+				Version: semver.MustParse("0.83.1"),
+				URL:     "https://example.com",
+				Files:   []storage.VersionFile{{Type: "script", Name: "batect", URL: "https://example.com"}},
+			},
+		}))
+	})
+
+	DescribeTable("rejecting invalid descriptors",
+		func(content string, expectedError string) {
+			_, err := storage.ParseVersionInfo([]byte(content))
+			Expect(err).To(MatchError(storage.ErrInvalidVersionDescriptor))
+			Expect(err).To(MatchError(ContainSubstring(expectedError)))
+		},
+		Entry("empty document", ``, "could not parse JSON"),
+		Entry("half-written document", `{"version": "0.83.2", "url": "https://git`, "could not parse JSON"),
+		Entry("not an object", `[]`, "could not parse JSON"),
+		Entry("multiple objects", `{"version": "0.83.2", "url": "https://example.com", "files": [{"type": "script", "name": "batect", "url": "https://example.com"}]} {}`,
+			"unexpected content after JSON object"),
+		Entry("unknown field", `{"vesion": "0.83.2"}`, `unknown field "vesion"`),
+		Entry("missing version", `{"url": "https://example.com", "files": [{"type": "script", "name": "batect", "url": "https://example.com"}]}`, "version is missing"),
+		Entry("invalid version", `{"version": "0.83", "url": "https://example.com", "files": []}`, "not a valid semantic version"),
+		Entry("missing URL", `{"version": "0.83.2", "files": [{"type": "script", "name": "batect", "url": "https://example.com"}]}`, "url is invalid: value is missing"),
+		Entry("non-HTTPS URL", `{"version": "0.83.2", "url": "http://example.com", "files": [{"type": "script", "name": "batect", "url": "https://example.com"}]}`,
+			"url is invalid: 'http://example.com' is not an absolute HTTPS URL"),
+		Entry("relative URL", `{"version": "0.83.2", "url": "/releases", "files": [{"type": "script", "name": "batect", "url": "https://example.com"}]}`,
+			"url is invalid: '/releases' is not an absolute HTTPS URL"),
+		Entry("no files", `{"version": "0.83.2", "url": "https://example.com", "files": []}`, "files is empty"),
+		Entry("file without type", `{"version": "0.83.2", "url": "https://example.com", "files": [{"name": "batect", "url": "https://example.com"}]}`, "files[0].type is missing"),
+		Entry("file without name", `{"version": "0.83.2", "url": "https://example.com", "files": [{"type": "script", "url": "https://example.com"}]}`, "files[0].name is missing"),
+		Entry("file with invalid URL", `{"version": "0.83.2", "url": "https://example.com", "files": [{"type": "script", "name": "batect", "url": "ftp://example.com"}]}`,
+			"files[0].url is invalid"),
+		Entry("rollout with percentage over 100", rolloutDescriptor(`{"percentage": 101, "previous": `+previousVersionJSON+`}`), "rollout is invalid: percentage must be between 0 and 100, but is 101"),
+		Entry("rollout with negative percentage", rolloutDescriptor(`{"percentage": -1, "previous": `+previousVersionJSON+`}`), "rollout is invalid: percentage must be between 0 and 100, but is -1"),
+		Entry("rollout without previous version", rolloutDescriptor(`{"percentage": 10}`), "rollout is invalid: previous version is invalid: version is missing"),
+		Entry("rollout with invalid previous version", rolloutDescriptor(`{"percentage": 10, "previous": {"version": "0.83.1"}}`), "rollout is invalid: previous version is invalid: url is invalid"),
+		Entry("rollout with newer previous version", rolloutDescriptor(`{"percentage": 10, "previous": `+strings.ReplaceAll(previousVersionJSON, "0.83.1", "0.84.0")+`}`),
+			"rollout is invalid: previous version 0.84.0 must be older than 0.83.2"),
+		Entry("support with minimum version newer than the version", supportDescriptor(`{"minimumVersion": "0.84.0"}`), "support is invalid: minimum version 0.84.0 is newer than 0.83.2"),
+		Entry("support with recommended minimum version newer than the version", supportDescriptor(`{"recommendedMinimumVersion": "0.84.0"}`),
+			"support is invalid: recommended minimum version 0.84.0 is newer than 0.83.2"),
+		Entry("support with minimum version newer than the rollout's previous version",
+			strings.TrimSuffix(rolloutDescriptor(`{"percentage": 10, "previous": `+previousVersionJSON+`}`), "}")+`, "support": {"minimumVersion": "0.83.2"}}`,
+			"support is invalid: minimum version 0.83.2 is newer than the rollout's previous version 0.83.1"),
+		Entry("support with invalid minimum version", supportDescriptor(`{"minimumVersion": "0.84"}`), "not a valid semantic version"),
+		Entry("support with bad version without range", supportDescriptor(`{"badVersions": [{"reason": "Broken"}]}`), "support is invalid: badVersions[0].range is missing"),
+		Entry("support with bad version with invalid range", supportDescriptor(`{"badVersions": [{"range": "=>0.82.0", "reason": "Broken"}]}`), "not a valid version range"),
+		Entry("support with bad version without reason", supportDescriptor(`{"badVersions": [{"range": "0.82.x"}]}`), "support is invalid: badVersions[0].reason is missing"),
+		Entry("rollout with nested rollout", rolloutDescriptor(`{"percentage": 10, "previous": `+strings.TrimSuffix(previousVersionJSON, "}")+`, "rollout": {"percentage": 10, "previous": `+previousVersionJSON+`}}}`),
+			"rollout is invalid: previous version cannot itself have a rollout"),
+	)
+
+	Describe("selecting the information to return to a client", func() {
+		previous := storage.VersionInfo{
+			Version: semver.MustParse("0.83.1"),
+			URL:     "https://github.com/batect/batect/releases/tag/0.83.1",
+			Files:   []storage.VersionFile{{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.83.1/batect"}},
+		}
+
+		current := storage.VersionInfo{
+			Version: semver.MustParse("0.83.2"),
+			URL:     "https://github.com/batect/batect/releases/tag/0.83.2",
+			Files:   []storage.VersionFile{{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.83.2/batect"}},
+		}
+
+		withRollout := current
+		withRollout.Rollout = &storage.Rollout{Percentage: 25, Previous: previous}
+		withRollout.Support = &storage.SupportPolicy{MinimumVersion: &previous.Version}
+
+		It("returns the new version without the rollout or support policy to clients selected to receive it", func() {
+			Expect(withRollout.ForClient(true)).To(Equal(current))
+		})
+
+		It("returns the previous version to other clients", func() {
+			Expect(withRollout.ForClient(false)).To(Equal(previous))
+		})
+
+		It("returns the version to all clients when there is no rollout", func() {
+			Expect(current.ForClient(false)).To(Equal(current))
+		})
+	})
+})
+
+const previousVersionJSON = `{"version": "0.83.1", "url": "https://example.com", "files": [{"type": "script", "name": "batect", "url": "https://example.com"}]}`
+
+func rolloutDescriptor(rollout string) string {
+	return `{"version": "0.83.2", "url": "https://example.com", "files": [{"type": "script", "name": "batect", "url": "https://example.com"}], "rollout": ` + rollout + `}`
+}
+
+func supportDescriptor(support string) string {
+	return `{"version": "0.83.2", "url": "https://example.com", "files": [{"type": "script", "name": "batect", "url": "https://example.com"}], "support": ` + support + `}`
+}
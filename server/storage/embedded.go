@@ -0,0 +1,97 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// embeddedObjects uses the same layout as the bucket, and is refreshed at build time by scripts/update_embedded_descriptors.sh.
+//
+//go:embed embedded
+var embeddedObjects embed.FS //nolint:gochecknoglobals
+
+var errEmbeddedObjectStoreIsReadOnly = errors.New("embedded object store is read-only")
+
+type embeddedObjectStore struct {
+	files fs.FS
+}
+
+func NewEmbeddedObjectStore() ObjectStore {
+	files, err := fs.Sub(embeddedObjects, "embedded")
+
+	if err != nil {
+		panic(err)
+	}
+
+	return &embeddedObjectStore{
+		files: files,
+	}
+}
+
+func (e *embeddedObjectStore) GetObject(_ context.Context, name string) (Object, error) {
+	content, err := fs.ReadFile(e.files, name)
+
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		return Object{}, newObjectNotFoundError(name, err)
+	}
+
+	if err != nil {
+		return Object{}, fmt.Errorf("could not read embedded object '%v': %w", name, err)
+	}
+
+	// Embedded files have no generation, so use a checksum so that the object's entity tag changes whenever its content does.
+	return Object{
+		Content:     content,
+		ContentType: contentTypeForFile(path.Base(name)),
+		Generation:  int64(crc32.ChecksumIEEE(content)),
+	}, nil
+}
+
+func (e *embeddedObjectStore) GetObjectGeneration(ctx context.Context, name string) (int64, error) {
+	object, err := e.GetObject(ctx, name)
+
+	return object.Generation, err
+}
+
+func (e *embeddedObjectStore) PutObject(_ context.Context, name string, _ []byte, _ string, _ Precondition) (Object, error) {
+	return Object{}, fmt.Errorf("could not write object '%v': %w", name, errEmbeddedObjectStoreIsReadOnly)
+}
+
+func (e *embeddedObjectStore) OpenObject(ctx context.Context, name string) (Object, io.ReadSeekCloser, error) {
+	object, err := e.GetObject(ctx, name)
+
+	if err != nil {
+		return Object{}, nil, err
+	}
+
+	return object, newBytesReader(object.Content), nil
+}
+
+func (e *embeddedObjectStore) PutObjectFromReader(_ context.Context, name string, _ io.Reader, _ string, _ Precondition) (Object, error) {
+	return Object{}, fmt.Errorf("could not write object '%v': %w", name, errEmbeddedObjectStoreIsReadOnly)
+}
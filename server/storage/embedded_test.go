@@ -0,0 +1,49 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Getting objects compiled into the binary", func() {
+	store := storage.NewEmbeddedObjectStore()
+	ctx := context.Background()
+
+	It("contains a valid latest version descriptor for the stable channel", func() {
+		descriptor, err := storage.NewLatestVersionStore(store).GetLatestVersionDescriptor(ctx, "stable")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(descriptor.ETag).ToNot(BeEmpty())
+	})
+
+	It("returns an error matching ErrObjectNotFound when getting an object that does not exist", func() {
+		_, err := store.GetObject(ctx, "v1/thing.json")
+		Expect(err).To(MatchError(storage.ErrObjectNotFound))
+	})
+
+	It("returns an error when writing an object", func() {
+		_, err := store.PutObject(ctx, "v1/latest.json", []byte("{}"), "application/json", storage.Precondition{})
+		Expect(err).To(MatchError("could not write object 'v1/latest.json': embedded object store is read-only"))
+	})
+})
@@ -0,0 +1,59 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrObjectNotFound     = errors.New("object does not exist")
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+type objectNotFoundError struct {
+	name  string
+	cause error
+}
+
+func newObjectNotFoundError(name string, cause error) error {
+	return &objectNotFoundError{name: name, cause: cause}
+}
+
+func (e *objectNotFoundError) Error() string {
+	return fmt.Sprintf("object '%v' does not exist", e.name)
+}
+
+func (e *objectNotFoundError) Is(target error) bool {
+	return target == ErrObjectNotFound //nolint:errorlint
+}
+
+func (e *objectNotFoundError) Unwrap() error {
+	return e.cause
+}
+
+func newPreconditionFailedError(name string, precondition Precondition) error {
+	if precondition.DoesNotExist {
+		return fmt.Errorf("%w: object '%v' already exists", ErrPreconditionFailed, name)
+	}
+
+	return fmt.Errorf("%w: object '%v' is not at generation %v", ErrPreconditionFailed, name, precondition.GenerationMatch)
+}
@@ -0,0 +1,116 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/batect/services-common/middleware"
+)
+
+type DescriptorSource string
+
+const (
+	DescriptorSourcePrimary  DescriptorSource = "primary"
+	DescriptorSourceSnapshot DescriptorSource = "snapshot"
+	DescriptorSourceEmbedded DescriptorSource = "embedded"
+)
+
+type fallbackLatestVersionStore struct {
+	primary           LatestVersionStore
+	snapshots         LatestVersionStore
+	snapshotPublisher LatestVersionPublisher
+	embedded          LatestVersionStore
+
+	lock              sync.Mutex
+	lastSnapshotETags map[string]string
+}
+
+// snapshotObjects may be nil, in which case no snapshots are kept.
+func NewFallbackLatestVersionStore(primary LatestVersionStore, snapshotObjects ObjectStore, embeddedObjects ObjectStore) LatestVersionStore {
+	store := &fallbackLatestVersionStore{
+		primary:           primary,
+		embedded:          NewLatestVersionStore(embeddedObjects),
+		lastSnapshotETags: map[string]string{},
+	}
+
+	if snapshotObjects != nil {
+		store.snapshots = NewLatestVersionStore(snapshotObjects)
+		store.snapshotPublisher = NewLatestVersionPublisher(snapshotObjects)
+	}
+
+	return store
+}
+
+func (f *fallbackLatestVersionStore) GetLatestVersionDescriptor(ctx context.Context, channel string) (VersionDescriptor, error) {
+	descriptor, err := f.primary.GetLatestVersionDescriptor(ctx, channel)
+
+	if err == nil {
+		f.saveSnapshot(ctx, channel, descriptor)
+		descriptor.Source = DescriptorSourcePrimary
+
+		return descriptor, nil
+	}
+
+	log := middleware.LoggerFromContext(ctx).WithError(err).WithField("channel", channel)
+
+	if f.snapshots != nil {
+		if snapshot, snapshotErr := f.snapshots.GetLatestVersionDescriptor(ctx, channel); snapshotErr == nil {
+			log.Warn("Getting latest version descriptor failed, serving snapshot of last known good descriptor.")
+			snapshot.Source = DescriptorSourceSnapshot
+
+			return snapshot, nil
+		}
+	}
+
+	if embedded, embeddedErr := f.embedded.GetLatestVersionDescriptor(ctx, channel); embeddedErr == nil {
+		log.Warn("Getting latest version descriptor failed and no snapshot is available, serving embedded descriptor.")
+		embedded.Source = DescriptorSourceEmbedded
+
+		return embedded, nil
+	}
+
+	return VersionDescriptor{}, fmt.Errorf("could not get latest version descriptor from any source: %w", err)
+}
+
+// saveSnapshot only writes a snapshot when the descriptor has changed since the last snapshot, as it is called for every successful read.
+func (f *fallbackLatestVersionStore) saveSnapshot(ctx context.Context, channel string, descriptor VersionDescriptor) {
+	if f.snapshotPublisher == nil {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if descriptor.ETag != "" && f.lastSnapshotETags[channel] == descriptor.ETag {
+		return
+	}
+
+	if _, err := f.snapshotPublisher.PublishLatestVersionDescriptor(ctx, channel, descriptor.Info, Precondition{}); err != nil {
+		log := middleware.LoggerFromContext(ctx).WithError(err).WithField("channel", channel)
+		log.Warn("Saving snapshot of latest version descriptor failed.")
+
+		return
+	}
+
+	f.lastSnapshotETags[channel] = descriptor.ETag
+}
@@ -0,0 +1,181 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/batect/services-common/middleware/testutils"
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("Falling back to other sources for the latest version descriptor", func() {
+	var primary *fakeLatestVersionStore
+	var snapshotObjects storage.ObjectStore
+	var embeddedObjects storage.ObjectStore
+	var ctx context.Context
+	var hook *test.Hook
+
+	primaryDescriptor := storage.VersionDescriptor{Info: descriptorInfo("2.0.0"), ETag: `"2"`}
+
+	BeforeEach(func() {
+		primary = &fakeLatestVersionStore{}
+		snapshotObjects = storage.NewInMemoryObjectStore(nil)
+		embeddedObjects = storage.NewInMemoryObjectStore(map[string]storage.Object{
+			"v1/latest.json": {Content: []byte(`{"version": "1.0.0", "url": "https://github.com/batect/batect/releases/tag/1.0.0", "files": [{"type": "script", "name": "batect", "url": "https://github.com/batect/batect/releases/download/1.0.0/batect"}]}`), Generation: 1},
+		})
+
+		ctx, hook = testutils.ContextWithTestLogger(context.Background())
+	})
+
+	Context("given the primary store returns a descriptor", func() {
+		var store storage.LatestVersionStore
+
+		BeforeEach(func() {
+			primary.Set("stable", primaryDescriptor, nil)
+			store = storage.NewFallbackLatestVersionStore(primary, snapshotObjects, embeddedObjects)
+		})
+
+		It("returns the descriptor from the primary store", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Info).To(Equal(primaryDescriptor.Info))
+			Expect(descriptor.ETag).To(Equal(primaryDescriptor.ETag))
+			Expect(descriptor.Source).To(Equal(storage.DescriptorSourcePrimary))
+		})
+
+		It("saves a snapshot of the descriptor", func() {
+			_, err := store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+
+			snapshot, err := storage.NewLatestVersionStore(snapshotObjects).GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshot.Info).To(Equal(primaryDescriptor.Info))
+		})
+
+		It("only saves a new snapshot when the descriptor changes", func() {
+			_, err := store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+			first, err := snapshotObjects.GetObject(ctx, "v1/latest.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+			second, err := snapshotObjects.GetObject(ctx, "v1/latest.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second.Generation).To(Equal(first.Generation))
+
+			primary.Set("stable", storage.VersionDescriptor{Info: descriptorInfo("3.0.0"), ETag: `"3"`}, nil)
+			_, err = store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+			third, err := snapshotObjects.GetObject(ctx, "v1/latest.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(third.Generation).ToNot(Equal(first.Generation))
+		})
+	})
+
+	Context("given the primary store fails after a descriptor has been read from it", func() {
+		var store storage.LatestVersionStore
+
+		BeforeEach(func() {
+			store = storage.NewFallbackLatestVersionStore(primary, snapshotObjects, embeddedObjects)
+
+			primary.Set("stable", primaryDescriptor, nil)
+			_, err := store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+
+			primary.Set("stable", storage.VersionDescriptor{}, errors.New("something went wrong"))
+		})
+
+		It("returns the snapshot of the last descriptor read from the primary store", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Info).To(Equal(primaryDescriptor.Info))
+			Expect(descriptor.Source).To(Equal(storage.DescriptorSourceSnapshot))
+		})
+
+		It("logs a warning", func() {
+			_, _ = store.GetLatestVersionDescriptor(ctx, "stable")
+
+			Expect(hook.LastEntry().Level).To(Equal(logrus.WarnLevel))
+			Expect(hook.LastEntry().Message).To(Equal("Getting latest version descriptor failed, serving snapshot of last known good descriptor."))
+		})
+	})
+
+	Context("given the primary store fails and there is no snapshot", func() {
+		var store storage.LatestVersionStore
+
+		BeforeEach(func() {
+			primary.Set("stable", storage.VersionDescriptor{}, errors.New("something went wrong"))
+			store = storage.NewFallbackLatestVersionStore(primary, snapshotObjects, embeddedObjects)
+		})
+
+		It("returns the embedded descriptor", func() {
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Info).To(Equal(descriptorInfo("1.0.0")))
+			Expect(descriptor.Source).To(Equal(storage.DescriptorSourceEmbedded))
+		})
+
+		It("logs a warning", func() {
+			_, _ = store.GetLatestVersionDescriptor(ctx, "stable")
+
+			Expect(hook.LastEntry().Level).To(Equal(logrus.WarnLevel))
+			Expect(hook.LastEntry().Message).To(Equal("Getting latest version descriptor failed and no snapshot is available, serving embedded descriptor."))
+		})
+	})
+
+	Context("given the primary store fails and snapshots are disabled", func() {
+		It("returns the embedded descriptor", func() {
+			primary.Set("stable", storage.VersionDescriptor{}, errors.New("something went wrong"))
+			store := storage.NewFallbackLatestVersionStore(primary, nil, embeddedObjects)
+
+			descriptor, err := store.GetLatestVersionDescriptor(ctx, "stable")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(descriptor.Source).To(Equal(storage.DescriptorSourceEmbedded))
+		})
+	})
+
+	Context("given every source fails", func() {
+		It("returns the error from the primary store", func() {
+			primary.Set("beta", storage.VersionDescriptor{}, errors.New("something went wrong"))
+			store := storage.NewFallbackLatestVersionStore(primary, snapshotObjects, embeddedObjects)
+
+			_, err := store.GetLatestVersionDescriptor(ctx, "beta")
+			Expect(err).To(MatchError("could not get latest version descriptor from any source: something went wrong"))
+		})
+	})
+})
+
+func descriptorInfo(version string) storage.VersionInfo {
+	return storage.VersionInfo{
+		Version: semver.MustParse(version),
+		URL:     "https://github.com/batect/batect/releases/tag/" + version,
+		Files: []storage.VersionFile{
+			{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/" + version + "/batect"},
+		},
+	}
+}
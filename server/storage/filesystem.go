@@ -0,0 +1,255 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type filesystemObjectStore struct {
+	directory string
+
+	// Preconditions are only enforced between writers in this process, which is enough for single-instance deployments.
+	writeLock sync.Mutex
+}
+
+// NewFilesystemObjectStore returns a store that reads objects from files in directory, using the same layout as a Cloud Storage bucket
+// (eg. the object 'v1/latest.json' is read from the file 'v1/latest.json' in directory).
+//
+// Files are read on every call, so changes made to them are picked up immediately.
+func NewFilesystemObjectStore(directory string) ObjectStore {
+	return &filesystemObjectStore{
+		directory: directory,
+	}
+}
+
+func (f *filesystemObjectStore) GetObject(_ context.Context, name string) (Object, error) {
+	filePath, err := f.pathFor(name)
+
+	if err != nil {
+		return Object{}, err
+	}
+
+	file, err := os.Open(filePath)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return Object{}, newObjectNotFoundError(name, err)
+	}
+
+	if err != nil {
+		return Object{}, fmt.Errorf("could not open file: %w", err)
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	if err != nil {
+		return Object{}, fmt.Errorf("could not get file information: %w", err)
+	}
+
+	content, err := io.ReadAll(file)
+
+	if err != nil {
+		return Object{}, fmt.Errorf("could not read file content: %w", err)
+	}
+
+	object := Object{
+		Content:      content,
+		ContentType:  contentTypeForFile(filePath),
+		Generation:   info.ModTime().UnixNano(),
+		LastModified: info.ModTime().UTC(),
+	}
+
+	return object, nil
+}
+
+func (f *filesystemObjectStore) GetObjectGeneration(_ context.Context, name string) (int64, error) {
+	filePath, err := f.pathFor(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(filePath)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, newObjectNotFoundError(name, err)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("could not get file information: %w", err)
+	}
+
+	return info.ModTime().UnixNano(), nil
+}
+
+func (f *filesystemObjectStore) PutObject(ctx context.Context, name string, content []byte, contentType string, precondition Precondition) (Object, error) {
+	object, err := f.PutObjectFromReader(ctx, name, bytes.NewReader(content), contentType, precondition)
+
+	if err != nil {
+		return Object{}, err
+	}
+
+	object.Content = content
+
+	return object, nil
+}
+
+func (f *filesystemObjectStore) OpenObject(_ context.Context, name string) (Object, io.ReadSeekCloser, error) {
+	filePath, err := f.pathFor(name)
+
+	if err != nil {
+		return Object{}, nil, err
+	}
+
+	file, err := os.Open(filePath)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return Object{}, nil, newObjectNotFoundError(name, err)
+	}
+
+	if err != nil {
+		return Object{}, nil, fmt.Errorf("could not open file: %w", err)
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		_ = file.Close()
+
+		return Object{}, nil, fmt.Errorf("could not get file information: %w", err)
+	}
+
+	object := Object{
+		ContentType:  contentTypeForFile(filePath),
+		Generation:   info.ModTime().UnixNano(),
+		LastModified: info.ModTime().UTC(),
+	}
+
+	return object, file, nil
+}
+
+// Writing to a temporary file first means readers never see a partially written file.
+func (f *filesystemObjectStore) PutObjectFromReader(_ context.Context, name string, content io.Reader, _ string, precondition Precondition) (Object, error) {
+	filePath, err := f.pathFor(name)
+
+	if err != nil {
+		return Object{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return Object{}, fmt.Errorf("could not create directory: %w", err)
+	}
+
+	temporaryFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+
+	if err != nil {
+		return Object{}, fmt.Errorf("could not create temporary file: %w", err)
+	}
+
+	defer os.Remove(temporaryFile.Name())
+
+	if _, err := io.Copy(temporaryFile, content); err != nil {
+		_ = temporaryFile.Close()
+
+		return Object{}, fmt.Errorf("could not write temporary file: %w", err)
+	}
+
+	if err := temporaryFile.Close(); err != nil {
+		return Object{}, fmt.Errorf("could not close temporary file: %w", err)
+	}
+
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	if err := checkFilePrecondition(filePath, name, precondition); err != nil {
+		return Object{}, err
+	}
+
+	if err := os.Rename(temporaryFile.Name(), filePath); err != nil {
+		return Object{}, fmt.Errorf("could not move temporary file into place: %w", err)
+	}
+
+	info, err := os.Stat(filePath)
+
+	if err != nil {
+		return Object{}, fmt.Errorf("could not get file information: %w", err)
+	}
+
+	object := Object{
+		ContentType:  contentTypeForFile(filePath),
+		Generation:   info.ModTime().UnixNano(),
+		LastModified: info.ModTime().UTC(),
+	}
+
+	return object, nil
+}
+
+func checkFilePrecondition(filePath string, name string, precondition Precondition) error {
+	if precondition == (Precondition{}) {
+		return nil
+	}
+
+	info, err := os.Stat(filePath)
+	exists := err == nil
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not get file information: %w", err)
+	}
+
+	if precondition.DoesNotExist && exists {
+		return newPreconditionFailedError(name, precondition)
+	}
+
+	if precondition.GenerationMatch != 0 && (!exists || info.ModTime().UnixNano() != precondition.GenerationMatch) {
+		return newPreconditionFailedError(name, precondition)
+	}
+
+	return nil
+}
+
+func (f *filesystemObjectStore) pathFor(name string) (string, error) {
+	cleaned := path.Clean("/" + name)
+
+	if cleaned == "/" || strings.HasSuffix(name, "/") {
+		return "", fmt.Errorf("'%v' is not a valid object name", name)
+	}
+
+	return filepath.Join(f.directory, filepath.FromSlash(cleaned)), nil
+}
+
+func contentTypeForFile(filePath string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(filePath)); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
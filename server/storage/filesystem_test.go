@@ -0,0 +1,109 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Getting objects from the local filesystem", func() {
+	var directory string
+	var store storage.ObjectStore
+
+	BeforeEach(func() {
+		directory = GinkgoT().TempDir()
+		store = storage.NewFilesystemObjectStore(directory)
+	})
+
+	writeFile := func(name string, content string, modTime time.Time) {
+		path := filepath.Join(directory, filepath.FromSlash(name))
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+	}
+
+	Context("given the file does not exist", func() {
+		It("returns an error matching ErrObjectNotFound", func() {
+			_, err := store.GetObject(context.Background(), "v1/latest.json")
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+	})
+
+	Context("given the file exists", func() {
+		modTime := time.Date(2021, 3, 1, 9, 54, 40, 0, time.UTC)
+
+		BeforeEach(func() {
+			writeFile("v1/latest.json", `{"some":"descriptor"}`, modTime)
+		})
+
+		It("returns the content and details of the file", func() {
+			Expect(store.GetObject(context.Background(), "v1/latest.json")).To(Equal(storage.Object{
+				Content:      []byte(`{"some":"descriptor"}`),
+				ContentType:  "application/json",
+				Generation:   modTime.UnixNano(),
+				LastModified: modTime,
+			}))
+		})
+
+		Context("when the file is modified", func() {
+			newModTime := time.Date(2021, 3, 2, 10, 0, 0, 0, time.UTC)
+
+			BeforeEach(func() {
+				_, err := store.GetObject(context.Background(), "v1/latest.json")
+				Expect(err).ToNot(HaveOccurred())
+
+				writeFile("v1/latest.json", `{"some":"other descriptor"}`, newModTime)
+			})
+
+			It("returns the updated content and details of the file", func() {
+				Expect(store.GetObject(context.Background(), "v1/latest.json")).To(Equal(storage.Object{
+					Content:      []byte(`{"some":"other descriptor"}`),
+					ContentType:  "application/json",
+					Generation:   newModTime.UnixNano(),
+					LastModified: newModTime,
+				}))
+			})
+		})
+	})
+
+	Context("given an object name that attempts to escape the directory", func() {
+		BeforeEach(func() {
+			writeFile("v1/latest.json", `{"some":"descriptor"}`, time.Now())
+		})
+
+		It("does not read files outside the directory", func() {
+			object, err := store.GetObject(context.Background(), "../"+filepath.Base(directory)+"/v1/latest.json")
+			Expect(err).To(MatchError(os.ErrNotExist))
+			Expect(object).To(Equal(storage.Object{}))
+		})
+	})
+
+	itBehavesLikeAWritableObjectStore(func() storage.ObjectStore {
+		return storage.NewFilesystemObjectStore(GinkgoT().TempDir())
+	})
+})
@@ -0,0 +1,121 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const maxDescriptorHistoryLength = 50
+
+const maxDescriptorHistoryWriteAttempts = 5
+
+type descriptorHistoryStore struct {
+	objects ObjectStore
+}
+
+func NewDescriptorHistoryStore(objects ObjectStore) DescriptorHistoryStore {
+	return &descriptorHistoryStore{
+		objects: objects,
+	}
+}
+
+type descriptorHistoryDocument struct {
+	History []HistoricalDescriptor `json:"history"`
+}
+
+func (s *descriptorHistoryStore) GetDescriptorHistory(ctx context.Context, channel string) ([]HistoricalDescriptor, error) {
+	document, _, err := s.read(ctx, channel)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get descriptor history for channel '%v': %w", channel, err)
+	}
+
+	return document.History, nil
+}
+
+func (s *descriptorHistoryStore) AddToDescriptorHistory(ctx context.Context, channel string, entry HistoricalDescriptor) error {
+	for attempt := 1; ; attempt++ {
+		err := s.tryAdd(ctx, channel, entry)
+
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, ErrPreconditionFailed) || attempt == maxDescriptorHistoryWriteAttempts {
+			return fmt.Errorf("could not add to descriptor history for channel '%v': %w", channel, err)
+		}
+	}
+}
+
+func (s *descriptorHistoryStore) tryAdd(ctx context.Context, channel string, entry HistoricalDescriptor) error {
+	document, generation, err := s.read(ctx, channel)
+
+	if err != nil {
+		return err
+	}
+
+	document.History = append([]HistoricalDescriptor{entry}, document.History...)
+
+	if len(document.History) > maxDescriptorHistoryLength {
+		document.History = document.History[:maxDescriptorHistoryLength]
+	}
+
+	content, err := json.MarshalIndent(document, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.objects.PutObject(ctx, descriptorHistoryObjectName(channel), content, "application/json", PreconditionForGeneration(generation))
+
+	return err
+}
+
+func (s *descriptorHistoryStore) read(ctx context.Context, channel string) (descriptorHistoryDocument, int64, error) {
+	object, err := s.objects.GetObject(ctx, descriptorHistoryObjectName(channel))
+
+	if errors.Is(err, ErrObjectNotFound) {
+		return descriptorHistoryDocument{History: []HistoricalDescriptor{}}, 0, nil
+	}
+
+	if err != nil {
+		return descriptorHistoryDocument{}, 0, err
+	}
+
+	var document descriptorHistoryDocument
+
+	if err := json.Unmarshal(object.Content, &document); err != nil {
+		return descriptorHistoryDocument{}, 0, fmt.Errorf("could not parse descriptor history: %w", err)
+	}
+
+	if document.History == nil {
+		document.History = []HistoricalDescriptor{}
+	}
+
+	return document, object.Generation, nil
+}
+
+func descriptorHistoryObjectName(channel string) string {
+	return "admin/channels/" + channel + "/history.json"
+}
@@ -0,0 +1,109 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recording the descriptor history", func() {
+	var objects storage.ObjectStore
+	var store storage.DescriptorHistoryStore
+	ctx := context.Background()
+
+	entryFor := func(version string, generation int64) storage.HistoricalDescriptor {
+		return storage.HistoricalDescriptor{
+			Info: storage.VersionInfo{
+				Version: semver.MustParse(version),
+				URL:     "https://github.com/batect/batect/releases/tag/" + version,
+				Files:   []storage.VersionFile{{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/" + version + "/batect"}},
+			},
+			Generation: generation,
+			ReplacedAt: time.Date(2021, 3, 1, 9, 54, 40, 0, time.UTC),
+			ReplacedBy: "alice",
+		}
+	}
+
+	BeforeEach(func() {
+		objects = storage.NewInMemoryObjectStore(nil)
+		store = storage.NewDescriptorHistoryStore(objects)
+	})
+
+	Context("given nothing has been replaced in the channel", func() {
+		It("returns an empty history", func() {
+			Expect(store.GetDescriptorHistory(ctx, "beta")).To(BeEmpty())
+		})
+	})
+
+	Context("given descriptors have been replaced in the channel", func() {
+		BeforeEach(func() {
+			Expect(store.AddToDescriptorHistory(ctx, "beta", entryFor("0.83.0", 1))).To(Succeed())
+			Expect(store.AddToDescriptorHistory(ctx, "beta", entryFor("0.83.1", 2))).To(Succeed())
+		})
+
+		It("returns the most recently replaced descriptor first", func() {
+			Expect(store.GetDescriptorHistory(ctx, "beta")).To(Equal([]storage.HistoricalDescriptor{entryFor("0.83.1", 2), entryFor("0.83.0", 1)}))
+		})
+
+		It("does not include them in the history of other channels", func() {
+			Expect(store.GetDescriptorHistory(ctx, "stable")).To(BeEmpty())
+		})
+	})
+
+	Context("given more descriptors have been replaced than are retained", func() {
+		BeforeEach(func() {
+			for i := 0; i < 60; i++ {
+				Expect(store.AddToDescriptorHistory(ctx, "beta", entryFor(fmt.Sprintf("0.%v.0", i), int64(i+1)))).To(Succeed())
+			}
+		})
+
+		It("retains only the most recently replaced descriptors", func() {
+			history, err := store.GetDescriptorHistory(ctx, "beta")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(HaveLen(50))
+			Expect(history[0]).To(Equal(entryFor("0.59.0", 60)))
+			Expect(history[49]).To(Equal(entryFor("0.10.0", 11)))
+		})
+	})
+
+	Context("given the history is invalid", func() {
+		BeforeEach(func() {
+			_, err := objects.PutObject(ctx, "admin/channels/beta/history.json", []byte(`not JSON`), "application/json", storage.Precondition{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error when getting the history", func() {
+			_, err := store.GetDescriptorHistory(ctx, "beta")
+			Expect(err).To(MatchError(ContainSubstring("could not get descriptor history for channel 'beta': could not parse descriptor history")))
+		})
+
+		It("returns an error when adding to the history, rather than replacing it", func() {
+			err := store.AddToDescriptorHistory(ctx, "beta", entryFor("0.83.0", 1))
+			Expect(err).To(MatchError(ContainSubstring("could not add to descriptor history for channel 'beta': could not parse descriptor history")))
+		})
+	})
+})
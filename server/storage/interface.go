@@ -19,13 +19,239 @@
 
 package storage
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
 
-type LatestVersionStore interface {
-	GetLatestVersionDescriptor(ctx context.Context) (VersionDescriptor, error)
+	"github.com/batect/updates.batect.dev/server/semver"
+)
+
+type ObjectStore interface {
+	GetObject(ctx context.Context, name string) (Object, error)
+
+	GetObjectGeneration(ctx context.Context, name string) (int64, error)
+
+	PutObject(ctx context.Context, name string, content []byte, contentType string, precondition Precondition) (Object, error)
+
+	// The reader returned by OpenObject must be closed.
+	OpenObject(ctx context.Context, name string) (Object, io.ReadSeekCloser, error)
+
+	// Nothing is stored if reading from content fails.
+	PutObjectFromReader(ctx context.Context, name string, content io.Reader, contentType string, precondition Precondition) (Object, error)
 }
 
-type VersionDescriptor struct {
+// The zero value places no restrictions on the write.
+type Precondition struct {
+	DoesNotExist bool
+
+	// GenerationMatch is ignored if it is zero.
+	GenerationMatch int64
+}
+
+// A generation of zero means the object must not exist.
+func PreconditionForGeneration(generation int64) Precondition {
+	if generation == 0 {
+		return Precondition{DoesNotExist: true}
+	}
+
+	return Precondition{GenerationMatch: generation}
+}
+
+type Object struct {
 	Content     []byte
 	ContentType string
+
+	// Generation identifies this version of the object, and changes whenever the object is modified.
+	Generation int64
+
+	LastModified time.Time
+}
+
+type LatestVersionStore interface {
+	GetLatestVersionDescriptor(ctx context.Context, channel string) (VersionDescriptor, error)
+}
+
+type LatestVersionRefresher interface {
+	RefreshLatestVersionDescriptor(ctx context.Context, channel string) error
+}
+
+type CachingLatestVersionStore interface {
+	LatestVersionStore
+	LatestVersionRefresher
+}
+
+// LatestVersionGenerationStore doesn't parse the descriptor, so that an invalid descriptor can still be replaced.
+type LatestVersionGenerationStore interface {
+	GetLatestVersionDescriptorGeneration(ctx context.Context, channel string) (int64, error)
 }
+
+type LatestVersionPublisher interface {
+	PublishLatestVersionDescriptor(ctx context.Context, channel string, info VersionInfo, precondition Precondition) (VersionDescriptor, error)
+}
+
+type VersionDescriptor struct {
+	Info VersionInfo
+
+	// ETag is quoted, ready for use in a HTTP ETag header.
+	ETag         string
+	LastModified time.Time
+
+	// Generation is the generation of the object the descriptor was read from.
+	Generation int64
+
+	// Staleness is how long it has been since this descriptor was last confirmed to be current.
+	// It is zero unless the descriptor was served from a cache that has been unable to refresh it.
+	Staleness time.Duration
+
+	// Source is empty if the descriptor was not served by a fallback chain.
+	Source DescriptorSource
+}
+
+type ReleaseHistoryStore interface {
+	// GetReleases returns every published release, newest first.
+	GetReleases(ctx context.Context) ([]Release, error)
+}
+
+type ReleaseHistoryUpdater interface {
+	// UpdateReleaseHistory returns false if update didn't change the release history, in which case nothing is written.
+	UpdateReleaseHistory(ctx context.Context, update func(releases []Release) []Release) (bool, error)
+}
+
+type Release struct {
+	Version     semver.Version `json:"version"`
+	ReleaseDate time.Time      `json:"releaseDate"`
+	URL         string         `json:"url"`
+	Status      ReleaseStatus  `json:"status"`
+
+	// Notes are in Markdown.
+	Notes string `json:"notes,omitempty"`
+}
+
+type ReleaseStatus string
+
+const (
+	ReleaseStatusCurrent    ReleaseStatus = "current"
+	ReleaseStatusDeprecated ReleaseStatus = "deprecated"
+	ReleaseStatusYanked     ReleaseStatus = "yanked"
+)
+
+type DescriptorHistoryStore interface {
+	// GetDescriptorHistory returns the most recently replaced descriptor first.
+	GetDescriptorHistory(ctx context.Context, channel string) ([]HistoricalDescriptor, error)
+
+	AddToDescriptorHistory(ctx context.Context, channel string, entry HistoricalDescriptor) error
+}
+
+type HistoricalDescriptor struct {
+	Info VersionInfo `json:"descriptor"`
+
+	Generation int64     `json:"generation"`
+	ReplacedAt time.Time `json:"replacedAt"`
+	ReplacedBy string    `json:"replacedBy"`
+}
+
+type AuditTrail interface {
+	RecordAdminAction(ctx context.Context, action AdminAction) error
+}
+
+type AdminAction struct {
+	Actor   string          `json:"actor"`
+	Action  AdminActionType `json:"action"`
+	Channel string          `json:"channel"`
+	Version string          `json:"version"`
+
+	// Generation is zero if nothing was published.
+	Generation int64 `json:"generation,omitempty"`
+
+	PreviousVersion    string `json:"previousVersion,omitempty"`
+	PreviousGeneration int64  `json:"previousGeneration,omitempty"`
+}
+
+type AdminActionType string
+
+const (
+	AdminActionPublish  AdminActionType = "publish"
+	AdminActionPreview  AdminActionType = "preview"
+	AdminActionRollback AdminActionType = "rollback"
+)
+
+type ReleaseManifestStore interface {
+	GetReleaseManifest(ctx context.Context, version string) (ReleaseManifest, error)
+}
+
+type ReleaseManifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Name string `json:"name"`
+
+	// SHA256 and SHA512 are lowercase hex.
+	SHA256 string `json:"sha256"`
+	SHA512 string `json:"sha512"`
+
+	// Signature is an ASCII-armored detached OpenPGP signature.
+	Signature string `json:"signature,omitempty"`
+
+	// Files with a Kind are the release's downloadable artifacts.
+	Kind           string `json:"kind,omitempty"`
+	Platform       string `json:"platform,omitempty"`
+	RedirectTarget string `json:"redirectTarget,omitempty"`
+}
+
+// The Content of an artifact returned by GetArtifact must be closed.
+type ArtifactCache interface {
+	GetArtifact(ctx context.Context, version string, fileName string) (Artifact, error)
+	PutArtifact(ctx context.Context, version string, fileName string, content io.Reader, contentType string) error
+}
+
+type Artifact struct {
+	Content      io.ReadSeekCloser
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+type YankedVersionStore interface {
+	GetYankedVersions(ctx context.Context) ([]YankedVersion, error)
+}
+
+type YankedVersion struct {
+	Version     semver.Version  `json:"version"`
+	Reason      string          `json:"reason"`
+	Replacement *semver.Version `json:"replacement,omitempty"`
+}
+
+type AdvisoryStore interface {
+	GetAdvisories(ctx context.Context) ([]Advisory, error)
+}
+
+type Advisory struct {
+	ID               string           `json:"id"`
+	Type             AdvisoryType     `json:"type"`
+	Severity         AdvisorySeverity `json:"severity"`
+	Summary          string           `json:"summary"`
+	AffectedVersions semver.Range     `json:"affectedVersions"`
+
+	// FixedIn is nil if no fix has been released yet.
+	FixedIn *semver.Version `json:"fixedIn"`
+
+	URL string `json:"url"`
+}
+
+type AdvisoryType string
+
+const (
+	AdvisoryTypeSecurity AdvisoryType = "security"
+	AdvisoryTypeBug      AdvisoryType = "bug"
+)
+
+type AdvisorySeverity string
+
+const (
+	AdvisorySeverityLow      AdvisorySeverity = "low"
+	AdvisorySeverityMedium   AdvisorySeverity = "medium"
+	AdvisorySeverityHigh     AdvisorySeverity = "high"
+	AdvisorySeverityCritical AdvisorySeverity = "critical"
+)
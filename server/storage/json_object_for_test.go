@@ -0,0 +1,42 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func jsonObject(name string, content string) storage.ObjectStore {
+	return storage.NewInMemoryObjectStore(map[string]storage.Object{
+		name: {Content: []byte(content), ContentType: "application/json"},
+	})
+}
+
+// itRejectsInvalidContent describes a store that validates a JSON object: read reads content with the store, and each entry gives the
+// content and part of the error expected for it.
+func itRejectsInvalidContent(description string, read func(content string) error, entries ...interface{}) {
+	body := func(content string, expectedError string) {
+		Expect(read(content)).To(MatchError(ContainSubstring(expectedError)))
+	}
+
+	DescribeTable(description, append([]interface{}{body}, entries...)...)
+}
@@ -0,0 +1,134 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StableChannel is the default release channel, and the only channel that existed before channels were introduced.
+const StableChannel = "stable"
+
+type latestVersionStore struct {
+	objects ObjectStore
+}
+
+func NewLatestVersionStore(objects ObjectStore) LatestVersionStore {
+	return &latestVersionStore{
+		objects: objects,
+	}
+}
+
+func (s *latestVersionStore) GetLatestVersionDescriptor(ctx context.Context, channel string) (VersionDescriptor, error) {
+	object, err := s.objects.GetObject(ctx, latestVersionDescriptorObjectName(channel))
+
+	if err != nil {
+		return VersionDescriptor{}, fmt.Errorf("could not get latest version descriptor for channel '%v': %w", channel, err)
+	}
+
+	info, err := ParseVersionInfo(object.Content)
+
+	if err != nil {
+		return VersionDescriptor{}, fmt.Errorf("could not get latest version descriptor for channel '%v': %w", channel, err)
+	}
+
+	return newVersionDescriptor(info, object), nil
+}
+
+func NewLatestVersionGenerationStore(objects ObjectStore) LatestVersionGenerationStore {
+	return &latestVersionStore{
+		objects: objects,
+	}
+}
+
+func (s *latestVersionStore) GetLatestVersionDescriptorGeneration(ctx context.Context, channel string) (int64, error) {
+	generation, err := s.objects.GetObjectGeneration(ctx, latestVersionDescriptorObjectName(channel))
+
+	if err != nil {
+		return 0, fmt.Errorf("could not get latest version descriptor generation for channel '%v': %w", channel, err)
+	}
+
+	return generation, nil
+}
+
+func NewLatestVersionPublisher(objects ObjectStore) LatestVersionPublisher {
+	return &latestVersionStore{
+		objects: objects,
+	}
+}
+
+func (s *latestVersionStore) PublishLatestVersionDescriptor(ctx context.Context, channel string, info VersionInfo, precondition Precondition) (VersionDescriptor, error) {
+	if err := info.Validate(); err != nil {
+		return VersionDescriptor{}, fmt.Errorf("could not publish latest version descriptor for channel '%v': %w: %v", channel, ErrInvalidVersionDescriptor, err)
+	}
+
+	content, err := json.MarshalIndent(info, "", "  ")
+
+	if err != nil {
+		return VersionDescriptor{}, fmt.Errorf("could not publish latest version descriptor for channel '%v': %w", channel, err)
+	}
+
+	object, err := s.objects.PutObject(ctx, latestVersionDescriptorObjectName(channel), content, "application/json", precondition)
+
+	if err != nil {
+		return VersionDescriptor{}, fmt.Errorf("could not publish latest version descriptor for channel '%v': %w", channel, err)
+	}
+
+	return newVersionDescriptor(info, object), nil
+}
+
+func newVersionDescriptor(info VersionInfo, object Object) VersionDescriptor {
+	return VersionDescriptor{
+		Info:         info,
+		ETag:         fmt.Sprintf(`"%v"`, object.Generation),
+		LastModified: object.LastModified,
+		Generation:   object.Generation,
+	}
+}
+
+// The descriptor for the stable channel remains at its original location so that existing tooling that publishes it continues to work.
+func latestVersionDescriptorObjectName(channel string) string {
+	if channel == StableChannel {
+		return "v1/latest.json"
+	}
+
+	return "v1/channels/" + channel + "/latest.json"
+}
+
+func ChannelForLatestVersionDescriptorObjectName(name string) (string, bool) {
+	if name == latestVersionDescriptorObjectName(StableChannel) {
+		return StableChannel, true
+	}
+
+	if !strings.HasPrefix(name, "v1/channels/") || !strings.HasSuffix(name, "/latest.json") {
+		return "", false
+	}
+
+	channel := strings.TrimSuffix(strings.TrimPrefix(name, "v1/channels/"), "/latest.json")
+
+	if channel == "" || channel == StableChannel || strings.Contains(channel, "/") {
+		return "", false
+	}
+
+	return channel, true
+}
@@ -0,0 +1,203 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Getting the latest version descriptor", func() {
+	Context("given the descriptor does not exist", func() {
+		var store storage.LatestVersionStore
+
+		BeforeEach(func() {
+			store = storage.NewLatestVersionStore(storage.NewInMemoryObjectStore(nil))
+		})
+
+		It("returns an appropriate error", func() {
+			_, err := store.GetLatestVersionDescriptor(context.Background(), "stable")
+			Expect(err).To(MatchError("could not get latest version descriptor for channel 'stable': object 'v1/latest.json' does not exist"))
+		})
+	})
+
+	Context("given the descriptor is invalid", func() {
+		var store storage.LatestVersionStore
+
+		BeforeEach(func() {
+			store = storage.NewLatestVersionStore(storage.NewInMemoryObjectStore(map[string]storage.Object{
+				"v1/latest.json": {Content: []byte(`{"version": "0.83.2"}`), ContentType: "application/json"},
+			}))
+		})
+
+		It("returns an error indicating that the descriptor is invalid", func() {
+			_, err := store.GetLatestVersionDescriptor(context.Background(), "stable")
+			Expect(err).To(MatchError(storage.ErrInvalidVersionDescriptor))
+			Expect(err).To(MatchError("could not get latest version descriptor for channel 'stable': version descriptor is invalid: url is invalid: value is missing"))
+		})
+	})
+
+	Context("given the descriptor exists", func() {
+		var store storage.LatestVersionStore
+		lastModified := time.Date(2021, 3, 1, 9, 54, 40, 0, time.UTC)
+
+		BeforeEach(func() {
+			store = storage.NewLatestVersionStore(storage.NewInMemoryObjectStore(map[string]storage.Object{
+				"v1/latest.json": {
+					Content:      []byte(`{"version": "0.83.2", "url": "https://github.com/batect/batect/releases/tag/0.83.2", "files": [{"type": "script", "name": "batect", "url": "https://github.com/batect/batect/releases/download/0.83.2/batect"}]}`),
+					ContentType:  "application/json",
+					Generation:   1234,
+					LastModified: lastModified,
+				},
+				"v1/channels/beta/latest.json": {
+					Content:      []byte(`{"version": "0.84.0-rc.1", "url": "https://github.com/batect/batect/releases/tag/0.84.0-rc.1", "files": [{"type": "script", "name": "batect", "url": "https://github.com/batect/batect/releases/download/0.84.0-rc.1/batect"}]}`),
+					ContentType:  "application/json",
+					Generation:   5678,
+					LastModified: lastModified,
+				},
+			}))
+		})
+
+		It("returns the descriptor for the stable channel from its original location, using the generation of the object as its entity tag", func() {
+			Expect(store.GetLatestVersionDescriptor(context.Background(), "stable")).To(Equal(storage.VersionDescriptor{
+				Info: storage.VersionInfo{
+					Version: semver.MustParse("0.83.2"),
+					URL:     "https://github.com/batect/batect/releases/tag/0.83.2",
+					Files:   []storage.VersionFile{{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.83.2/batect"}},
+				},
+				ETag:         `"1234"`,
+				LastModified: lastModified,
+				Generation:   1234,
+			}))
+		})
+
+		It("returns the descriptor for other channels from the channel's location", func() {
+			Expect(store.GetLatestVersionDescriptor(context.Background(), "beta")).To(Equal(storage.VersionDescriptor{
+				Info: storage.VersionInfo{
+					Version: semver.MustParse("0.84.0-rc.1"),
+					URL:     "https://github.com/batect/batect/releases/tag/0.84.0-rc.1",
+					Files:   []storage.VersionFile{{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.84.0-rc.1/batect"}},
+				},
+				ETag:         `"5678"`,
+				LastModified: lastModified,
+				Generation:   5678,
+			}))
+		})
+	})
+})
+
+var _ = Describe("Getting the generation of the latest version descriptor", func() {
+	var store storage.LatestVersionGenerationStore
+
+	BeforeEach(func() {
+		store = storage.NewLatestVersionGenerationStore(storage.NewInMemoryObjectStore(map[string]storage.Object{
+			"v1/channels/beta/latest.json": {Content: []byte(`{"version": "0.84.0-rc.1"}`), ContentType: "application/json", Generation: 5678},
+		}))
+	})
+
+	Context("given the descriptor exists", func() {
+		It("returns the generation of the object, even if the descriptor is invalid", func() {
+			Expect(store.GetLatestVersionDescriptorGeneration(context.Background(), "beta")).To(BeEquivalentTo(5678))
+		})
+	})
+
+	Context("given the descriptor does not exist", func() {
+		It("returns an appropriate error", func() {
+			_, err := store.GetLatestVersionDescriptorGeneration(context.Background(), "stable")
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+		})
+	})
+})
+
+var _ = Describe("Publishing the latest version descriptor", func() {
+	var objects storage.ObjectStore
+	var publisher storage.LatestVersionPublisher
+	var store storage.LatestVersionStore
+
+	info := storage.VersionInfo{
+		Version: semver.MustParse("0.84.0-rc.1"),
+		URL:     "https://github.com/batect/batect/releases/tag/0.84.0-rc.1",
+		Files:   []storage.VersionFile{{Type: "script", Name: "batect", URL: "https://github.com/batect/batect/releases/download/0.84.0-rc.1/batect"}},
+	}
+
+	BeforeEach(func() {
+		objects = storage.NewInMemoryObjectStore(nil)
+		publisher = storage.NewLatestVersionPublisher(objects)
+		store = storage.NewLatestVersionStore(objects)
+	})
+
+	Context("given the descriptor is valid and the precondition holds", func() {
+		var published storage.VersionDescriptor
+
+		BeforeEach(func() {
+			var err error
+			published, err = publisher.PublishLatestVersionDescriptor(context.Background(), "beta", info, storage.PreconditionForGeneration(0))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("writes the descriptor to the channel's location so that it can be read back", func() {
+			Expect(store.GetLatestVersionDescriptor(context.Background(), "beta")).To(Equal(published))
+			Expect(published.Info).To(Equal(info))
+		})
+
+		It("writes the descriptor as JSON", func() {
+			object, err := objects.GetObject(context.Background(), "v1/channels/beta/latest.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(object.ContentType).To(Equal("application/json"))
+		})
+	})
+
+	Context("given the precondition does not hold", func() {
+		It("returns an error indicating that the precondition failed", func() {
+			_, err := publisher.PublishLatestVersionDescriptor(context.Background(), "stable", info, storage.PreconditionForGeneration(1234))
+			Expect(err).To(MatchError(storage.ErrPreconditionFailed))
+			Expect(err).To(MatchError("could not publish latest version descriptor for channel 'stable': precondition failed: object 'v1/latest.json' is not at generation 1234"))
+		})
+	})
+
+	Context("given the descriptor is invalid", func() {
+		It("returns an error indicating that the descriptor is invalid without writing it", func() {
+			_, err := publisher.PublishLatestVersionDescriptor(context.Background(), "stable", storage.VersionInfo{Version: info.Version}, storage.Precondition{})
+			Expect(err).To(MatchError(storage.ErrInvalidVersionDescriptor))
+
+			_, err = objects.GetObject(context.Background(), "v1/latest.json")
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+		})
+	})
+})
+
+var _ = DescribeTable("Identifying the channel for a latest version descriptor object",
+	func(name string, expectedChannel string, expectedOk bool) {
+		channel, ok := storage.ChannelForLatestVersionDescriptorObjectName(name)
+		Expect(channel).To(Equal(expectedChannel))
+		Expect(ok).To(Equal(expectedOk))
+	},
+	Entry("the stable channel", "v1/latest.json", "stable", true),
+	Entry("another channel", "v1/channels/beta/latest.json", "beta", true),
+	Entry("the stable channel at the location used for other channels", "v1/channels/stable/latest.json", "", false),
+	Entry("a channel with an empty name", "v1/channels//latest.json", "", false),
+	Entry("a nested path", "v1/channels/beta/thing/latest.json", "", false),
+	Entry("another object", "v1/releases.json", "", false),
+)
@@ -0,0 +1,121 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type releaseManifestStore struct {
+	objects ObjectStore
+}
+
+func NewReleaseManifestStore(objects ObjectStore) ReleaseManifestStore {
+	return &releaseManifestStore{
+		objects: objects,
+	}
+}
+
+func (s *releaseManifestStore) GetReleaseManifest(ctx context.Context, version string) (ReleaseManifest, error) {
+	object, err := s.objects.GetObject(ctx, releaseManifestObjectName(version))
+
+	if err != nil {
+		return ReleaseManifest{}, fmt.Errorf("could not get release manifest for version %v: %w", version, err)
+	}
+
+	var manifest ReleaseManifest
+
+	if err := json.Unmarshal(object.Content, &manifest); err != nil {
+		return ReleaseManifest{}, fmt.Errorf("could not parse release manifest for version %v: %w", version, err)
+	}
+
+	seenNames := map[string]struct{}{}
+
+	for _, file := range manifest.Files {
+		if err := validateManifestFile(file); err != nil {
+			return ReleaseManifest{}, fmt.Errorf("release manifest for version %v contains an invalid file: %w", version, err)
+		}
+
+		if _, seen := seenNames[file.Name]; seen {
+			return ReleaseManifest{}, fmt.Errorf("release manifest for version %v contains more than one file named '%v'", version, file.Name)
+		}
+
+		seenNames[file.Name] = struct{}{}
+	}
+
+	return manifest, nil
+}
+
+func (m ReleaseManifest) File(name string) (ManifestFile, bool) {
+	for _, file := range m.Files {
+		if file.Name == name {
+			return file, true
+		}
+	}
+
+	return ManifestFile{}, false
+}
+
+func validateManifestFile(file ManifestFile) error {
+	if file.Name == "" {
+		return errors.New("file has no name")
+	}
+
+	if err := validateHexDigest(file.SHA256, 32); err != nil {
+		return fmt.Errorf("file '%v' has an invalid SHA-256 digest: %w", file.Name, err)
+	}
+
+	if err := validateHexDigest(file.SHA512, 64); err != nil {
+		return fmt.Errorf("file '%v' has an invalid SHA-512 digest: %w", file.Name, err)
+	}
+
+	if file.Signature != "" && !strings.HasPrefix(file.Signature, "-----BEGIN PGP SIGNATURE-----") {
+		return fmt.Errorf("file '%v' has a signature that is not an ASCII-armored OpenPGP signature", file.Name)
+	}
+
+	return nil
+}
+
+func validateHexDigest(digest string, expectedLength int) error {
+	if digest != strings.ToLower(digest) {
+		return errors.New("digest must be lowercase")
+	}
+
+	decoded, err := hex.DecodeString(digest)
+
+	if err != nil {
+		return fmt.Errorf("digest is not hex-encoded: %w", err)
+	}
+
+	if len(decoded) != expectedLength {
+		return fmt.Errorf("digest is %v bytes long, but should be %v bytes long", len(decoded), expectedLength)
+	}
+
+	return nil
+}
+
+func releaseManifestObjectName(version string) string {
+	return "v1/manifests/" + version + ".json"
+}
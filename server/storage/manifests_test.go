@@ -0,0 +1,96 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Getting release manifests", func() {
+	sha256 := strings.Repeat("ab", 32)
+	sha512 := strings.Repeat("cd", 64)
+	signature := "-----BEGIN PGP SIGNATURE-----\n\nabc123\n-----END PGP SIGNATURE-----\n"
+
+	Context("given there is no manifest for the version", func() {
+		It("returns an error matching ErrObjectNotFound", func() {
+			store := storage.NewReleaseManifestStore(storage.NewInMemoryObjectStore(nil))
+
+			_, err := store.GetReleaseManifest(context.Background(), "0.83.2")
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+		})
+	})
+
+	Context("given the manifest is valid", func() {
+		var manifest storage.ReleaseManifest
+
+		BeforeEach(func() {
+			store := storage.NewReleaseManifestStore(jsonObject("v1/manifests/0.83.2.json", `{
+				"files": [
+					{"name": "batect-0.83.2.jar", "sha256": "`+sha256+`", "sha512": "`+sha512+`", "signature": "-----BEGIN PGP SIGNATURE-----\n\nabc123\n-----END PGP SIGNATURE-----\n"},
+					{"name": "batect", "sha256": "`+sha256+`", "sha512": "`+sha512+`"}
+				]
+			}`))
+
+			var err error
+			manifest, err = store.GetReleaseManifest(context.Background(), "0.83.2")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns all files in the manifest", func() {
+			Expect(manifest.Files).To(Equal([]storage.ManifestFile{
+				{Name: "batect-0.83.2.jar", SHA256: sha256, SHA512: sha512, Signature: signature},
+				{Name: "batect", SHA256: sha256, SHA512: sha512},
+			}))
+		})
+
+		It("can find a file by name", func() {
+			file, found := manifest.File("batect")
+			Expect(found).To(BeTrue())
+			Expect(file.Name).To(Equal("batect"))
+		})
+
+		It("reports that a file not in the manifest cannot be found", func() {
+			_, found := manifest.File("batect.cmd")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	itRejectsInvalidContent("given the manifest is invalid",
+		func(content string) error {
+			_, err := storage.NewReleaseManifestStore(jsonObject("v1/manifests/0.83.2.json", content)).GetReleaseManifest(context.Background(), "0.83.2")
+			return err
+		},
+		Entry("content that is not JSON", `thing`, "could not parse release manifest for version 0.83.2"),
+		Entry("file with no name", `{"files": [{"sha256": "`+sha256+`", "sha512": "`+sha512+`"}]}`, "file has no name"),
+		Entry("missing SHA-256", `{"files": [{"name": "batect", "sha512": "`+sha512+`"}]}`, "file 'batect' has an invalid SHA-256 digest"),
+		Entry("short SHA-256", `{"files": [{"name": "batect", "sha256": "abcd", "sha512": "`+sha512+`"}]}`, "digest is 2 bytes long, but should be 32 bytes long"),
+		Entry("uppercase SHA-256", `{"files": [{"name": "batect", "sha256": "`+strings.ToUpper(sha256)+`", "sha512": "`+sha512+`"}]}`, "digest must be lowercase"),
+		Entry("SHA-512 that is not hex", `{"files": [{"name": "batect", "sha256": "`+sha256+`", "sha512": "`+strings.Repeat("zz", 64)+`"}]}`, "file 'batect' has an invalid SHA-512 digest: digest is not hex-encoded"),
+		Entry("unarmored signature", `{"files": [{"name": "batect", "sha256": "`+sha256+`", "sha512": "`+sha512+`", "signature": "abc"}]}`,
+			"file 'batect' has a signature that is not an ASCII-armored OpenPGP signature"),
+		Entry("duplicate file", `{"files": [{"name": "batect", "sha256": "`+sha256+`", "sha512": "`+sha512+`"}, {"name": "batect", "sha256": "`+sha256+`", "sha512": "`+sha512+`"}]}`,
+			"release manifest for version 0.83.2 contains more than one file named 'batect'"),
+	)
+})
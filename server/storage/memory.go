@@ -0,0 +1,133 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type inMemoryObjectStore struct {
+	lock           sync.RWMutex
+	objects        map[string]Object
+	lastGeneration int64
+}
+
+// NewInMemoryObjectStore returns a store that serves the provided objects from memory.
+func NewInMemoryObjectStore(objects map[string]Object) ObjectStore {
+	copied := make(map[string]Object, len(objects))
+	lastGeneration := int64(0)
+
+	for name, object := range objects {
+		copied[name] = object
+
+		if object.Generation > lastGeneration {
+			lastGeneration = object.Generation
+		}
+	}
+
+	return &inMemoryObjectStore{
+		objects:        copied,
+		lastGeneration: lastGeneration,
+	}
+}
+
+func (m *inMemoryObjectStore) GetObject(_ context.Context, name string) (Object, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	object, ok := m.objects[name]
+
+	if !ok {
+		return Object{}, newObjectNotFoundError(name, nil)
+	}
+
+	return object, nil
+}
+
+func (m *inMemoryObjectStore) GetObjectGeneration(ctx context.Context, name string) (int64, error) {
+	object, err := m.GetObject(ctx, name)
+
+	return object.Generation, err
+}
+
+func (m *inMemoryObjectStore) PutObject(_ context.Context, name string, content []byte, contentType string, precondition Precondition) (Object, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing, exists := m.objects[name]
+
+	if precondition.DoesNotExist && exists {
+		return Object{}, newPreconditionFailedError(name, precondition)
+	}
+
+	if precondition.GenerationMatch != 0 && (!exists || existing.Generation != precondition.GenerationMatch) {
+		return Object{}, newPreconditionFailedError(name, precondition)
+	}
+
+	m.lastGeneration++
+
+	object := Object{
+		Content:      content,
+		ContentType:  contentType,
+		Generation:   m.lastGeneration,
+		LastModified: time.Now().UTC(),
+	}
+
+	m.objects[name] = object
+
+	return object, nil
+}
+
+func (m *inMemoryObjectStore) OpenObject(ctx context.Context, name string) (Object, io.ReadSeekCloser, error) {
+	object, err := m.GetObject(ctx, name)
+
+	if err != nil {
+		return Object{}, nil, err
+	}
+
+	return object, newBytesReader(object.Content), nil
+}
+
+func (m *inMemoryObjectStore) PutObjectFromReader(ctx context.Context, name string, content io.Reader, contentType string, precondition Precondition) (Object, error) {
+	data, err := io.ReadAll(content)
+
+	if err != nil {
+		return Object{}, fmt.Errorf("could not read content: %w", err)
+	}
+
+	return m.PutObject(ctx, name, data, contentType, precondition)
+}
+
+type bytesReader struct {
+	*bytes.Reader
+}
+
+func newBytesReader(content []byte) io.ReadSeekCloser {
+	return bytesReader{bytes.NewReader(content)}
+}
+
+func (bytesReader) Close() error {
+	return nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storing objects in memory", func() {
+	It("returns an error matching ErrObjectNotFound when getting an object that does not exist", func() {
+		_, err := storage.NewInMemoryObjectStore(nil).GetObject(context.Background(), "v1/thing.json")
+		Expect(err).To(MatchError(storage.ErrObjectNotFound))
+		Expect(err).To(MatchError("object 'v1/thing.json' does not exist"))
+	})
+
+	itBehavesLikeAWritableObjectStore(func() storage.ObjectStore {
+		return storage.NewInMemoryObjectStore(nil)
+	})
+})
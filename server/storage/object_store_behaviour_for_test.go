@@ -0,0 +1,147 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing/iotest"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// itBehavesLikeAWritableObjectStore describes the behaviour expected of all ObjectStore implementations when writing objects.
+func itBehavesLikeAWritableObjectStore(createStore func() storage.ObjectStore) {
+	Describe("writing objects", func() {
+		var store storage.ObjectStore
+		ctx := context.Background()
+
+		BeforeEach(func() {
+			store = createStore()
+		})
+
+		Context("given the object does not exist", func() {
+			It("stores the object when no precondition is provided", func() {
+				written, err := store.PutObject(ctx, "v1/thing.json", []byte(`{"some":"thing"}`), "application/json", storage.Precondition{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(written.Generation).ToNot(BeZero())
+
+				read, err := store.GetObject(ctx, "v1/thing.json")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(read.Content).To(Equal([]byte(`{"some":"thing"}`)))
+				Expect(read.ContentType).To(Equal("application/json"))
+				Expect(read.Generation).To(Equal(written.Generation))
+			})
+
+			It("stores the object when the precondition requires that it does not exist", func() {
+				_, err := store.PutObject(ctx, "v1/thing.json", []byte(`{"some":"thing"}`), "application/json", storage.Precondition{DoesNotExist: true})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("rejects the write when the precondition requires a particular generation", func() {
+				_, err := store.PutObject(ctx, "v1/thing.json", []byte(`{"some":"thing"}`), "application/json", storage.Precondition{GenerationMatch: 1234})
+				Expect(err).To(MatchError(storage.ErrPreconditionFailed))
+			})
+
+			It("returns an error matching ErrObjectNotFound when getting its generation", func() {
+				_, err := store.GetObjectGeneration(ctx, "v1/thing.json")
+				Expect(err).To(MatchError(storage.ErrObjectNotFound))
+			})
+
+			It("returns an error matching ErrObjectNotFound when opening it", func() {
+				_, _, err := store.OpenObject(ctx, "v1/thing.json")
+				Expect(err).To(MatchError(storage.ErrObjectNotFound))
+			})
+
+			It("stores the object read from a reader", func() {
+				written, err := store.PutObjectFromReader(ctx, "v1/thing.json", strings.NewReader(`{"some":"thing"}`), "application/json", storage.Precondition{})
+				Expect(err).ToNot(HaveOccurred())
+
+				read, err := store.GetObject(ctx, "v1/thing.json")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(read.Content).To(Equal([]byte(`{"some":"thing"}`)))
+				Expect(read.Generation).To(Equal(written.Generation))
+			})
+
+			It("does not store anything when reading from the reader fails", func() {
+				content := io.MultiReader(strings.NewReader(`{"some":`), iotest.ErrReader(errors.New("connection reset")))
+				_, err := store.PutObjectFromReader(ctx, "v1/thing.json", content, "application/json", storage.Precondition{})
+				Expect(err).To(MatchError(ContainSubstring("connection reset")))
+
+				_, err = store.GetObject(ctx, "v1/thing.json")
+				Expect(err).To(MatchError(storage.ErrObjectNotFound))
+			})
+		})
+
+		Context("given the object exists", func() {
+			var existing storage.Object
+
+			BeforeEach(func() {
+				var err error
+				existing, err = store.PutObject(ctx, "v1/thing.json", []byte(`{"some":"thing"}`), "application/json", storage.Precondition{})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns its generation", func() {
+				Expect(store.GetObjectGeneration(ctx, "v1/thing.json")).To(Equal(existing.Generation))
+			})
+
+			It("opens it for reading from any position", func() {
+				object, reader, err := store.OpenObject(ctx, "v1/thing.json")
+				Expect(err).ToNot(HaveOccurred())
+				defer reader.Close()
+
+				Expect(object.Generation).To(Equal(existing.Generation))
+				Expect(io.ReadAll(reader)).To(Equal([]byte(`{"some":"thing"}`)))
+				Expect(reader.Seek(2, io.SeekStart)).To(BeEquivalentTo(2))
+				Expect(io.ReadAll(reader)).To(Equal([]byte(`some":"thing"}`)))
+				Expect(reader.Seek(0, io.SeekEnd)).To(BeEquivalentTo(16))
+			})
+
+			It("replaces the object when the precondition requires its current generation", func() {
+				written, err := store.PutObject(ctx, "v1/thing.json", []byte(`{"some":"other thing"}`), "application/json", storage.Precondition{GenerationMatch: existing.Generation})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(written.Generation).ToNot(Equal(existing.Generation))
+
+				read, err := store.GetObject(ctx, "v1/thing.json")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(read.Content).To(Equal([]byte(`{"some":"other thing"}`)))
+			})
+
+			It("rejects the write when the precondition requires a different generation", func() {
+				_, err := store.PutObject(ctx, "v1/thing.json", []byte(`{"some":"other thing"}`), "application/json", storage.Precondition{GenerationMatch: existing.Generation + 1})
+				Expect(err).To(MatchError(storage.ErrPreconditionFailed))
+
+				read, err := store.GetObject(ctx, "v1/thing.json")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(read.Content).To(Equal([]byte(`{"some":"thing"}`)))
+			})
+
+			It("rejects the write when the precondition requires that it does not exist", func() {
+				_, err := store.PutObject(ctx, "v1/thing.json", []byte(`{"some":"other thing"}`), "application/json", storage.Precondition{DoesNotExist: true})
+				Expect(err).To(MatchError(storage.ErrPreconditionFailed))
+			})
+		})
+	})
+}
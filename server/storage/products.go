@@ -0,0 +1,83 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+const DefaultProduct = "batect"
+
+type productObjectStore struct {
+	objects ObjectStore
+	prefix  string
+}
+
+// The default product's objects remain at their original locations, so that existing tooling that publishes them continues to work.
+func NewProductObjectStore(objects ObjectStore, product string) ObjectStore {
+	if product == DefaultProduct {
+		return objects
+	}
+
+	return &productObjectStore{
+		objects: objects,
+		prefix:  productObjectPrefix(product),
+	}
+}
+
+func productObjectPrefix(product string) string {
+	return "products/" + product + "/"
+}
+
+func ProductForObjectName(name string) (string, string) {
+	if !strings.HasPrefix(name, "products/") {
+		return DefaultProduct, name
+	}
+
+	product, nameWithinProduct, ok := strings.Cut(strings.TrimPrefix(name, "products/"), "/")
+
+	if !ok || product == "" {
+		return DefaultProduct, name
+	}
+
+	return product, nameWithinProduct
+}
+
+func (s *productObjectStore) GetObject(ctx context.Context, name string) (Object, error) {
+	return s.objects.GetObject(ctx, s.prefix+name)
+}
+
+func (s *productObjectStore) GetObjectGeneration(ctx context.Context, name string) (int64, error) {
+	return s.objects.GetObjectGeneration(ctx, s.prefix+name)
+}
+
+func (s *productObjectStore) PutObject(ctx context.Context, name string, content []byte, contentType string, precondition Precondition) (Object, error) {
+	return s.objects.PutObject(ctx, s.prefix+name, content, contentType, precondition)
+}
+
+func (s *productObjectStore) OpenObject(ctx context.Context, name string) (Object, io.ReadSeekCloser, error) {
+	return s.objects.OpenObject(ctx, s.prefix+name)
+}
+
+func (s *productObjectStore) PutObjectFromReader(ctx context.Context, name string, content io.Reader, contentType string, precondition Precondition) (Object, error) {
+	return s.objects.PutObjectFromReader(ctx, s.prefix+name, content, contentType, precondition)
+}
@@ -0,0 +1,94 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storing objects for a product", func() {
+	var underlying storage.ObjectStore
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		underlying = storage.NewInMemoryObjectStore(map[string]storage.Object{
+			"v1/latest.json":                       {Content: []byte("batect"), Generation: 1},
+			"products/my-plugin/v1/latest.json":    {Content: []byte("my-plugin"), Generation: 2},
+			"products/other-plugin/v1/latest.json": {Content: []byte("other-plugin"), Generation: 3},
+		})
+	})
+
+	Context("given the default product", func() {
+		It("reads objects from their original locations", func() {
+			object, err := storage.NewProductObjectStore(underlying, storage.DefaultProduct).GetObject(ctx, "v1/latest.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(object.Content)).To(Equal("batect"))
+		})
+	})
+
+	Context("given another product", func() {
+		var store storage.ObjectStore
+
+		BeforeEach(func() {
+			store = storage.NewProductObjectStore(underlying, "my-plugin")
+		})
+
+		It("reads objects from the product's prefix", func() {
+			object, err := store.GetObject(ctx, "v1/latest.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(object.Content)).To(Equal("my-plugin"))
+		})
+
+		It("writes objects to the product's prefix", func() {
+			_, err := store.PutObject(ctx, "v1/channels/beta/latest.json", []byte("beta"), "application/json", storage.Precondition{})
+			Expect(err).ToNot(HaveOccurred())
+
+			object, err := underlying.GetObject(ctx, "products/my-plugin/v1/channels/beta/latest.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(object.Content)).To(Equal("beta"))
+		})
+
+		It("returns an error matching ErrObjectNotFound when getting an object that does not exist", func() {
+			_, err := store.GetObject(ctx, "v1/thing.json")
+			Expect(err).To(MatchError(storage.ErrObjectNotFound))
+			Expect(err).To(MatchError("object 'products/my-plugin/v1/thing.json' does not exist"))
+		})
+	})
+
+	itBehavesLikeAWritableObjectStore(func() storage.ObjectStore {
+		return storage.NewProductObjectStore(storage.NewInMemoryObjectStore(nil), "my-plugin")
+	})
+})
+
+var _ = DescribeTable("Identifying the product for an object",
+	func(name string, expectedProduct string, expectedNameWithinProduct string) {
+		product, nameWithinProduct := storage.ProductForObjectName(name)
+		Expect(product).To(Equal(expectedProduct))
+		Expect(nameWithinProduct).To(Equal(expectedNameWithinProduct))
+	},
+	Entry("an object for the default product", "v1/latest.json", "batect", "v1/latest.json"),
+	Entry("an object for another product", "products/my-plugin/v1/channels/beta/latest.json", "my-plugin", "v1/channels/beta/latest.json"),
+	Entry("an object with no product name", "products//v1/latest.json", "batect", "products//v1/latest.json"),
+	Entry("an object directly under the products prefix", "products/thing.json", "batect", "products/thing.json"),
+)
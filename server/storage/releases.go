@@ -0,0 +1,171 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const releaseHistoryObjectName = "v1/releases.json"
+
+const maxReleaseHistoryWriteAttempts = 5
+
+type releaseHistoryStore struct {
+	objects ObjectStore
+}
+
+// NewReleaseHistoryStore returns a store that reads the release history from the 'v1/releases.json' object in objects.
+func NewReleaseHistoryStore(objects ObjectStore) ReleaseHistoryStore {
+	return &releaseHistoryStore{
+		objects: objects,
+	}
+}
+
+type releaseHistoryDocument struct {
+	Releases []Release `json:"releases"`
+}
+
+func NewReleaseHistoryUpdater(objects ObjectStore) ReleaseHistoryUpdater {
+	return &releaseHistoryStore{
+		objects: objects,
+	}
+}
+
+func (s *releaseHistoryStore) GetReleases(ctx context.Context) ([]Release, error) {
+	object, err := s.objects.GetObject(ctx, releaseHistoryObjectName)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get release history: %w", err)
+	}
+
+	return parseReleaseHistory(object.Content)
+}
+
+func (s *releaseHistoryStore) UpdateReleaseHistory(ctx context.Context, update func(releases []Release) []Release) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		updated, err := s.tryUpdate(ctx, update)
+
+		if err == nil {
+			return updated, nil
+		}
+
+		if !errors.Is(err, ErrPreconditionFailed) || attempt == maxReleaseHistoryWriteAttempts {
+			return false, fmt.Errorf("could not update release history: %w", err)
+		}
+	}
+}
+
+func (s *releaseHistoryStore) tryUpdate(ctx context.Context, update func(releases []Release) []Release) (bool, error) {
+	object, err := s.objects.GetObject(ctx, releaseHistoryObjectName)
+	current := []Release{}
+
+	switch {
+	case errors.Is(err, ErrObjectNotFound):
+	case err != nil:
+		return false, err
+	default:
+		if current, err = parseReleaseHistory(object.Content); err != nil {
+			return false, err
+		}
+	}
+
+	existing, err := json.MarshalIndent(releaseHistoryDocument{Releases: current}, "", "  ")
+
+	if err != nil {
+		return false, err
+	}
+
+	releases := update(append([]Release{}, current...))
+
+	for _, release := range releases {
+		if err := validateRelease(release); err != nil {
+			return false, fmt.Errorf("updated release history contains an invalid release: %w", err)
+		}
+	}
+
+	sortReleases(releases)
+
+	content, err := json.MarshalIndent(releaseHistoryDocument{Releases: releases}, "", "  ")
+
+	if err != nil {
+		return false, err
+	}
+
+	if bytes.Equal(content, existing) {
+		return false, nil
+	}
+
+	if _, err := s.objects.PutObject(ctx, releaseHistoryObjectName, content, "application/json", PreconditionForGeneration(object.Generation)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func parseReleaseHistory(content []byte) ([]Release, error) {
+	var document releaseHistoryDocument
+
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("could not parse release history: %w", err)
+	}
+
+	for _, release := range document.Releases {
+		if err := validateRelease(release); err != nil {
+			return nil, fmt.Errorf("release history contains an invalid release: %w", err)
+		}
+	}
+
+	if document.Releases == nil {
+		document.Releases = []Release{}
+	}
+
+	sortReleases(document.Releases)
+
+	return document.Releases, nil
+}
+
+func sortReleases(releases []Release) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[j].Version.LessThan(releases[i].Version)
+	})
+}
+
+func validateRelease(release Release) error {
+	switch release.Status {
+	case ReleaseStatusCurrent, ReleaseStatusDeprecated, ReleaseStatusYanked:
+	default:
+		return fmt.Errorf("release %v has unknown status '%v'", release.Version, release.Status)
+	}
+
+	if release.URL == "" {
+		return fmt.Errorf("release %v has no URL", release.Version)
+	}
+
+	if release.ReleaseDate.IsZero() {
+		return fmt.Errorf("release %v has no release date", release.Version)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type cachingReleaseHistoryStore struct {
+	releases *cachedList[Release]
+}
+
+// A release history that doesn't exist is treated as an empty one.
+func NewCachingReleaseHistoryStore(ctx context.Context, underlying ReleaseHistoryStore, refreshInterval time.Duration) ReleaseHistoryStore {
+	get := func(ctx context.Context) ([]Release, error) {
+		releases, err := underlying.GetReleases(ctx)
+
+		if errors.Is(err, ErrObjectNotFound) {
+			return []Release{}, nil
+		}
+
+		return releases, err
+	}
+
+	return &cachingReleaseHistoryStore{
+		releases: newCachedList(ctx, "release history", get, refreshInterval),
+	}
+}
+
+func (c *cachingReleaseHistoryStore) GetReleases(ctx context.Context) ([]Release, error) {
+	return c.releases.Get(ctx)
+}
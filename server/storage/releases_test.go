@@ -0,0 +1,180 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Getting the release history", func() {
+	Context("given the release history does not exist", func() {
+		It("returns an appropriate error", func() {
+			store := storage.NewReleaseHistoryStore(storage.NewInMemoryObjectStore(nil))
+			_, err := store.GetReleases(context.Background())
+			Expect(err).To(MatchError("could not get release history: object 'v1/releases.json' does not exist"))
+		})
+	})
+
+	Context("given the release history is valid", func() {
+		It("returns all releases, newest first", func() {
+			store := storage.NewReleaseHistoryStore(jsonObject("v1/releases.json", `{
+				"releases": [
+					{"version": "0.79.1", "releaseDate": "2022-01-02T03:04:05Z", "url": "https://github.com/batect/batect/releases/tag/0.79.1", "status": "deprecated"},
+					{"version": "0.83.2", "releaseDate": "2023-02-03T04:05:06Z", "url": "https://github.com/batect/batect/releases/tag/0.83.2", "status": "current", "notes": "Fixes a bug."},
+					{"version": "0.80.0", "releaseDate": "2022-06-07T08:09:10Z", "url": "https://github.com/batect/batect/releases/tag/0.80.0", "status": "yanked"}
+				]
+			}`))
+
+			Expect(store.GetReleases(context.Background())).To(Equal([]storage.Release{
+				{
+					Version:     semver.MustParse("0.83.2"),
+					ReleaseDate: time.Date(2023, 2, 3, 4, 5, 6, 0, time.UTC),
+					URL:         "https://github.com/batect/batect/releases/tag/0.83.2",
+					Status:      storage.ReleaseStatusCurrent,
+					Notes:       "Fixes a bug.",
+				},
+				{
+					Version:     semver.MustParse("0.80.0"),
+					ReleaseDate: time.Date(2022, 6, 7, 8, 9, 10, 0, time.UTC),
+					URL:         "https://github.com/batect/batect/releases/tag/0.80.0",
+					Status:      storage.ReleaseStatusYanked,
+				},
+				{
+					Version:     semver.MustParse("0.79.1"),
+					ReleaseDate: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+					URL:         "https://github.com/batect/batect/releases/tag/0.79.1",
+					Status:      storage.ReleaseStatusDeprecated,
+				},
+			}))
+		})
+	})
+
+	itRejectsInvalidContent("given the release history is invalid",
+		func(content string) error {
+			_, err := storage.NewReleaseHistoryStore(jsonObject("v1/releases.json", content)).GetReleases(context.Background())
+			return err
+		},
+		Entry("not JSON", `blah`, "could not parse release history"),
+		Entry("invalid version", `{"releases": [{"version": "1.2", "releaseDate": "2023-02-03T04:05:06Z", "url": "https://example.com", "status": "current"}]}`, "could not parse release history"),
+		Entry("unknown status", `{"releases": [{"version": "1.2.3", "releaseDate": "2023-02-03T04:05:06Z", "url": "https://example.com", "status": "blah"}]}`, "release 1.2.3 has unknown status 'blah'"),
+		Entry("missing URL", `{"releases": [{"version": "1.2.3", "releaseDate": "2023-02-03T04:05:06Z", "status": "current"}]}`, "release 1.2.3 has no URL"),
+		Entry("missing release date", `{"releases": [{"version": "1.2.3", "url": "https://example.com", "status": "current"}]}`, "release 1.2.3 has no release date"),
+	)
+})
+
+var _ = Describe("Updating the release history", func() {
+	var objects storage.ObjectStore
+	var updater storage.ReleaseHistoryUpdater
+	ctx := context.Background()
+
+	release := storage.Release{
+		Version:     semver.MustParse("0.83.2"),
+		ReleaseDate: time.Date(2023, 2, 3, 4, 5, 6, 0, time.UTC),
+		URL:         "https://github.com/batect/batect/releases/tag/0.83.2",
+		Status:      storage.ReleaseStatusCurrent,
+	}
+
+	olderRelease := storage.Release{
+		Version:     semver.MustParse("0.79.1"),
+		ReleaseDate: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		URL:         "https://github.com/batect/batect/releases/tag/0.79.1",
+		Status:      storage.ReleaseStatusDeprecated,
+	}
+
+	BeforeEach(func() {
+		objects = storage.NewInMemoryObjectStore(nil)
+		updater = storage.NewReleaseHistoryUpdater(objects)
+	})
+
+	Context("given the release history does not exist", func() {
+		var current []storage.Release
+
+		BeforeEach(func() {
+			updated, err := updater.UpdateReleaseHistory(ctx, func(releases []storage.Release) []storage.Release {
+				current = releases
+
+				return []storage.Release{olderRelease, release}
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated).To(BeTrue())
+		})
+
+		It("updates an empty release history", func() {
+			Expect(current).To(BeEmpty())
+		})
+
+		It("stores the updated release history, newest first", func() {
+			Expect(storage.NewReleaseHistoryStore(objects).GetReleases(ctx)).To(Equal([]storage.Release{release, olderRelease}))
+		})
+	})
+
+	Context("given the release history exists", func() {
+		var generation int64
+
+		BeforeEach(func() {
+			_, err := updater.UpdateReleaseHistory(ctx, func([]storage.Release) []storage.Release {
+				return []storage.Release{release, olderRelease}
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			generation, err = objects.GetObjectGeneration(ctx, "v1/releases.json")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("updates the current release history", func() {
+			var current []storage.Release
+
+			_, err := updater.UpdateReleaseHistory(ctx, func(releases []storage.Release) []storage.Release {
+				current = releases
+
+				return releases[:1]
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(current).To(Equal([]storage.Release{release, olderRelease}))
+			Expect(storage.NewReleaseHistoryStore(objects).GetReleases(ctx)).To(Equal([]storage.Release{release}))
+		})
+
+		It("does not write anything if the release history is unchanged", func() {
+			updated, err := updater.UpdateReleaseHistory(ctx, func([]storage.Release) []storage.Release {
+				return []storage.Release{olderRelease, release}
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated).To(BeFalse())
+			Expect(objects.GetObjectGeneration(ctx, "v1/releases.json")).To(Equal(generation))
+		})
+	})
+
+	It("rejects an updated release history containing an invalid release", func() {
+		_, err := updater.UpdateReleaseHistory(ctx, func([]storage.Release) []storage.Release {
+			return []storage.Release{{Version: semver.MustParse("1.2.3"), Status: storage.ReleaseStatusCurrent}}
+		})
+
+		Expect(err).To(MatchError(ContainSubstring("release 1.2.3 has no URL")))
+	})
+})
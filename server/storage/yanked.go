@@ -0,0 +1,114 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const yankedVersionsObjectName = "v1/yanked.json"
+
+type yankedVersionStore struct {
+	objects ObjectStore
+}
+
+func NewYankedVersionStore(objects ObjectStore) YankedVersionStore {
+	return &yankedVersionStore{
+		objects: objects,
+	}
+}
+
+type yankedVersionsDocument struct {
+	Versions []YankedVersion `json:"versions"`
+}
+
+func (s *yankedVersionStore) GetYankedVersions(ctx context.Context) ([]YankedVersion, error) {
+	object, err := s.objects.GetObject(ctx, yankedVersionsObjectName)
+
+	// Most of the time, nothing has been yanked.
+	if errors.Is(err, ErrObjectNotFound) {
+		return []YankedVersion{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get yanked versions: %w", err)
+	}
+
+	var document yankedVersionsDocument
+
+	if err := json.Unmarshal(object.Content, &document); err != nil {
+		return nil, fmt.Errorf("could not parse yanked versions: %w", err)
+	}
+
+	if err := validateYankedVersions(document.Versions); err != nil {
+		return nil, fmt.Errorf("yanked versions are invalid: %w", err)
+	}
+
+	if document.Versions == nil {
+		document.Versions = []YankedVersion{}
+	}
+
+	return document.Versions, nil
+}
+
+// Build metadata is significant, as versions that differ only in build metadata are separate releases.
+func FindYankedVersion(yanked []YankedVersion, version string) (YankedVersion, bool) {
+	for _, entry := range yanked {
+		if entry.Version.String() == version {
+			return entry, true
+		}
+	}
+
+	return YankedVersion{}, false
+}
+
+func validateYankedVersions(yanked []YankedVersion) error {
+	seen := map[string]struct{}{}
+
+	for _, entry := range yanked {
+		version := entry.Version.String()
+
+		if _, duplicate := seen[version]; duplicate {
+			return fmt.Errorf("version %v is listed more than once", version)
+		}
+
+		seen[version] = struct{}{}
+
+		if strings.TrimSpace(entry.Reason) == "" {
+			return fmt.Errorf("version %v has no reason", version)
+		}
+	}
+
+	for _, entry := range yanked {
+		if entry.Replacement == nil {
+			continue
+		}
+
+		if _, yanked := seen[entry.Replacement.String()]; yanked {
+			return fmt.Errorf("version %v is replaced by %v, but that version has also been yanked", entry.Version, entry.Replacement)
+		}
+	}
+
+	return nil
+}
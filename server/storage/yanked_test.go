@@ -0,0 +1,98 @@
+// Copyright 2019-2023 Charles Korn.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// and the Commons Clause License Condition v1.0 (the "Condition");
+// you may not use this file except in compliance with both the License and Condition.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// You may obtain a copy of the Condition at
+//
+//     https://commonsclause.com/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License and the Condition is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See both the License and the Condition for the specific language governing permissions and
+// limitations under the License and the Condition.
+
+package storage_test
+
+import (
+	"context"
+
+	"github.com/batect/updates.batect.dev/server/semver"
+	"github.com/batect/updates.batect.dev/server/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Getting yanked versions", func() {
+	Context("given no versions have been yanked", func() {
+		It("returns an empty list", func() {
+			store := storage.NewYankedVersionStore(storage.NewInMemoryObjectStore(nil))
+			Expect(store.GetYankedVersions(context.Background())).To(BeEmpty())
+		})
+	})
+
+	Context("given the yanked versions are valid", func() {
+		It("returns all yanked versions", func() {
+			store := storage.NewYankedVersionStore(jsonObject("v1/yanked.json", `{
+				"versions": [
+					{"version": "0.83.1", "reason": "Corrupted JAR published", "replacement": "0.83.2"},
+					{"version": "0.80.0", "reason": "Breaks Docker Compose support"}
+				]
+			}`))
+
+			replacement := semver.MustParse("0.83.2")
+
+			Expect(store.GetYankedVersions(context.Background())).To(Equal([]storage.YankedVersion{
+				{Version: semver.MustParse("0.83.1"), Reason: "Corrupted JAR published", Replacement: &replacement},
+				{Version: semver.MustParse("0.80.0"), Reason: "Breaks Docker Compose support"},
+			}))
+		})
+	})
+
+	itRejectsInvalidContent("given the yanked versions are invalid",
+		func(content string) error {
+			_, err := storage.NewYankedVersionStore(jsonObject("v1/yanked.json", content)).GetYankedVersions(context.Background())
+			return err
+		},
+		Entry("not JSON", `this is not JSON`, "could not parse yanked versions"),
+		Entry("an invalid version", `{"versions": [{"version": "0.83", "reason": "Broken"}]}`, "could not parse yanked versions"),
+		Entry("a duplicate version", `{"versions": [{"version": "0.83.1", "reason": "Broken"}, {"version": "0.83.1", "reason": "Still broken"}]}`,
+			"version 0.83.1 is listed more than once"),
+		Entry("a version with no reason", `{"versions": [{"version": "0.83.1", "reason": " "}]}`, "version 0.83.1 has no reason"),
+		Entry("a yanked replacement", `{"versions": [{"version": "0.83.1", "reason": "Broken", "replacement": "0.83.2"}, {"version": "0.83.2", "reason": "Also broken"}]}`,
+			"version 0.83.1 is replaced by 0.83.2, but that version has also been yanked"),
+		Entry("a replacement for itself", `{"versions": [{"version": "0.83.1", "reason": "Broken", "replacement": "0.83.1"}]}`,
+			"version 0.83.1 is replaced by 0.83.1, but that version has also been yanked"),
+	)
+})
+
+var _ = Describe("Finding a yanked version", func() {
+	yanked := []storage.YankedVersion{
+		{Version: semver.MustParse("0.83.1"), Reason: "Broken"},
+		{Version: semver.MustParse("1.0.0+build.5"), Reason: "Broken"},
+	}
+
+	It("returns the entry for a version that has been yanked", func() {
+		entry, ok := storage.FindYankedVersion(yanked, "0.83.1")
+
+		Expect(ok).To(BeTrue())
+		Expect(entry.Version).To(Equal(semver.MustParse("0.83.1")))
+	})
+
+	It("treats build metadata as significant", func() {
+		_, ok := storage.FindYankedVersion(yanked, "1.0.0+build.6")
+
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns false for a version that has not been yanked", func() {
+		_, ok := storage.FindYankedVersion(yanked, "0.83.2")
+
+		Expect(ok).To(BeFalse())
+	})
+})